@@ -0,0 +1,398 @@
+// Package admin exposes the live state of named policies for diagnostics, via
+// expvar and a JSON http.Handler.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+// Bulkhead is implemented by concurrency-limiting policies that can report how many
+// calls they are running.
+type Bulkhead interface {
+	Name() string
+	InFlight() int
+	Capacity() int
+}
+
+// PolicyStatus is the state of one named policy. Fields that do not apply to the
+// policy's kind are omitted from JSON.
+type PolicyStatus struct {
+	Name string `json:"name"`
+	Kind string `json:"kind,omitempty"`
+
+	// Pipeline is the pipeline the event statistics were collected in. A policy
+	// seen in several pipelines has one entry per pipeline; registered state such
+	// as a breaker's is shared, so it is repeated in each of them.
+	Pipeline string `json:"pipeline,omitempty"`
+
+	// Enabled is set for policies wrapped with Registry.Toggle.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Circuit breakers.
+	State               string     `json:"state,omitempty"`
+	Forced              bool       `json:"forced,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures,omitempty"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+
+	// Rate limiters.
+	Tokens *float64 `json:"tokens,omitempty"`
+	Rate   float64  `json:"rate,omitempty"`
+	Burst  int      `json:"burst,omitempty"`
+
+	// Bulkheads.
+	InFlight *int `json:"in_flight,omitempty"`
+	Capacity int  `json:"capacity,omitempty"`
+
+	// Load balancers.
+	Backends []BackendStatus `json:"backends,omitempty"`
+
+	// Event statistics, collected when the Registry is attached as an observer.
+	Calls       uint64     `json:"calls"`
+	Successes   uint64     `json:"successes"`
+	Failures    uint64     `json:"failures"`
+	Retries     uint64     `json:"retries"`
+	Rejections  uint64     `json:"rejections"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// BackendStatus is the state of one backend of a load balancer.
+type BackendStatus struct {
+	Backend             string     `json:"backend"`
+	InFlight            int        `json:"in_flight"`
+	Requests            uint64     `json:"requests"`
+	Failures            uint64     `json:"failures"`
+	ConsecutiveFailures int        `json:"consecutive_failures,omitempty"`
+	Latency             string     `json:"latency,omitempty"`
+	Ejected             bool       `json:"ejected,omitempty"`
+	EjectedUntil        *time.Time `json:"ejected_until,omitempty"`
+	Ejections           int        `json:"ejections,omitempty"`
+}
+
+// Status is the state of every policy known to a Registry.
+type Status struct {
+	Policies []PolicyStatus `json:"policies"`
+
+	// Pipelines describes the policy tree of each registered pipeline, sorted by name.
+	Pipelines []gosentry.PipelineDescription `json:"pipelines,omitempty"`
+}
+
+// Registry tracks named policies. It is a gosentry.Observer: attach it globally or
+// to pipelines to collect per-policy statistics and last errors.
+type Registry struct {
+	mu        sync.Mutex
+	breakers  map[string]*policies.Breaker
+	limiters  map[string]*policies.Limiter
+	bulkheads map[string]Bulkhead
+	balancers map[string]*policies.Balancer
+	pipelines map[string]*gosentry.Pipeline
+	toggles   map[string]*atomic.Bool
+	stats     map[statsKey]*policyStats
+}
+
+// statsKey identifies the statistics of a policy within a pipeline, so policies
+// that share a name, such as the default "retry", are not merged across pipelines.
+type statsKey struct {
+	pipeline string
+	policy   string
+}
+
+type policyStats struct {
+	kind        string
+	calls       uint64
+	successes   uint64
+	failures    uint64
+	retries     uint64
+	rejections  uint64
+	lastError   string
+	lastErrorAt time.Time
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		breakers:  map[string]*policies.Breaker{},
+		limiters:  map[string]*policies.Limiter{},
+		bulkheads: map[string]Bulkhead{},
+		balancers: map[string]*policies.Balancer{},
+		pipelines: map[string]*gosentry.Pipeline{},
+		toggles:   map[string]*atomic.Bool{},
+		stats:     map[statsKey]*policyStats{},
+	}
+}
+
+// RegisterBreaker makes b visible under b.Name(), replacing any breaker of that name.
+func (r *Registry) RegisterBreaker(b *policies.Breaker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.breakers[b.Name()] = b
+}
+
+// RegisterLimiter makes l visible under l.Name(), replacing any limiter of that name.
+func (r *Registry) RegisterLimiter(l *policies.Limiter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limiters[l.Name()] = l
+}
+
+// RegisterBulkhead makes b visible under b.Name(), replacing any bulkhead of that name.
+func (r *Registry) RegisterBulkhead(b Bulkhead) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bulkheads[b.Name()] = b
+}
+
+// RegisterBalancer makes b and its per-backend stats visible under b.Name(),
+// replacing any balancer of that name.
+func (r *Registry) RegisterBalancer(b *policies.Balancer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.balancers[b.Name()] = b
+}
+
+// RegisterPipeline adds p's policy tree, as returned by Describe, to the Snapshot,
+// replacing any pipeline of the same name.
+func (r *Registry) RegisterPipeline(p *gosentry.Pipeline) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pipelines[p.Name()] = p
+}
+
+// Toggle wraps p so it can be switched off at runtime under name. While disabled,
+// calls bypass p and go straight to the next handler. Wrapping several policies
+// with the same name switches them together.
+func (r *Registry) Toggle(name string, p gosentry.Policy) gosentry.Policy {
+	r.mu.Lock()
+	enabled, ok := r.toggles[name]
+	if !ok {
+		enabled = &atomic.Bool{}
+		enabled.Store(true)
+		r.toggles[name] = enabled
+	}
+	r.mu.Unlock()
+
+	return func(next gosentry.Handler) gosentry.Handler {
+		guarded := p(next)
+		return func(ctx context.Context) (any, error) {
+			if !enabled.Load() {
+				return next(ctx)
+			}
+			return guarded(ctx)
+		}
+	}
+}
+
+// SetEnabled switches a policy wrapped with Toggle on or off. It reports false if
+// no policy was toggled under name.
+func (r *Registry) SetEnabled(name string, enabled bool) bool {
+	r.mu.Lock()
+	toggle, ok := r.toggles[name]
+	r.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+	toggle.Store(enabled)
+	return true
+}
+
+// Breaker returns the breaker registered under name.
+func (r *Registry) Breaker(name string) (*policies.Breaker, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b, ok := r.breakers[name]
+	return b, ok
+}
+
+// Limiter returns the limiter registered under name.
+func (r *Registry) Limiter(name string) (*policies.Limiter, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.limiters[name]
+	return l, ok
+}
+
+// Observe implements gosentry.Observer.
+func (r *Registry) Observe(ev gosentry.Event) {
+	if ev.Policy == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := statsKey{pipeline: ev.Pipeline, policy: ev.Policy}
+	st, ok := r.stats[key]
+	if !ok {
+		st = &policyStats{}
+		r.stats[key] = st
+	}
+	st.kind = ev.PolicyKind
+
+	switch ev.Kind {
+	case gosentry.EventSuccess:
+		st.calls++
+		st.successes++
+	case gosentry.EventFailure, gosentry.EventTimeout:
+		st.calls++
+		st.failures++
+	case gosentry.EventRetry:
+		st.retries++
+	case gosentry.EventRejected:
+		st.rejections++
+	}
+
+	if ev.Err != nil {
+		st.lastError = ev.Err.Error()
+		st.lastErrorAt = ev.Time
+	}
+}
+
+// Snapshot returns the current state of every known policy, sorted by name and
+// pipeline.
+func (r *Registry) Snapshot() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Entries are keyed by pipeline and name. State registered under a name is
+	// applied to every entry of that name, or to a pipeline-less entry if the
+	// policy has not been seen in any pipeline.
+	entries := map[statsKey]*PolicyStatus{}
+	for key, st := range r.stats {
+		ps := &PolicyStatus{
+			Name:       key.policy,
+			Kind:       st.kind,
+			Pipeline:   key.pipeline,
+			Calls:      st.calls,
+			Successes:  st.successes,
+			Failures:   st.failures,
+			Retries:    st.retries,
+			Rejections: st.rejections,
+		}
+		if st.lastError != "" {
+			at := st.lastErrorAt
+			ps.LastError = st.lastError
+			ps.LastErrorAt = &at
+		}
+		entries[key] = ps
+	}
+	named := func(name string, fill func(ps *PolicyStatus)) {
+		found := false
+		for key, ps := range entries {
+			if key.policy == name {
+				fill(ps)
+				found = true
+			}
+		}
+		if !found {
+			ps := &PolicyStatus{Name: name}
+			fill(ps)
+			entries[statsKey{policy: name}] = ps
+		}
+	}
+
+	for name, b := range r.breakers {
+		stats := b.Stats()
+		named(name, func(ps *PolicyStatus) {
+			ps.Kind = string(gosentry.KindCircuitBreaker)
+			ps.State = string(stats.State)
+			ps.Forced = stats.Forced
+			ps.ConsecutiveFailures = stats.ConsecutiveFailures
+			if !stats.OpenedAt.IsZero() {
+				ps.OpenedAt = &stats.OpenedAt
+			}
+		})
+	}
+	for name, l := range r.limiters {
+		tokens, rate, burst := l.Tokens(), l.Rate(), l.Burst()
+		named(name, func(ps *PolicyStatus) {
+			ps.Kind = string(gosentry.KindRateLimit)
+			ps.Tokens = &tokens
+			ps.Rate = rate
+			ps.Burst = burst
+		})
+	}
+	for name, b := range r.bulkheads {
+		inFlight, capacity := b.InFlight(), b.Capacity()
+		named(name, func(ps *PolicyStatus) {
+			ps.Kind = string(gosentry.KindBulkhead)
+			ps.InFlight = &inFlight
+			ps.Capacity = capacity
+		})
+	}
+	for name, b := range r.balancers {
+		var backends []BackendStatus
+		for _, st := range b.Stats() {
+			bs := BackendStatus{
+				Backend:             st.Backend,
+				InFlight:            st.InFlight,
+				Requests:            st.Requests,
+				Failures:            st.Failures,
+				ConsecutiveFailures: st.ConsecutiveFailures,
+				Ejected:             st.Ejected,
+				Ejections:           st.Ejections,
+			}
+			if st.Latency > 0 {
+				bs.Latency = st.Latency.String()
+			}
+			if st.Ejected {
+				until := st.EjectedUntil
+				bs.EjectedUntil = &until
+			}
+			backends = append(backends, bs)
+		}
+		named(name, func(ps *PolicyStatus) {
+			ps.Kind = string(gosentry.KindLoadBalancer)
+			ps.Backends = backends
+		})
+	}
+	for name, toggle := range r.toggles {
+		enabled := toggle.Load()
+		named(name, func(ps *PolicyStatus) { ps.Enabled = &enabled })
+	}
+
+	status := Status{Policies: make([]PolicyStatus, 0, len(entries))}
+	for _, ps := range entries {
+		status.Policies = append(status.Policies, *ps)
+	}
+	sort.Slice(status.Policies, func(i, j int) bool {
+		a, b := status.Policies[i], status.Policies[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Pipeline < b.Pipeline
+	})
+
+	for _, p := range r.pipelines {
+		status.Pipelines = append(status.Pipelines, p.Describe())
+	}
+	sort.Slice(status.Pipelines, func(i, j int) bool {
+		return status.Pipelines[i].Name < status.Pipelines[j].Name
+	})
+	return status
+}
+
+// ServeHTTP writes the current Snapshot as JSON.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(r.Snapshot())
+}
+
+// Publish exposes the registry's Snapshot as the expvar variable name, served on
+// /debug/vars. Like expvar.Publish, it panics if name is already published.
+func (r *Registry) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return r.Snapshot()
+	}))
+}
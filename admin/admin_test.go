@@ -0,0 +1,182 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+type fakeBulkhead struct{}
+
+func (fakeBulkhead) Name() string  { return "uploads" }
+func (fakeBulkhead) InFlight() int { return 3 }
+func (fakeBulkhead) Capacity() int { return 10 }
+
+func find(t *testing.T, status Status, name string) PolicyStatus {
+	t.Helper()
+	for _, ps := range status.Policies {
+		if ps.Name == name {
+			return ps
+		}
+	}
+	t.Fatalf("policy %q not found in %+v", name, status)
+	return PolicyStatus{}
+}
+
+func TestRegistry_SnapshotCombinesStateAndStatistics(t *testing.T) {
+	reg := NewRegistry()
+	breaker := policies.NewBreaker(policies.CircuitBreakerOptions{Name: "payments-cb", FailureThreshold: 1, OpenTimeout: time.Minute})
+	limiter := policies.NewLimiter(policies.RateLimitOptions{Name: "payments-rl", Rate: 1, Burst: 5})
+	reg.RegisterBreaker(breaker)
+	reg.RegisterLimiter(limiter)
+	reg.RegisterBulkhead(fakeBulkhead{})
+
+	p := gosentry.NewPipeline("payments",
+		policies.Retry(policies.RetryOptions{Name: "payments-retry", MaxAttempts: 2, InitialDelay: time.Millisecond, Backoff: policies.BackoffFixed}),
+		limiter.Policy(),
+		breaker.Policy(),
+	).WithObserver(reg)
+
+	p.Execute(context.Background(), func(ctx context.Context) (any, error) {
+		return nil, errors.New("upstream down")
+	})
+
+	status := reg.Snapshot()
+
+	cb := find(t, status, "payments-cb")
+	if cb.Kind != "circuit_breaker" || cb.State != "open" || cb.OpenedAt == nil {
+		t.Fatalf("unexpected breaker status %+v", cb)
+	}
+	if cb.Rejections != 1 || cb.LastError != policies.ErrCircuitOpen.Error() {
+		t.Fatalf("expected the rejection to be the last error, got %+v", cb)
+	}
+
+	rl := find(t, status, "payments-rl")
+	if rl.Tokens == nil || *rl.Tokens > 3.1 || rl.Burst != 5 {
+		t.Fatalf("unexpected limiter status %+v", rl)
+	}
+
+	retry := find(t, status, "payments-retry")
+	if retry.Kind != "retry" || retry.Retries != 1 || retry.Failures != 1 {
+		t.Fatalf("unexpected retry status %+v", retry)
+	}
+
+	bh := find(t, status, "uploads")
+	if bh.InFlight == nil || *bh.InFlight != 3 || bh.Capacity != 10 {
+		t.Fatalf("unexpected bulkhead status %+v", bh)
+	}
+}
+
+func TestRegistry_KeepsPipelinesApart(t *testing.T) {
+	reg := NewRegistry()
+	breaker := policies.NewBreaker(policies.CircuitBreakerOptions{Name: "shared-cb"})
+	reg.RegisterBreaker(breaker)
+	retry := policies.Retry(policies.RetryOptions{MaxAttempts: 2, InitialDelay: time.Millisecond})
+
+	payments := gosentry.NewPipeline("payments", retry, breaker.Policy()).WithObserver(reg)
+	search := gosentry.NewPipeline("search", retry, breaker.Policy()).WithObserver(reg)
+	payments.Execute(context.Background(), func(ctx context.Context) (any, error) { return nil, errors.New("down") })
+	search.Execute(context.Background(), func(ctx context.Context) (any, error) { return "ok", nil })
+
+	var retries, breakers []PolicyStatus
+	for _, ps := range reg.Snapshot().Policies {
+		switch ps.Name {
+		case "retry":
+			retries = append(retries, ps)
+		case "shared-cb":
+			breakers = append(breakers, ps)
+		}
+	}
+	if len(retries) != 2 || retries[0].Pipeline != "payments" || retries[0].Failures != 1 || retries[1].Pipeline != "search" || retries[1].Successes != 1 {
+		t.Fatalf("expected one retry entry per pipeline, got %+v", retries)
+	}
+	if len(breakers) != 2 || breakers[0].State != "closed" || breakers[1].State != "closed" {
+		t.Fatalf("expected the shared breaker state in both pipelines, got %+v", breakers)
+	}
+}
+
+func TestRegistry_ServesJSON(t *testing.T) {
+	reg := NewRegistry()
+	reg.RegisterBreaker(policies.NewBreaker(policies.CircuitBreakerOptions{Name: "cb"}))
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest("GET", "/status", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	var status Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got := find(t, status, "cb"); got.State != "closed" {
+		t.Fatalf("expected closed breaker, got %+v", got)
+	}
+}
+
+func TestRegistry_PublishesExpvar(t *testing.T) {
+	reg := NewRegistry()
+	reg.RegisterLimiter(policies.NewLimiter(policies.RateLimitOptions{Name: "rl", Rate: 1, Burst: 2}))
+	reg.Publish("gosentry_admin_test")
+
+	v := expvar.Get("gosentry_admin_test")
+	if v == nil {
+		t.Fatal("expected expvar to be published")
+	}
+	var status Status
+	if err := json.Unmarshal([]byte(v.String()), &status); err != nil {
+		t.Fatalf("invalid expvar JSON: %v", err)
+	}
+	if got := find(t, status, "rl"); got.Tokens == nil || *got.Tokens != 2 {
+		t.Fatalf("unexpected limiter status %+v", got)
+	}
+}
+
+func TestRegistry_DescribesPipelines(t *testing.T) {
+	reg := NewRegistry()
+	reg.RegisterPipeline(gosentry.NewPipeline("payments",
+		policies.Timeout(policies.TimeoutOptions{Name: "payments-timeout", Duration: time.Second}),
+		policies.Retry(policies.RetryOptions{Name: "payments-retry"}),
+	))
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest("GET", "/status", nil))
+
+	var status Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(status.Pipelines) != 1 || status.Pipelines[0].Name != "payments" {
+		t.Fatalf("expected the payments pipeline, got %+v", status.Pipelines)
+	}
+	ps := status.Pipelines[0].Policies
+	if len(ps) != 2 || ps[0].Name != "payments-timeout" || ps[1].Kind != gosentry.KindRetry {
+		t.Fatalf("unexpected policy tree %+v", ps)
+	}
+	if v, _ := ps[0].Option("duration"); v != "1s" {
+		t.Fatalf("expected duration 1s, got %v", v)
+	}
+}
+
+func TestRegistry_ReportsBalancerBackends(t *testing.T) {
+	reg := NewRegistry()
+	b := policies.NewBalancer(policies.BalancerOptions{Name: "api", Backends: []string{"a", "b"}})
+	reg.RegisterBalancer(b)
+	b.Policy()(func(ctx context.Context) (any, error) { return nil, errors.New("down") })(context.Background())
+
+	status := reg.Snapshot()
+	if len(status.Policies) != 1 || status.Policies[0].Kind != string(gosentry.KindLoadBalancer) {
+		t.Fatalf("expected the balancer, got %+v", status.Policies)
+	}
+	backends := status.Policies[0].Backends
+	if len(backends) != 2 || backends[0].Backend != "a" || backends[0].Failures != 1 || backends[1].Requests != 0 {
+		t.Fatalf("unexpected backends %+v", backends)
+	}
+}
@@ -0,0 +1,304 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrForbidden is returned by authorizers to deny a request.
+	ErrForbidden = errors.New("admin: forbidden")
+)
+
+// Action identifies an operation requested through the admin API.
+type Action string
+
+const (
+	ActionList         Action = "list"
+	ActionBreakerOpen  Action = "breaker.open"
+	ActionBreakerClose Action = "breaker.close"
+	ActionBreakerReset Action = "breaker.reset"
+	ActionLimiterSet   Action = "limiter.set"
+	ActionEnable       Action = "policy.enable"
+	ActionDisable      Action = "policy.disable"
+)
+
+// Authorizer decides whether r may perform action on the named policy. It returns
+// the principal to record in the audit log, or an error to deny the request.
+type Authorizer interface {
+	Authorize(r *http.Request, action Action, policy string) (principal string, err error)
+}
+
+// AuthorizerFunc adapts a function to the Authorizer interface.
+type AuthorizerFunc func(r *http.Request, action Action, policy string) (string, error)
+
+func (f AuthorizerFunc) Authorize(r *http.Request, action Action, policy string) (string, error) {
+	return f(r, action, policy)
+}
+
+// AuditEntry records one change attempted through the admin API.
+type AuditEntry struct {
+	Time       time.Time
+	Principal  string
+	RemoteAddr string
+	Action     Action
+	Policy     string
+
+	// Detail describes the requested change, e.g. "rate=5 burst=10".
+	Detail string
+
+	// Err is set when the change was denied or failed.
+	Err error
+}
+
+// AuditLog receives an entry for every change attempted through the admin API.
+type AuditLog interface {
+	Record(entry AuditEntry)
+}
+
+// AuditLogFunc adapts a function to the AuditLog interface.
+type AuditLogFunc func(entry AuditEntry)
+
+func (f AuditLogFunc) Record(entry AuditEntry) {
+	f(entry)
+}
+
+// SlogAuditLog writes audit entries to logger at info level, or warn level for
+// denied and failed changes.
+func SlogAuditLog(logger *slog.Logger) AuditLog {
+	return AuditLogFunc(func(e AuditEntry) {
+		level := slog.LevelInfo
+		attrs := []slog.Attr{
+			slog.String("principal", e.Principal),
+			slog.String("remote_addr", e.RemoteAddr),
+			slog.String("action", string(e.Action)),
+			slog.String("policy", e.Policy),
+		}
+		if e.Detail != "" {
+			attrs = append(attrs, slog.String("detail", e.Detail))
+		}
+		if e.Err != nil {
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.String("error", e.Err.Error()))
+		}
+		logger.LogAttrs(context.Background(), level, "gosentry admin", attrs...)
+	})
+}
+
+type HandlerOptions struct {
+	// Authorizer guards every endpoint. If nil, every request is denied.
+	Authorizer Authorizer
+
+	// AuditLog records every change. If nil, changes are logged with slog.Default().
+	AuditLog AuditLog
+
+	// Now is used for audit timestamps; if nil, time.Now is used.
+	Now func() time.Time
+}
+
+// NewHandler returns an http.Handler to inspect and change the policies of reg at
+// runtime:
+//
+//	GET  /policies                       list policies and their state
+//	POST /breakers/{name}/open           force the circuit open
+//	POST /breakers/{name}/close          force the circuit closed
+//	POST /breakers/{name}/reset          release a forced state
+//	POST /limiters/{name}                set {"rate": 5, "burst": 10} (either may be omitted)
+//	POST /policies/{name}/enable         switch a toggled policy on
+//	POST /policies/{name}/disable        switch a toggled policy off
+//
+// Mount it under a prefix with http.StripPrefix.
+func NewHandler(reg *Registry, options HandlerOptions) http.Handler {
+	opts := applyHandlerDefaults(options)
+	h := &handler{reg: reg, opts: opts}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /policies", h.list)
+	mux.HandleFunc("POST /breakers/{name}/{action}", h.breaker)
+	mux.HandleFunc("POST /limiters/{name}", h.limiter)
+	mux.HandleFunc("POST /policies/{name}/{action}", h.toggle)
+	return mux
+}
+
+type handler struct {
+	reg  *Registry
+	opts HandlerOptions
+}
+
+func (h *handler) list(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.opts.Authorizer.Authorize(r, ActionList, ""); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.reg.Snapshot())
+}
+
+func (h *handler) breaker(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var action Action
+	switch r.PathValue("action") {
+	case "open":
+		action = ActionBreakerOpen
+	case "close":
+		action = ActionBreakerClose
+	case "reset":
+		action = ActionBreakerReset
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown breaker action %q", r.PathValue("action")))
+		return
+	}
+
+	h.change(w, r, action, name, "", func() (int, error) {
+		b, ok := h.reg.Breaker(name)
+		if !ok {
+			return http.StatusNotFound, fmt.Errorf("no circuit breaker named %q", name)
+		}
+		switch action {
+		case ActionBreakerOpen:
+			b.ForceOpen()
+		case ActionBreakerClose:
+			b.ForceClose()
+		case ActionBreakerReset:
+			b.Reset()
+		}
+		return http.StatusOK, nil
+	})
+}
+
+type limiterRequest struct {
+	Rate  *float64 `json:"rate"`
+	Burst *int     `json:"burst"`
+}
+
+func (h *handler) limiter(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var req limiterRequest
+	decodeErr := json.NewDecoder(r.Body).Decode(&req)
+
+	var detail []string
+	if req.Rate != nil {
+		detail = append(detail, fmt.Sprintf("rate=%v", *req.Rate))
+	}
+	if req.Burst != nil {
+		detail = append(detail, fmt.Sprintf("burst=%d", *req.Burst))
+	}
+
+	h.change(w, r, ActionLimiterSet, name, strings.Join(detail, " "), func() (int, error) {
+		if decodeErr != nil {
+			return http.StatusBadRequest, fmt.Errorf("invalid request body: %w", decodeErr)
+		}
+		if req.Rate == nil && req.Burst == nil {
+			return http.StatusBadRequest, errors.New("request must set rate or burst")
+		}
+		l, ok := h.reg.Limiter(name)
+		if !ok {
+			return http.StatusNotFound, fmt.Errorf("no rate limiter named %q", name)
+		}
+		rate, burst := l.Rate(), l.Burst()
+		if req.Rate != nil {
+			rate = *req.Rate
+		}
+		if req.Burst != nil {
+			burst = *req.Burst
+		}
+		if err := l.SetLimits(rate, burst); err != nil {
+			return http.StatusBadRequest, err
+		}
+		return http.StatusOK, nil
+	})
+}
+
+func (h *handler) toggle(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var action Action
+	switch r.PathValue("action") {
+	case "enable":
+		action = ActionEnable
+	case "disable":
+		action = ActionDisable
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown policy action %q", r.PathValue("action")))
+		return
+	}
+
+	h.change(w, r, action, name, "", func() (int, error) {
+		if !h.reg.SetEnabled(name, action == ActionEnable) {
+			return http.StatusNotFound, fmt.Errorf("no toggled policy named %q", name)
+		}
+		return http.StatusOK, nil
+	})
+}
+
+// change authorizes, applies and audits one mutation, then responds with the new
+// status of the policy.
+func (h *handler) change(w http.ResponseWriter, r *http.Request, action Action, name, detail string, apply func() (int, error)) {
+	entry := AuditEntry{
+		Time:       h.opts.Now(),
+		RemoteAddr: r.RemoteAddr,
+		Action:     action,
+		Policy:     name,
+		Detail:     detail,
+	}
+
+	principal, err := h.opts.Authorizer.Authorize(r, action, name)
+	entry.Principal = principal
+	if err != nil {
+		entry.Err = err
+		h.opts.AuditLog.Record(entry)
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	status, err := apply()
+	entry.Err = err
+	h.opts.AuditLog.Record(entry)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+
+	for _, ps := range h.reg.Snapshot().Policies {
+		if ps.Name == name {
+			writeJSON(w, status, ps)
+			return
+		}
+	}
+	w.WriteHeader(status)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func denyAll(*http.Request, Action, string) (string, error) {
+	return "", ErrForbidden
+}
+
+func applyHandlerDefaults(options HandlerOptions) HandlerOptions {
+	if options.Authorizer == nil {
+		options.Authorizer = AuthorizerFunc(denyAll)
+	}
+	if options.AuditLog == nil {
+		options.AuditLog = SlogAuditLog(slog.Default())
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+
+	return options
+}
@@ -0,0 +1,155 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+type auditRecorder struct {
+	entries []AuditEntry
+}
+
+func (a *auditRecorder) Record(e AuditEntry) {
+	a.entries = append(a.entries, e)
+}
+
+func allowToken(r *http.Request, action Action, policy string) (string, error) {
+	if r.Header.Get("Authorization") != "Bearer sre" {
+		return "", ErrForbidden
+	}
+	return "sre", nil
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string, authorized bool) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if authorized {
+		req.Header.Set("Authorization", "Bearer sre")
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_ForcesBreakerState(t *testing.T) {
+	reg := NewRegistry()
+	breaker := policies.NewBreaker(policies.CircuitBreakerOptions{Name: "payments"})
+	reg.RegisterBreaker(breaker)
+	audit := &auditRecorder{}
+	h := NewHandler(reg, HandlerOptions{Authorizer: AuthorizerFunc(allowToken), AuditLog: audit})
+
+	rec := do(t, h, "POST", "/breakers/payments/open", "", true)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var ps PolicyStatus
+	json.Unmarshal(rec.Body.Bytes(), &ps)
+	if ps.State != "open" || !ps.Forced {
+		t.Fatalf("expected forced open status, got %+v", ps)
+	}
+
+	_, err := gosentry.Execute(context.Background(), func(ctx context.Context) (any, error) { return "ok", nil }, breaker.Policy())
+	if !errors.Is(err, policies.ErrCircuitOpen) {
+		t.Fatalf("expected calls to be rejected, got %v", err)
+	}
+
+	do(t, h, "POST", "/breakers/payments/reset", "", true)
+	if breaker.State() != policies.CircuitClosed || breaker.Stats().Forced {
+		t.Fatalf("expected breaker reset, got %+v", breaker.Stats())
+	}
+
+	if len(audit.entries) != 2 || audit.entries[0].Action != ActionBreakerOpen || audit.entries[0].Principal != "sre" {
+		t.Fatalf("unexpected audit log %+v", audit.entries)
+	}
+}
+
+func TestHandler_AdjustsLimiter(t *testing.T) {
+	reg := NewRegistry()
+	limiter := policies.NewLimiter(policies.RateLimitOptions{Name: "api", Rate: 100, Burst: 100})
+	reg.RegisterLimiter(limiter)
+	audit := &auditRecorder{}
+	h := NewHandler(reg, HandlerOptions{Authorizer: AuthorizerFunc(allowToken), AuditLog: audit})
+
+	rec := do(t, h, "POST", "/limiters/api", `{"rate": 50, "burst": 20}`, true)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if limiter.Rate() != 50 || limiter.Burst() != 20 {
+		t.Fatalf("expected rate 50 burst 20, got %v/%v", limiter.Rate(), limiter.Burst())
+	}
+	if audit.entries[0].Detail != "rate=50 burst=20" {
+		t.Fatalf("unexpected audit detail %q", audit.entries[0].Detail)
+	}
+
+	rec = do(t, h, "POST", "/limiters/api", `{"rate": -1}`, true)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid rate, got %d", rec.Code)
+	}
+	if audit.entries[1].Err == nil {
+		t.Fatal("expected failed change to be audited with its error")
+	}
+
+	rec = do(t, h, "POST", "/limiters/api", `{"rate": 10, "burst": 0}`, true)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid burst, got %d", rec.Code)
+	}
+	if limiter.Rate() != 50 || limiter.Burst() != 20 {
+		t.Fatalf("expected a rejected change to leave the limiter alone, got %v/%v", limiter.Rate(), limiter.Burst())
+	}
+}
+
+func TestHandler_TogglesPolicy(t *testing.T) {
+	reg := NewRegistry()
+	h := NewHandler(reg, HandlerOptions{Authorizer: AuthorizerFunc(allowToken), AuditLog: &auditRecorder{}})
+
+	limited := reg.Toggle("strict", policies.RateLimit(policies.RateLimitOptions{Rate: 0.001, Burst: 1}))
+	ok := func(ctx context.Context) (any, error) { return "ok", nil }
+
+	gosentry.Execute(context.Background(), ok, limited)
+	if _, err := gosentry.Execute(context.Background(), ok, limited); !errors.Is(err, policies.ErrRateLimitExceeded) {
+		t.Fatalf("expected rate limit while enabled, got %v", err)
+	}
+
+	rec := do(t, h, "POST", "/policies/strict/disable", "", true)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if _, err := gosentry.Execute(context.Background(), ok, limited); err != nil {
+		t.Fatalf("expected policy to be bypassed while disabled, got %v", err)
+	}
+
+	if rec := do(t, h, "POST", "/policies/missing/disable", "", true); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown policy, got %d", rec.Code)
+	}
+}
+
+func TestHandler_DeniesUnauthorizedRequests(t *testing.T) {
+	reg := NewRegistry()
+	breaker := policies.NewBreaker(policies.CircuitBreakerOptions{Name: "payments", OpenTimeout: time.Minute})
+	reg.RegisterBreaker(breaker)
+	audit := &auditRecorder{}
+	h := NewHandler(reg, HandlerOptions{Authorizer: AuthorizerFunc(allowToken), AuditLog: audit})
+
+	if rec := do(t, h, "POST", "/breakers/payments/open", "", false); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+	if breaker.State() != policies.CircuitClosed {
+		t.Fatal("expected breaker to be untouched")
+	}
+	if len(audit.entries) != 1 || !errors.Is(audit.entries[0].Err, ErrForbidden) {
+		t.Fatalf("expected denied attempt to be audited, got %+v", audit.entries)
+	}
+
+	if rec := do(t, NewHandler(reg, HandlerOptions{AuditLog: audit}), "GET", "/policies", "", true); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected default authorizer to deny, got %d", rec.Code)
+	}
+}
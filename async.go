@@ -0,0 +1,157 @@
+package gosentry
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrBatchAborted is the error of batch items that were not started because an
+// earlier item failed in fail-fast mode.
+var ErrBatchAborted = errors.New("gosentry: batch aborted after an earlier failure")
+
+// Future is the pending result of an asynchronous execution.
+type Future struct {
+	done   chan struct{}
+	result any
+	err    error
+}
+
+func startFuture(run func() (any, error)) *Future {
+	f := &Future{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		f.result, f.err = run()
+	}()
+	return f
+}
+
+// Done is closed once the execution has finished.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait returns the result of the execution once it has finished, or ctx.Err() if
+// ctx is done first. The execution itself is only cancelled through the context it
+// was started with.
+func (f *Future) Wait(ctx context.Context) (any, error) {
+	select {
+	case <-f.done:
+		return f.result, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ExecuteAsync runs handler through policies in a new goroutine, like Execute.
+func ExecuteAsync(ctx context.Context, handler Handler, policies ...Policy) *Future {
+	return startFuture(func() (any, error) {
+		return Execute(ctx, handler, policies...)
+	})
+}
+
+// Result is the outcome of one handler of a batch.
+type Result struct {
+	Value any
+	Err   error
+}
+
+type BatchOptions struct {
+	// Concurrency is the maximum number of handlers running at once. Zero means no
+	// limit.
+	Concurrency int
+
+	// FailFast stops the batch at the first error: running handlers are cancelled
+	// and the ones not yet started fail with ErrBatchAborted. Otherwise every
+	// handler runs and all errors are collected.
+	FailFast bool
+}
+
+func DefaultBatchOptions() BatchOptions {
+	return BatchOptions{}
+}
+
+func applyBatchDefaults(options BatchOptions) BatchOptions {
+	if options.Concurrency < 0 {
+		options.Concurrency = 0
+	}
+	return options
+}
+
+// ExecuteAll runs every handler through policies concurrently and returns their
+// results in the order of handlers. All handlers share the same policy instances,
+// so a rate limiter or circuit breaker sees the whole batch. The error joins the
+// errors of the failed handlers; it is nil if all succeeded.
+func ExecuteAll(ctx context.Context, handlers []Handler, policies ...Policy) ([]Result, error) {
+	return ExecuteBatch(ctx, handlers, DefaultBatchOptions(), policies...)
+}
+
+// ExecuteBatch is ExecuteAll with a concurrency limit and fail-fast mode. In
+// fail-fast mode the error is the first one that occurred.
+func ExecuteBatch(ctx context.Context, handlers []Handler, options BatchOptions, policies ...Policy) ([]Result, error) {
+	return runBatch(ctx, handlers, options, func(ctx context.Context, h Handler) (any, error) {
+		return Execute(ctx, h, policies...)
+	})
+}
+
+func runBatch(ctx context.Context, handlers []Handler, options BatchOptions, execute func(context.Context, Handler) (any, error)) ([]Result, error) {
+	opts := applyBatchDefaults(options)
+	results := make([]Result, len(handlers))
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var sem chan struct{}
+	if opts.Concurrency > 0 {
+		sem = make(chan struct{}, opts.Concurrency)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	for i, h := range handlers {
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+			}
+		}
+		// After an abort the cause is ErrBatchAborted, otherwise the caller's error.
+		if ctx.Err() != nil {
+			for j := i; j < len(handlers); j++ {
+				results[j].Err = context.Cause(ctx)
+			}
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
+			value, err := execute(ctx, h)
+			results[i] = Result{Value: value, Err: err}
+			if err != nil && opts.FailFast {
+				once.Do(func() {
+					first = err
+					cancel(ErrBatchAborted)
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if first != nil {
+		return results, first
+	}
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return results, errors.Join(errs...)
+}
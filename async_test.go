@@ -0,0 +1,154 @@
+package gosentry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func value(v any) Handler {
+	return func(context.Context) (any, error) { return v, nil }
+}
+
+func failing(err error) Handler {
+	return func(context.Context) (any, error) { return nil, err }
+}
+
+func TestExecuteAsync(t *testing.T) {
+	release := make(chan struct{})
+	f := ExecuteAsync(context.Background(), func(context.Context) (any, error) {
+		<-release
+		return "ok", nil
+	})
+
+	select {
+	case <-f.Done():
+		t.Fatal("expected the future to be pending")
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := f.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Wait to give up with its context, got %v", err)
+	}
+
+	close(release)
+	<-f.Done()
+	res, err := f.Wait(context.Background())
+	if err != nil || res != "ok" {
+		t.Fatalf("unexpected result %v, %v", res, err)
+	}
+}
+
+func TestPipeline_ExecuteAsyncIsObserved(t *testing.T) {
+	rec := &recorder{}
+	p := NewPipeline("async").WithObserver(rec)
+
+	if _, err := p.ExecuteAsync(context.Background(), value(1)).Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.events) != 1 || rec.events[0].Pipeline != "async" {
+		t.Fatalf("expected a completed event of the pipeline, got %+v", rec.events)
+	}
+}
+
+func TestExecuteAll_OrderedResultsAndErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+	handlers := []Handler{
+		func(context.Context) (any, error) {
+			time.Sleep(10 * time.Millisecond)
+			return 0, nil
+		},
+		failing(errBoom),
+		value(2),
+	}
+
+	results, err := ExecuteAll(context.Background(), handlers)
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected the joined errors, got %v", err)
+	}
+	if results[0].Value != 0 || results[1].Err != errBoom || results[2].Value != 2 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+}
+
+func TestExecuteAll_SharesPolicies(t *testing.T) {
+	var calls atomic.Int32
+	counting := func(next Handler) Handler {
+		return func(ctx context.Context) (any, error) {
+			calls.Add(1)
+			return next(ctx)
+		}
+	}
+
+	handlers := []Handler{value(0), value(1), value(2)}
+	if _, err := ExecuteAll(context.Background(), handlers, counting); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected the policy to see every handler, got %d calls", calls.Load())
+	}
+}
+
+func TestExecuteBatch_Concurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	h := func(context.Context) (any, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		return nil, nil
+	}
+
+	handlers := make([]Handler, 10)
+	for i := range handlers {
+		handlers[i] = h
+	}
+	if _, err := ExecuteBatch(context.Background(), handlers, BatchOptions{Concurrency: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if peak.Load() != 2 {
+		t.Fatalf("expected at most 2 handlers at once, got %d", peak.Load())
+	}
+}
+
+func TestExecuteBatch_FailFast(t *testing.T) {
+	errBoom := errors.New("boom")
+	handlers := []Handler{
+		func(ctx context.Context) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+		failing(errBoom),
+		value(2),
+	}
+
+	results, err := ExecuteBatch(context.Background(), handlers, BatchOptions{Concurrency: 2, FailFast: true})
+	if err != errBoom {
+		t.Fatalf("expected the first error, got %v", err)
+	}
+	if !errors.Is(results[0].Err, context.Canceled) {
+		t.Fatalf("expected the running handler to be cancelled, got %v", results[0].Err)
+	}
+	if results[2].Err != ErrBatchAborted {
+		t.Fatalf("expected the remaining handler not to start, got %+v", results[2])
+	}
+}
+
+func TestExecuteBatch_CallerCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := ExecuteBatch(ctx, []Handler{value(0)}, BatchOptions{Concurrency: 1})
+	if !errors.Is(err, context.Canceled) || !errors.Is(results[0].Err, context.Canceled) {
+		t.Fatalf("expected the caller's cancellation, got %v", err)
+	}
+}
@@ -1,12 +1,13 @@
 package main
 
 import (
-	"context"
 	"fmt"
-	"gosentry"
+	"io"
 	"net/http"
 	"time"
 
+	"gosentry"
+	"gosentry/gosentryhttp"
 	"gosentry/policies"
 )
 
@@ -18,15 +19,16 @@ func main() {
 		Backoff:      policies.BackoffExponential,
 		Jitter:       true,
 	}
-	retryPolicy := policies.Retry(retryOptions)
-	handler := func(ctx context.Context) (any, error) {
-		resp, err := http.Get("https://www.google.com/")
-		if err != nil {
-			return nil, err
-		}
-		return resp, nil
+	pipeline := gosentry.NewPipeline("google", policies.Retry(retryOptions))
+
+	client := &http.Client{Transport: gosentryhttp.NewTransport(pipeline, gosentryhttp.TransportOptions{})}
+	resp, err := client.Get("https://www.google.com/")
+	if err != nil {
+		fmt.Println(err)
+		return
 	}
-	resp, err := gosentry.Execute(context.Background(), handler, retryPolicy)
-	fmt.Println(resp)
-	fmt.Println(err)
+	defer resp.Body.Close()
+
+	n, err := io.Copy(io.Discard, resp.Body)
+	fmt.Println(resp.Status, n, err)
 }
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+// Set holds the pipelines built from a Config.
+type Set struct {
+	pipelines map[string]*gosentry.Pipeline
+	chains    map[string][]gosentry.Policy
+	built     map[string]*builtPolicy
+	breakers  []*policies.Breaker
+	limiters  []*policies.Limiter
+	warnings  []gosentry.LintWarning
+}
+
+// builtPolicy remembers the spec a policy was built from so that a later build can
+// tell whether it may be reused.
+type builtPolicy struct {
+	spec    PolicySpec
+	policy  gosentry.Policy
+	breaker *policies.Breaker
+	limiter *policies.Limiter
+
+	// apply, if set, updates reused state once the build has succeeded.
+	apply func() error
+}
+
+// Build creates a pipeline for every entry of c. Each spec's Name becomes the
+// policy name, so it labels the policy in events, metrics and admin status.
+func (c *Config) Build() (*Set, error) {
+	return c.BuildFrom(nil)
+}
+
+// BuildFrom is like Build but carries state over from prev, a set built from an
+// earlier version of the configuration. A policy whose name, type and options are
+// unchanged is reused as is, keeping its circuit state, tokens or latency history.
+// Options are compared by their data fields only: a reused policy keeps the
+// functions, such as ShouldTrip or Now, it was first built with.
+// A rate limiter whose name is unchanged keeps its tokens even if its rate or burst
+// changed, and a circuit breaker keeps its state and counters even if its
+// thresholds changed. Other policies start afresh.
+func (c *Config) BuildFrom(prev *Set) (*Set, error) {
+	s := &Set{
+		pipelines: map[string]*gosentry.Pipeline{},
+		chains:    map[string][]gosentry.Policy{},
+		built:     map[string]*builtPolicy{},
+	}
+
+	for _, name := range c.Names() {
+		specs := c.Pipelines[name]
+		chain := make([]gosentry.Policy, 0, len(specs))
+		for _, spec := range specs {
+			var old *builtPolicy
+			if prev != nil {
+				old = prev.built[spec.Name]
+			}
+			b, err := build(spec, old)
+			if err != nil {
+				return nil, fmt.Errorf("config: pipeline %q: %w", name, err)
+			}
+			if _, dup := s.built[spec.Name]; dup {
+				return nil, fmt.Errorf("config: pipeline %q: duplicate policy name %q", name, spec.Name)
+			}
+			s.built[spec.Name] = b
+			if b.breaker != nil {
+				s.breakers = append(s.breakers, b.breaker)
+			}
+			if b.limiter != nil {
+				s.limiters = append(s.limiters, b.limiter)
+			}
+			chain = append(chain, b.policy)
+		}
+		p := gosentry.NewPipeline(name, chain...)
+		s.pipelines[name] = p
+		s.chains[name] = chain
+		s.warnings = append(s.warnings, p.Lint()...)
+	}
+
+	for name, b := range s.built {
+		if b.apply != nil {
+			// The options were validated when the policy was built.
+			if err := b.apply(); err != nil {
+				return nil, fmt.Errorf("config: policy %q: %w", name, err)
+			}
+			b.apply = nil
+		}
+	}
+	return s, nil
+}
+
+func build(spec PolicySpec, old *builtPolicy) (*builtPolicy, error) {
+	if old != nil && sameSpec(old.spec, spec) {
+		return old, nil
+	}
+
+	if err := validate(spec); err != nil {
+		return nil, fmt.Errorf("policy %q: %w", spec.Name, err)
+	}
+
+	b := &builtPolicy{spec: spec}
+	switch {
+	case spec.Type == TypeRetry && spec.Retry != nil:
+		opts := *spec.Retry
+		opts.Name = spec.Name
+		b.policy = policies.Retry(opts)
+	case spec.Type == TypeCircuitBreaker && spec.CircuitBreaker != nil:
+		if old != nil && old.breaker != nil {
+			// Keep the breaker's state and retune it once the whole set has been
+			// built, so that a failed build leaves the previous set untouched.
+			cb := old.breaker
+			b.policy, b.breaker = cb.Policy(), cb
+			b.apply = func() error { return cb.Retune(*spec.CircuitBreaker) }
+			return b, nil
+		}
+		opts := *spec.CircuitBreaker
+		opts.Name = spec.Name
+		b.breaker = policies.NewBreaker(opts)
+		b.policy = b.breaker.Policy()
+	case spec.Type == TypeTimeout && spec.Timeout != nil:
+		opts := *spec.Timeout
+		opts.Name = spec.Name
+		b.policy = policies.Timeout(opts)
+	case spec.Type == TypeRateLimit && spec.RateLimit != nil:
+		if old != nil && old.limiter != nil {
+			// Likewise, keep the limiter's tokens.
+			l := old.limiter
+			b.policy, b.limiter = l.Policy(), l
+			b.apply = func() error { return l.Retune(*spec.RateLimit) }
+			return b, nil
+		}
+		opts := *spec.RateLimit
+		opts.Name = spec.Name
+		b.limiter = policies.NewLimiter(opts)
+		b.policy = b.limiter.Policy()
+	default:
+		return nil, fmt.Errorf("policy %q: type %q has no matching options", spec.Name, spec.Type)
+	}
+	return b, nil
+}
+
+// validate checks the options matching the type of spec with their Validate
+// method. Parsed specs have been checked field by field already; this also covers
+// specs built in code.
+func validate(spec PolicySpec) error {
+	switch {
+	case spec.Type == TypeRetry && spec.Retry != nil:
+		return spec.Retry.Validate()
+	case spec.Type == TypeCircuitBreaker && spec.CircuitBreaker != nil:
+		return spec.CircuitBreaker.Validate()
+	case spec.Type == TypeTimeout && spec.Timeout != nil:
+		return spec.Timeout.Validate()
+	case spec.Type == TypeRateLimit && spec.RateLimit != nil:
+		return spec.RateLimit.Validate()
+	}
+	return nil
+}
+
+// sameSpec reports whether a and b describe the same policy, ignoring where in the
+// document they appear and any function fields of their options.
+func sameSpec(a, b PolicySpec) bool {
+	a.Pos, b.Pos = Position{}, Position{}
+	return sameData(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+// sameData is like reflect.DeepEqual but treats all functions as equal. Functions
+// are never equal to DeepEqual, so a spec built in code with, say, a ShouldTrip
+// would otherwise be rebuilt on every reload.
+func sameData(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Func:
+		return true
+	case reflect.Pointer:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return sameData(a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := range a.NumField() {
+			if !sameData(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// Pipeline returns the pipeline with the given name.
+func (s *Set) Pipeline(name string) (*gosentry.Pipeline, bool) {
+	p, ok := s.pipelines[name]
+	return p, ok
+}
+
+// Names returns the pipeline names in sorted order.
+func (s *Set) Names() []string {
+	names := make([]string, 0, len(s.pipelines))
+	for name := range s.pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Breakers returns the circuit breakers of every pipeline, e.g. to register them
+// with an admin.Registry.
+func (s *Set) Breakers() []*policies.Breaker {
+	return append([]*policies.Breaker(nil), s.breakers...)
+}
+
+// Warnings returns the problems gosentry.Lint found in the pipelines' policy order.
+// They do not prevent the set from being built.
+func (s *Set) Warnings() []gosentry.LintWarning {
+	return append([]gosentry.LintWarning(nil), s.warnings...)
+}
+
+// Limiters returns the rate limiters of every pipeline.
+func (s *Set) Limiters() []*policies.Limiter {
+	return append([]*policies.Limiter(nil), s.limiters...)
+}
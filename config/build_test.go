@@ -0,0 +1,240 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+func TestBuild(t *testing.T) {
+	cfg, err := Parse([]byte(`
+pipelines:
+  payments:
+    - type: retry
+      max_attempts: 3
+      initial_delay: 1ms
+    - type: circuit_breaker
+      name: payments-cb
+      failure_threshold: 2
+    - type: rate_limit
+      rate: 1
+      burst: 10
+  search:
+    - type: timeout
+      duration: 1s
+`), FormatYAML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	set, err := cfg.Build()
+	if err != nil {
+		t.Fatalf("unexpected build error: %v", err)
+	}
+
+	if names := set.Names(); len(names) != 2 || names[0] != "payments" || names[1] != "search" {
+		t.Fatalf("unexpected pipelines: %v", names)
+	}
+	if _, ok := set.Pipeline("missing"); ok {
+		t.Fatal("expected no pipeline named missing")
+	}
+
+	breakers := set.Breakers()
+	if len(breakers) != 1 || breakers[0].Name() != "payments-cb" {
+		t.Fatalf("unexpected breakers: %v", breakers)
+	}
+	limiters := set.Limiters()
+	if len(limiters) != 1 || limiters[0].Name() != "payments.rate_limit" || limiters[0].Burst() != 10 {
+		t.Fatalf("unexpected limiters: %v", limiters)
+	}
+
+	payments, _ := set.Pipeline("payments")
+	var events []gosentry.Event
+	payments.WithObserver(gosentry.ObserverFunc(func(ev gosentry.Event) {
+		events = append(events, ev)
+	}))
+
+	calls := 0
+	boom := errors.New("boom")
+	_, err = payments.Execute(context.Background(), func(ctx context.Context) (any, error) {
+		calls++
+		return nil, boom
+	})
+
+	// The breaker opens after two failures, so the third attempt is rejected.
+	if !errors.Is(err, policies.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+	if breakers[0].State() != policies.CircuitOpen {
+		t.Fatalf("expected open breaker, got %s", breakers[0].State())
+	}
+
+	seen := map[string]bool{}
+	for _, ev := range events {
+		seen[ev.Policy] = true
+	}
+	for _, name := range []string{"payments.retry", "payments-cb", "payments.rate_limit"} {
+		if !seen[name] {
+			t.Errorf("expected events from %s, got %v", name, seen)
+		}
+	}
+}
+
+func TestBuild_MissingOptions(t *testing.T) {
+	cfg := &Config{Pipelines: map[string][]PolicySpec{
+		"p": {{Type: TypeRetry, Name: "r"}},
+	}}
+	if _, err := cfg.Build(); err == nil {
+		t.Fatal("expected error for spec without options")
+	}
+}
+
+func TestBuild_ValidatesOptions(t *testing.T) {
+	cfg := &Config{Pipelines: map[string][]PolicySpec{
+		"p": {{Type: TypeRetry, Name: "r", Retry: &policies.RetryOptions{MaxAttempts: -1}}},
+	}}
+	_, err := cfg.Build()
+	var oerr *policies.OptionError
+	if !errors.As(err, &oerr) || oerr.Field != "RetryOptions.MaxAttempts" {
+		t.Fatalf("expected the option error, got %v", err)
+	}
+}
+
+func TestBuild_ValidatesOptionsOfTheSpecType(t *testing.T) {
+	cfg := &Config{Pipelines: map[string][]PolicySpec{
+		"p": {{
+			Type:      TypeRateLimit,
+			Name:      "rl",
+			Retry:     &policies.RetryOptions{},
+			RateLimit: &policies.RateLimitOptions{Burst: -1},
+		}},
+	}}
+	_, err := cfg.Build()
+	var oerr *policies.OptionError
+	if !errors.As(err, &oerr) || oerr.Field != "RateLimitOptions.Burst" {
+		t.Fatalf("expected the rate limit option error, got %v", err)
+	}
+}
+
+func TestBuildFrom_ReusesSpecsWithFunctions(t *testing.T) {
+	cfg := func() *Config {
+		return &Config{Pipelines: map[string][]PolicySpec{
+			"p": {{Type: TypeCircuitBreaker, Name: "cb", CircuitBreaker: &policies.CircuitBreakerOptions{
+				FailureThreshold: 2,
+				ShouldTrip:       func(err error) bool { return err != nil },
+			}}},
+		}}
+	}
+	first, err := cfg().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := cfg().BuildFrom(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.built["cb"] != second.built["cb"] {
+		t.Fatal("expected the unchanged spec to be reused despite its ShouldTrip")
+	}
+}
+
+func TestBuildFrom_PreservesState(t *testing.T) {
+	parse := func(cb, rl string) *Config {
+		t.Helper()
+		cfg, err := Parse([]byte(`{"pipelines": {"p": [`+cb+`, `+rl+`]}}`), FormatJSON)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return cfg
+	}
+
+	first, err := parse(`{"type": "circuit_breaker", "name": "cb", "failure_threshold": 1}`,
+		`{"type": "rate_limit", "name": "rl", "rate": 1, "burst": 5}`).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	breaker, limiter := first.Breakers()[0], first.Limiters()[0]
+	breaker.ForceOpen()
+	p, _ := first.Pipeline("p")
+	p.Execute(context.Background(), func(ctx context.Context) (any, error) { return nil, nil })
+	tokens := limiter.Tokens()
+
+	// Same breaker, retuned limiter.
+	second, err := parse(`{"type": "circuit_breaker", "name": "cb", "failure_threshold": 1}`,
+		`{"type": "rate_limit", "name": "rl", "rate": 2, "burst": 5}`).BuildFrom(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Breakers()[0] != breaker || breaker.State() != policies.CircuitOpen {
+		t.Fatal("expected the unchanged breaker to be reused with its state")
+	}
+	if second.Limiters()[0] != limiter || limiter.Rate() != 2 || limiter.Tokens() < tokens {
+		t.Fatalf("expected the limiter to be retuned in place, got rate %v tokens %v", limiter.Rate(), limiter.Tokens())
+	}
+
+	// Changed thresholds retune the breaker in place; an open circuit stays open.
+	breaker.Reset()
+	p, _ = second.Pipeline("p")
+	p.Execute(context.Background(), func(ctx context.Context) (any, error) { return nil, errors.New("down") })
+	if breaker.State() != policies.CircuitOpen {
+		t.Fatal("expected the failure to open the breaker")
+	}
+	third, err := parse(`{"type": "circuit_breaker", "name": "cb", "failure_threshold": 3, "open_timeout": "1ms"}`,
+		`{"type": "rate_limit", "name": "rl", "rate": 2, "burst": 5}`).BuildFrom(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Breakers()[0] != breaker || breaker.State() != policies.CircuitOpen {
+		t.Fatal("expected the retuned breaker to stay open")
+	}
+	time.Sleep(2 * time.Millisecond)
+	p, _ = third.Pipeline("p")
+	if _, err := p.Execute(context.Background(), func(ctx context.Context) (any, error) { return "ok", nil }); err != nil {
+		t.Fatalf("expected the new open_timeout to allow a trial call, got %v", err)
+	}
+}
+
+func TestBuildFrom_FailureLeavesPreviousUntouched(t *testing.T) {
+	first, err := (&Config{Pipelines: map[string][]PolicySpec{
+		"p": {{Type: TypeRateLimit, Name: "rl", RateLimit: &policies.RateLimitOptions{Rate: 1, Burst: 5}}},
+	}}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = (&Config{Pipelines: map[string][]PolicySpec{
+		"p": {
+			{Type: TypeRateLimit, Name: "rl", RateLimit: &policies.RateLimitOptions{Rate: 9, Burst: 5}},
+			{Type: TypeRetry, Name: "broken"},
+		},
+	}}).BuildFrom(first)
+	if err == nil {
+		t.Fatal("expected build error")
+	}
+	if rate := first.Limiters()[0].Rate(); rate != 1 {
+		t.Fatalf("expected the previous limiter to keep rate 1, got %v", rate)
+	}
+}
+
+func TestBuild_Warnings(t *testing.T) {
+	cfg, err := Parse([]byte(`{"pipelines": {"p": [{"type": "circuit_breaker"}, {"type": "retry"}]}}`), FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := cfg.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warnings := set.Warnings()
+	if len(warnings) != 1 || warnings[0].Pipeline != "p" || warnings[0].Rule != "retry-inside-circuit-breaker" {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+}
@@ -0,0 +1,220 @@
+// Package config builds gosentry pipelines from declarative JSON or YAML documents.
+//
+// A document lists named pipelines, each an ordered list of policies (outermost first):
+//
+//	pipelines:
+//	  payments:
+//	    - type: timeout
+//	      duration: 2s
+//	    - type: retry
+//	      max_attempts: 3
+//	      initial_delay: 100ms
+//	      backoff: exponential
+//	    - type: circuit_breaker
+//	      name: payments-cb
+//	      failure_threshold: 5
+//	      open_timeout: 30s
+//	    - type: rate_limit
+//	      rate: 50
+//	      burst: 100
+//
+// Durations use Go syntax ("250ms", "1m30s"). Field names are the snake_case form
+// of the corresponding policies option fields.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Format is the syntax of a configuration document.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// Error is a problem at a precise location in a configuration document.
+type Error struct {
+	Pos Position
+
+	// Path is the location of the offending value, e.g. "pipelines.payments[1].max_attempts".
+	Path string
+
+	Msg string
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Pos, e.Path, e.Msg)
+}
+
+// ErrorList collects every problem found while validating a document.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, e := range l {
+		errs[i] = e
+	}
+	return errs
+}
+
+// Config is a parsed and validated configuration document.
+type Config struct {
+	// Pipelines maps pipeline names to their policies, outermost first.
+	Pipelines map[string][]PolicySpec
+}
+
+// Names returns the pipeline names in sorted order.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Pipelines))
+	for name := range c.Pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse parses and validates a configuration document.
+func Parse(data []byte, format Format) (*Config, error) {
+	return parse(data, format, "")
+}
+
+// ParseFile reads and parses the document at path. The format is chosen from the
+// file extension: .json, or .yaml/.yml.
+func ParseFile(path string) (*Config, error) {
+	format, err := formatFor(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parse(data, format, path)
+}
+
+func formatFor(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	}
+	return "", fmt.Errorf("config: cannot infer format of %q from its extension", path)
+}
+
+func parse(data []byte, format Format, file string) (*Config, error) {
+	var (
+		root *node
+		err  error
+	)
+	switch format {
+	case FormatJSON:
+		root, err = parseJSON(data)
+	case FormatYAML:
+		root, err = parseYAML(data)
+	default:
+		return nil, fmt.Errorf("config: unknown format %q", format)
+	}
+	if err != nil {
+		var perr *Error
+		if errors.As(err, &perr) {
+			perr.Pos.File = file
+		}
+		return nil, err
+	}
+	root.setFile(file)
+
+	d := &decoder{names: map[string]Position{}}
+	cfg := d.document(root)
+	if len(d.errs) > 0 {
+		return nil, d.errs
+	}
+	return cfg, nil
+}
+
+type decoder struct {
+	errs ErrorList
+
+	// names records where each policy name was first used; names identify stateful
+	// policies, so they must be unique across the document.
+	names map[string]Position
+}
+
+func (d *decoder) errorf(n *node, path, format string, args ...any) {
+	d.errs = append(d.errs, &Error{Pos: n.pos, Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (d *decoder) document(root *node) *Config {
+	cfg := &Config{Pipelines: map[string][]PolicySpec{}}
+
+	if root.kind != kindObject {
+		d.errorf(root, "", "document must be an object, got %s", root.kind)
+		return cfg
+	}
+
+	for _, f := range root.fields {
+		switch f.key {
+		case "pipelines":
+			d.pipelines(f.value, "pipelines", cfg)
+		default:
+			d.errorf(f.value, f.key, "unknown field")
+		}
+	}
+	return cfg
+}
+
+func (d *decoder) pipelines(n *node, path string, cfg *Config) {
+	if n.kind != kindObject {
+		d.errorf(n, path, "must be an object of pipelines, got %s", n.kind)
+		return
+	}
+
+	for _, f := range n.fields {
+		pipelinePath := path + "." + f.key
+		if f.key == "" {
+			d.errorf(f.value, pipelinePath, "pipeline name must not be empty")
+			continue
+		}
+		if f.value.kind != kindArray {
+			d.errorf(f.value, pipelinePath, "must be a list of policies, got %s", f.value.kind)
+			continue
+		}
+
+		specs := make([]PolicySpec, 0, len(f.value.items))
+		for i, item := range f.value.items {
+			itemPath := fmt.Sprintf("%s[%d]", pipelinePath, i)
+			spec, ok := d.policy(item, itemPath)
+			if !ok {
+				continue
+			}
+			if spec.Name == "" {
+				spec.Name = f.key + "." + spec.Type
+			}
+			if first, dup := d.names[spec.Name]; dup {
+				d.errorf(item, itemPath, "duplicate policy name %q (first used at %s); set a unique \"name\"", spec.Name, first)
+				continue
+			}
+			d.names[spec.Name] = spec.Pos
+			specs = append(specs, spec)
+		}
+		cfg.Pipelines[f.key] = specs
+	}
+}
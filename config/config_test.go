@@ -0,0 +1,199 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gosentry/policies"
+)
+
+const paymentsYAML = `
+pipelines:
+  payments:
+    - type: timeout
+      duration: 2s
+    - type: retry
+      max_attempts: 3
+      initial_delay: 100ms
+      max_delay: 1s
+      backoff: exponential
+      jitter: true
+    - type: circuit_breaker
+      name: payments-cb
+      failure_threshold: 5
+      open_timeout: 30s
+    - type: rate_limit
+      rate: 50
+      burst: 100
+`
+
+const paymentsJSON = `{
+  "pipelines": {
+    "payments": [
+      {"type": "timeout", "duration": "2s"},
+      {"type": "retry", "max_attempts": 3, "initial_delay": "100ms", "max_delay": "1s", "backoff": "exponential", "jitter": true},
+      {"type": "circuit_breaker", "name": "payments-cb", "failure_threshold": 5, "open_timeout": "30s"},
+      {"type": "rate_limit", "rate": 50, "burst": 100}
+    ]
+  }
+}`
+
+func TestParse_JSONAndYAMLAgree(t *testing.T) {
+	for _, tc := range []struct {
+		format Format
+		data   string
+	}{
+		{FormatYAML, paymentsYAML},
+		{FormatJSON, paymentsJSON},
+	} {
+		t.Run(string(tc.format), func(t *testing.T) {
+			cfg, err := Parse([]byte(tc.data), tc.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			specs := cfg.Pipelines["payments"]
+			if len(specs) != 4 {
+				t.Fatalf("expected 4 policies, got %d", len(specs))
+			}
+
+			if specs[0].Type != TypeTimeout || specs[0].Timeout.Duration != 2*time.Second {
+				t.Errorf("unexpected timeout spec: %+v", specs[0])
+			}
+			if specs[0].Name != "payments.timeout" {
+				t.Errorf("expected default name payments.timeout, got %q", specs[0].Name)
+			}
+
+			r := specs[1].Retry
+			if r == nil || r.MaxAttempts != 3 || r.InitialDelay != 100*time.Millisecond ||
+				r.MaxDelay != time.Second || r.Backoff != policies.BackoffExponential || !r.Jitter {
+				t.Errorf("unexpected retry spec: %+v", r)
+			}
+
+			cb := specs[2].CircuitBreaker
+			if specs[2].Name != "payments-cb" || cb.FailureThreshold != 5 || cb.OpenTimeout != 30*time.Second {
+				t.Errorf("unexpected circuit breaker spec: %+v", specs[2])
+			}
+
+			rl := specs[3].RateLimit
+			if rl.Rate != 50 || rl.Burst != 100 {
+				t.Errorf("unexpected rate limit spec: %+v", rl)
+			}
+		})
+	}
+}
+
+func TestParse_ReportsEveryErrorWithPosition(t *testing.T) {
+	data := `
+pipelines:
+  payments:
+    - type: retry
+      max_attempts: 0
+      initial_delay: soon
+      backof: exponential
+    - type: rate_limit
+      rate: -1
+    - type: bulkhead
+    - max_attempts: 3
+`
+	_, err := Parse([]byte(data), FormatYAML)
+
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected ErrorList, got %T: %v", err, err)
+	}
+
+	want := []string{
+		"5:21: pipelines.payments[0].max_attempts: must be at least 1, got 0",
+		`6:22: pipelines.payments[0].initial_delay: invalid duration "soon"`,
+		"7:15: pipelines.payments[0].backof: unknown field",
+		"9:13: pipelines.payments[1].rate: must be positive, got -1",
+		`10:13: pipelines.payments[2].type: unknown policy type "bulkhead"`,
+		`11:7: pipelines.payments[3]: missing required field "type"`,
+	}
+	if len(list) != len(want) {
+		t.Fatalf("expected %d errors, got %d:\n%v", len(want), len(list), err)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(list[i].Error(), w) {
+			t.Errorf("error %d: expected prefix %q, got %q", i, w, list[i].Error())
+		}
+	}
+}
+
+func TestParse_TypeMismatch(t *testing.T) {
+	data := `{"pipelines": {"p": [{"type": "timeout", "duration": 5}]}}`
+	_, err := Parse([]byte(data), FormatJSON)
+	if err == nil || !strings.Contains(err.Error(), `pipelines.p[0].duration: must be a duration string such as "250ms", got number`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParse_RejectsInconsistentBounds(t *testing.T) {
+	data := `{"pipelines": {"p": [{"type": "retry", "initial_delay": "1s", "max_delay": "10ms"}]}}`
+	_, err := Parse([]byte(data), FormatJSON)
+	if err == nil || !strings.Contains(err.Error(), "max_delay: must be at least initial_delay (1s)") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParse_RejectsEmptyType(t *testing.T) {
+	data := `{"pipelines": {"p": [{"type": "retry"}, {"type": ""}]}}`
+	_, err := Parse([]byte(data), FormatJSON)
+	if err == nil || !strings.Contains(err.Error(), "pipelines.p[1].type: must not be empty") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParse_RejectsNaN(t *testing.T) {
+	for _, rate := range []string{"nan", ".nan", "NaN"} {
+		data := "pipelines:\n  p:\n    - type: rate_limit\n      rate: " + rate + "\n"
+		if _, err := Parse([]byte(data), FormatYAML); err == nil || !strings.Contains(err.Error(), "pipelines.p[0].rate:") {
+			t.Errorf("rate %s: unexpected error: %v", rate, err)
+		}
+	}
+}
+
+func TestParse_DuplicatePolicyNames(t *testing.T) {
+	data := `
+pipelines:
+  a:
+    - type: circuit_breaker
+      name: shared
+  b:
+    - type: circuit_breaker
+      name: shared
+`
+	_, err := Parse([]byte(data), FormatYAML)
+	if err == nil || !strings.Contains(err.Error(), `duplicate policy name "shared" (first used at 4:7)`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParse_UnknownTopLevelField(t *testing.T) {
+	_, err := Parse([]byte(`{"pipeline": {}}`), FormatJSON)
+	if err == nil || !strings.Contains(err.Error(), "pipeline: unknown field") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gosentry.yml")
+	if err := os.WriteFile(path, []byte("pipelines:\n  p:\n    - type: retry\n      max_attempts: x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ParseFile(path)
+	if err == nil || !strings.HasPrefix(err.Error(), path+":4:21: ") {
+		t.Fatalf("expected error located in %s, got %v", path, err)
+	}
+
+	if _, err := ParseFile(filepath.Join(dir, "gosentry.toml")); err == nil {
+		t.Fatal("expected error for unknown extension")
+	}
+}
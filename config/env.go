@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gosentry/policies"
+)
+
+// EnvError is an environment variable whose value could not be applied.
+type EnvError struct {
+	Var   string
+	Value string
+	Msg   string
+}
+
+func (e *EnvError) Error() string {
+	return fmt.Sprintf("%s=%q: %s", e.Var, e.Value, e.Msg)
+}
+
+// EnvErrorList collects every invalid variable, sorted by name.
+type EnvErrorList []*EnvError
+
+func (l EnvErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, e := range l {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (l EnvErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, e := range l {
+		errs[i] = e
+	}
+	return errs
+}
+
+// RetryFromEnv overrides fields of opts from environment variables named
+// <prefix>_RETRY_<FIELD>, where FIELD is the upper-case form of the field name used
+// in configuration files:
+//
+//	GOSENTRY_PAYMENTS_RETRY_MAX_ATTEMPTS=5
+//	GOSENTRY_PAYMENTS_RETRY_INITIAL_DELAY=250ms
+//	GOSENTRY_PAYMENTS_RETRY_BACKOFF=exponential
+//
+// Fields without a variable are left as they are. If any variable is invalid, or a
+// variable under the policy's prefix names no field, opts is left untouched and an
+// EnvErrorList describing every such variable is returned. If the variables are
+// valid on their own but the resulting options are not, opts is left untouched and
+// the error of their Validate method is returned.
+func RetryFromEnv(prefix string, opts *policies.RetryOptions) error {
+	return fromEnv(prefix, TypeRetry, opts, func(r *objectReader, o *policies.RetryOptions) {
+		r.string("name", &o.Name)
+		r.retry(o)
+	})
+}
+
+// CircuitBreakerFromEnv overrides fields of opts from environment variables named
+// <prefix>_CIRCUIT_BREAKER_<FIELD>, e.g. GOSENTRY_PAYMENTS_CIRCUIT_BREAKER_OPEN_TIMEOUT=30s.
+// See RetryFromEnv.
+func CircuitBreakerFromEnv(prefix string, opts *policies.CircuitBreakerOptions) error {
+	return fromEnv(prefix, TypeCircuitBreaker, opts, func(r *objectReader, o *policies.CircuitBreakerOptions) {
+		r.string("name", &o.Name)
+		r.circuitBreaker(o)
+	})
+}
+
+// TimeoutFromEnv overrides fields of opts from environment variables named
+// <prefix>_TIMEOUT_<FIELD>, e.g. GOSENTRY_PAYMENTS_TIMEOUT_DURATION=2s. Adaptive
+// timeout fields use <prefix>_TIMEOUT_ADAPTIVE_<FIELD>. See RetryFromEnv.
+func TimeoutFromEnv(prefix string, opts *policies.TimeoutOptions) error {
+	return fromEnv(prefix, TypeTimeout, opts, func(r *objectReader, o *policies.TimeoutOptions) {
+		r.string("name", &o.Name)
+		r.timeout(o)
+	}, "adaptive")
+}
+
+// RateLimitFromEnv overrides fields of opts from environment variables named
+// <prefix>_RATE_LIMIT_<FIELD>, e.g. GOSENTRY_PAYMENTS_RATE_LIMIT_RATE=50.
+// See RetryFromEnv.
+func RateLimitFromEnv(prefix string, opts *policies.RateLimitOptions) error {
+	return fromEnv(prefix, TypeRateLimit, opts, func(r *objectReader, o *policies.RateLimitOptions) {
+		r.string("name", &o.Name)
+		r.rateLimit(o)
+	})
+}
+
+// fromEnv reads the variables under <prefix>_<policyType>_ into an object node,
+// nesting those that start with one of the nested field names, and applies them
+// to a copy of opts with read. The copy replaces opts only if it validates.
+func fromEnv[T interface{ Validate() error }](prefix, policyType string, opts *T, read func(*objectReader, *T), nested ...string) error {
+	section := strings.ToUpper(policyType)
+	if p := strings.TrimSuffix(prefix, "_"); p != "" {
+		section = p + "_" + section
+	}
+
+	values := map[string]string{}
+	root := &node{kind: kindObject}
+	children := map[string]*node{}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		key, ok := strings.CutPrefix(name, section+"_")
+		if !ok || key == "" {
+			continue
+		}
+		values[name] = value
+		key = strings.ToLower(key)
+
+		parent := root
+		for _, n := range nested {
+			if rest, ok := strings.CutPrefix(key, n+"_"); ok {
+				child := children[n]
+				if child == nil {
+					child = &node{kind: kindObject}
+					children[n] = child
+					root.fields = append(root.fields, field{key: n, value: child})
+				}
+				parent, key = child, rest
+				break
+			}
+		}
+		parent.fields = append(parent.fields, field{key: key, value: &node{kind: kindString, scalar: value, untyped: true}})
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	d := &decoder{}
+	r := d.object(root, section)
+	o := *opts
+	read(r, &o)
+	r.done()
+
+	if len(d.errs) > 0 {
+		errs := make(EnvErrorList, len(d.errs))
+		for i, e := range d.errs {
+			name := strings.ToUpper(strings.ReplaceAll(e.Path, ".", "_"))
+			errs[i] = &EnvError{Var: name, Value: values[name], Msg: e.Msg}
+		}
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Var < errs[j].Var })
+		return errs
+	}
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	*opts = o
+	return nil
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gosentry/policies"
+)
+
+func TestRetryFromEnv(t *testing.T) {
+	t.Setenv("GOSENTRY_PAYMENTS_RETRY_MAX_ATTEMPTS", "5")
+	t.Setenv("GOSENTRY_PAYMENTS_RETRY_INITIAL_DELAY", "250ms")
+	t.Setenv("GOSENTRY_PAYMENTS_RETRY_BACKOFF", "linear")
+	t.Setenv("GOSENTRY_PAYMENTS_RETRY_JITTER", "true")
+	t.Setenv("GOSENTRY_SEARCH_RETRY_MAX_ATTEMPTS", "9")
+
+	opts := policies.DefaultRetryOptions()
+	if err := RetryFromEnv("GOSENTRY_PAYMENTS", &opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	defaults := policies.DefaultRetryOptions()
+	if opts.MaxAttempts != 5 || opts.InitialDelay != 250*time.Millisecond ||
+		opts.Backoff != policies.BackoffLinear || !opts.Jitter {
+		t.Fatalf("overrides not applied: %+v", opts)
+	}
+	if opts.MaxDelay != defaults.MaxDelay || opts.Name != defaults.Name {
+		t.Fatalf("expected other fields to keep their defaults, got %+v", opts)
+	}
+}
+
+func TestRetryFromEnv_ValidatesResult(t *testing.T) {
+	t.Setenv("APP_RETRY_BACKOFF", "linear")
+
+	opts := policies.RetryOptions{MaxAttempts: -1}
+	err := RetryFromEnv("APP", &opts)
+	var oerr *policies.OptionError
+	if !errors.As(err, &oerr) || oerr.Field != "RetryOptions.MaxAttempts" {
+		t.Fatalf("expected the option error, got %v", err)
+	}
+	if opts.Backoff != "" {
+		t.Fatalf("expected opts to be left untouched, got %+v", opts)
+	}
+}
+
+func TestFromEnv_ListsEveryInvalidVariable(t *testing.T) {
+	t.Setenv("APP_CIRCUIT_BREAKER_FAILURE_THRESHOLD", "many")
+	t.Setenv("APP_CIRCUIT_BREAKER_OPEN_TIMEOUT", "30")
+	t.Setenv("APP_CIRCUIT_BREAKER_SUCCESS_THRESHOLD", "0")
+	t.Setenv("APP_CIRCUIT_BREAKER_OPEN_TIMOUT", "30s")
+	t.Setenv("APP_CIRCUIT_BREAKER_NAME", "payments-cb")
+
+	opts := policies.DefaultCircuitBreakerOptions()
+	err := CircuitBreakerFromEnv("APP", &opts)
+
+	var list EnvErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected EnvErrorList, got %T: %v", err, err)
+	}
+	want := []string{
+		`APP_CIRCUIT_BREAKER_FAILURE_THRESHOLD="many": must be an integer, got "many"`,
+		`APP_CIRCUIT_BREAKER_OPEN_TIMEOUT="30": invalid duration "30"`,
+		`APP_CIRCUIT_BREAKER_OPEN_TIMOUT="30s": unknown field`,
+		`APP_CIRCUIT_BREAKER_SUCCESS_THRESHOLD="0": must be at least 1, got 0`,
+	}
+	if len(list) != len(want) {
+		t.Fatalf("expected %d errors, got %d:\n%v", len(want), len(list), err)
+	}
+	for i, w := range want {
+		if list[i].Error() != w {
+			t.Errorf("error %d: expected %q, got %q", i, w, list[i].Error())
+		}
+	}
+
+	if opts.Name != "circuit_breaker" || opts.FailureThreshold != policies.DefaultCircuitBreakerOptions().FailureThreshold {
+		t.Fatalf("expected options untouched on error, got %+v", opts)
+	}
+}
+
+func TestTimeoutFromEnv_Adaptive(t *testing.T) {
+	t.Setenv("SVC_TIMEOUT_DURATION", "2s")
+	t.Setenv("SVC_TIMEOUT_ADAPTIVE_PERCENTILE", "0.95")
+	t.Setenv("SVC_TIMEOUT_ADAPTIVE_MAX", "5s")
+
+	opts := policies.DefaultTimeoutOptions()
+	if err := TimeoutFromEnv("SVC_", &opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.Duration != 2*time.Second || opts.Adaptive == nil ||
+		opts.Adaptive.Percentile != 0.95 || opts.Adaptive.Max != 5*time.Second {
+		t.Fatalf("overrides not applied: %+v %+v", opts, opts.Adaptive)
+	}
+}
+
+func TestRateLimitFromEnv(t *testing.T) {
+	t.Setenv("SVC_RATE_LIMIT_RATE", "-1")
+
+	opts := policies.DefaultRateLimitOptions()
+	err := RateLimitFromEnv("SVC", &opts)
+	if err == nil || err.Error() != `SVC_RATE_LIMIT_RATE="-1": must be positive, got -1` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Setenv("SVC_RATE_LIMIT_RATE", "2.5")
+	t.Setenv("SVC_RATE_LIMIT_BURST", "20")
+	if err := RateLimitFromEnv("SVC", &opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.Rate != 2.5 || opts.Burst != 20 {
+		t.Fatalf("overrides not applied: %+v", opts)
+	}
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// jsonParser is a small recursive-descent JSON parser that records the position of
+// every value, which encoding/json does not expose.
+type jsonParser struct {
+	data []byte
+	off  int
+	line int
+	col  int
+}
+
+func parseJSON(data []byte) (*node, error) {
+	p := &jsonParser{data: data, line: 1, col: 1}
+	p.skipSpace()
+	n, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.off < len(p.data) {
+		return nil, p.errorf("unexpected %q after top-level value", p.data[p.off])
+	}
+	return n, nil
+}
+
+func (p *jsonParser) pos() Position {
+	return Position{Line: p.line, Col: p.col}
+}
+
+func (p *jsonParser) errorf(format string, args ...any) error {
+	return &Error{Pos: p.pos(), Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *jsonParser) advance(n int) {
+	for i := 0; i < n && p.off < len(p.data); i++ {
+		if p.data[p.off] == '\n' {
+			p.line++
+			p.col = 1
+			p.off++
+			continue
+		}
+		_, size := utf8.DecodeRune(p.data[p.off:])
+		p.off += size
+		p.col++
+		i += size - 1
+	}
+}
+
+func (p *jsonParser) skipSpace() {
+	for p.off < len(p.data) {
+		switch p.data[p.off] {
+		case ' ', '\t', '\r', '\n':
+			p.advance(1)
+		default:
+			return
+		}
+	}
+}
+
+func (p *jsonParser) value() (*node, error) {
+	if p.off >= len(p.data) {
+		return nil, p.errorf("unexpected end of input")
+	}
+
+	start := p.pos()
+	switch c := p.data[p.off]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"':
+		s, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: kindString, pos: start, scalar: s}, nil
+	case c == 't' || c == 'f' || c == 'n':
+		for _, lit := range []string{"true", "false", "null"} {
+			if p.hasPrefix(lit) {
+				p.advance(len(lit))
+				if lit == "null" {
+					return &node{kind: kindNull, pos: start}, nil
+				}
+				return &node{kind: kindBool, pos: start, scalar: lit}, nil
+			}
+		}
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.number()
+	}
+	return nil, p.errorf("unexpected character %q", p.data[p.off])
+}
+
+func (p *jsonParser) hasPrefix(s string) bool {
+	return len(p.data)-p.off >= len(s) && string(p.data[p.off:p.off+len(s)]) == s
+}
+
+func (p *jsonParser) object() (*node, error) {
+	n := &node{kind: kindObject, pos: p.pos()}
+	p.advance(1)
+	p.skipSpace()
+	if p.hasPrefix("}") {
+		p.advance(1)
+		return n, nil
+	}
+
+	for {
+		p.skipSpace()
+		if !p.hasPrefix(`"`) {
+			return nil, p.errorf("expected object key")
+		}
+		keyPos := p.pos()
+		key, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range n.fields {
+			if f.key == key {
+				return nil, &Error{Pos: keyPos, Msg: fmt.Sprintf("duplicate key %q", key)}
+			}
+		}
+
+		p.skipSpace()
+		if !p.hasPrefix(":") {
+			return nil, p.errorf("expected ':' after object key")
+		}
+		p.advance(1)
+		p.skipSpace()
+
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		n.fields = append(n.fields, field{key: key, pos: keyPos, value: v})
+
+		p.skipSpace()
+		switch {
+		case p.hasPrefix(","):
+			p.advance(1)
+		case p.hasPrefix("}"):
+			p.advance(1)
+			return n, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in object")
+		}
+	}
+}
+
+func (p *jsonParser) array() (*node, error) {
+	n := &node{kind: kindArray, pos: p.pos()}
+	p.advance(1)
+	p.skipSpace()
+	if p.hasPrefix("]") {
+		p.advance(1)
+		return n, nil
+	}
+
+	for {
+		p.skipSpace()
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		n.items = append(n.items, v)
+
+		p.skipSpace()
+		switch {
+		case p.hasPrefix(","):
+			p.advance(1)
+		case p.hasPrefix("]"):
+			p.advance(1)
+			return n, nil
+		default:
+			return nil, p.errorf("expected ',' or ']' in list")
+		}
+	}
+}
+
+// string scans a quoted string and decodes its escapes with encoding/json.
+func (p *jsonParser) string() (string, error) {
+	start := p.off
+	startPos := p.pos()
+	i := p.off + 1
+	for ; i < len(p.data); i++ {
+		switch p.data[i] {
+		case '\\':
+			i++
+		case '\n':
+			return "", &Error{Pos: startPos, Msg: "unterminated string"}
+		case '"':
+			var s string
+			if err := json.Unmarshal(p.data[start:i+1], &s); err != nil {
+				return "", &Error{Pos: startPos, Msg: "invalid string: " + err.Error()}
+			}
+			p.advance(i + 1 - start)
+			return s, nil
+		}
+	}
+	return "", &Error{Pos: startPos, Msg: "unterminated string"}
+}
+
+func (p *jsonParser) number() (*node, error) {
+	start := p.off
+	n := &node{kind: kindNumber, pos: p.pos()}
+	for p.off < len(p.data) {
+		c := p.data[p.off]
+		if (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E' {
+			p.advance(1)
+			continue
+		}
+		break
+	}
+	n.scalar = string(p.data[start:p.off])
+	if !json.Valid([]byte(n.scalar)) {
+		return nil, &Error{Pos: n.pos, Msg: fmt.Sprintf("invalid number %q", n.scalar)}
+	}
+	return n, nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseJSON_Positions(t *testing.T) {
+	n, err := parseJSON([]byte("{\n  \"a\": [1, \"x\", true, null],\n  \"b\": {}\n}"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n.kind != kindObject || len(n.fields) != 2 {
+		t.Fatalf("unexpected root: %+v", n)
+	}
+
+	a := n.fields[0].value
+	if a.kind != kindArray || len(a.items) != 4 {
+		t.Fatalf("unexpected array: %+v", a)
+	}
+	if got := a.items[1].pos.String(); got != "2:12" {
+		t.Errorf("expected string at 2:12, got %s", got)
+	}
+	if a.items[1].scalar != "x" || a.items[2].kind != kindBool || a.items[3].kind != kindNull {
+		t.Errorf("unexpected items: %+v %+v %+v", a.items[1], a.items[2], a.items[3])
+	}
+}
+
+func TestParseJSON_SyntaxErrors(t *testing.T) {
+	for _, tc := range []struct {
+		data string
+		want string
+	}{
+		{`{"a": 1,}`, "1:9"},
+		{`{"a": 1, "a": 2}`, `duplicate key "a"`},
+		{"{\n  \"a\" 1\n}", "2:7"},
+		{`{"a": 1} x`, "1:10"},
+		{`[1, 2`, "1:6"},
+	} {
+		_, err := parseJSON([]byte(tc.data))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: expected error containing %q, got %v", tc.data, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// kind is the type of a parsed value.
+type kind int
+
+const (
+	kindNull kind = iota
+	kindBool
+	kindNumber
+	kindString
+	kindObject
+	kindArray
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindNull:
+		return "null"
+	case kindBool:
+		return "boolean"
+	case kindNumber:
+		return "number"
+	case kindString:
+		return "string"
+	case kindObject:
+		return "object"
+	case kindArray:
+		return "list"
+	}
+	return "unknown"
+}
+
+// node is a parsed value with the position it was read from. Both the JSON and YAML
+// parsers produce nodes, so validation reports locations the same way for both.
+type node struct {
+	kind kind
+	pos  Position
+
+	// scalar holds the decoded text of strings, numbers and booleans.
+	scalar string
+
+	// untyped marks text whose type depends on where it is used, such as the value
+	// of an environment variable. Its kind is kindString.
+	untyped bool
+
+	fields []field
+	items  []*node
+}
+
+type field struct {
+	key   string
+	pos   Position
+	value *node
+}
+
+// Position is a location in a configuration document.
+type Position struct {
+	File string
+	Line int
+	Col  int
+}
+
+func (p Position) String() string {
+	var b strings.Builder
+	if p.File != "" {
+		b.WriteString(p.File)
+		b.WriteString(":")
+	}
+	fmt.Fprintf(&b, "%d:%d", p.Line, p.Col)
+	return b.String()
+}
+
+func (n *node) setFile(file string) {
+	n.pos.File = file
+	for i := range n.fields {
+		n.fields[i].pos.File = file
+		n.fields[i].value.setFile(file)
+	}
+	for _, item := range n.items {
+		item.setFile(file)
+	}
+}
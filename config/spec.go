@@ -0,0 +1,306 @@
+package config
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+// Policy types accepted in the "type" field.
+const (
+	TypeRetry          = string(gosentry.KindRetry)
+	TypeCircuitBreaker = string(gosentry.KindCircuitBreaker)
+	TypeTimeout        = string(gosentry.KindTimeout)
+	TypeRateLimit      = string(gosentry.KindRateLimit)
+)
+
+// PolicySpec is one validated policy entry. Exactly one of the option fields is set,
+// matching Type. Option fields not present in the document are left zero so the
+// policies package applies its defaults.
+type PolicySpec struct {
+	Type string
+
+	// Name is the policy name given in the document, or "<pipeline>.<type>" if none was.
+	Name string
+
+	// Pos is where the entry starts in the document.
+	Pos Position
+
+	Retry          *policies.RetryOptions
+	CircuitBreaker *policies.CircuitBreakerOptions
+	Timeout        *policies.TimeoutOptions
+	RateLimit      *policies.RateLimitOptions
+}
+
+func (d *decoder) policy(n *node, path string) (PolicySpec, bool) {
+	spec := PolicySpec{Pos: n.pos}
+	if n.kind != kindObject {
+		d.errorf(n, path, "policy must be an object, got %s", n.kind)
+		return spec, false
+	}
+
+	r := d.object(n, path)
+	r.string("type", &spec.Type)
+	r.string("name", &spec.Name)
+
+	errs := len(d.errs)
+	switch spec.Type {
+	case TypeRetry:
+		spec.Retry = &policies.RetryOptions{}
+		r.retry(spec.Retry)
+	case TypeCircuitBreaker:
+		spec.CircuitBreaker = &policies.CircuitBreakerOptions{}
+		r.circuitBreaker(spec.CircuitBreaker)
+	case TypeTimeout:
+		spec.Timeout = &policies.TimeoutOptions{}
+		r.timeout(spec.Timeout)
+	case TypeRateLimit:
+		spec.RateLimit = &policies.RateLimitOptions{}
+		r.rateLimit(spec.RateLimit)
+	case "":
+		if t, ok := r.field("type"); !ok {
+			d.errorf(n, path, "missing required field \"type\"")
+		} else if t.value.kind == kindString {
+			d.errorf(t.value, path+".type", "must not be empty (want %s, %s, %s or %s)",
+				TypeRetry, TypeCircuitBreaker, TypeTimeout, TypeRateLimit)
+		}
+		return spec, false
+	default:
+		t, _ := r.field("type")
+		d.errorf(t.value, path+".type", "unknown policy type %q (want %s, %s, %s or %s)",
+			spec.Type, TypeRetry, TypeCircuitBreaker, TypeTimeout, TypeRateLimit)
+		return spec, false
+	}
+	r.done()
+
+	return spec, len(d.errs) == errs
+}
+
+// The readers below overwrite only the fields present in the object, so they can
+// also apply overrides on top of existing options.
+
+func (r *objectReader) retry(opts *policies.RetryOptions) {
+	r.int("max_attempts", &opts.MaxAttempts, 1)
+	r.duration("initial_delay", &opts.InitialDelay)
+	r.duration("max_delay", &opts.MaxDelay)
+	r.bool("jitter", &opts.Jitter)
+
+	var backoff string
+	if f, ok := r.string("backoff", &backoff); ok {
+		switch b := policies.BackoffStrategy(backoff); b {
+		case policies.BackoffFixed, policies.BackoffLinear, policies.BackoffExponential:
+			opts.Backoff = b
+		default:
+			r.d.errorf(f.value, r.path+".backoff", "unknown backoff %q (want %s, %s or %s)",
+				backoff, policies.BackoffFixed, policies.BackoffLinear, policies.BackoffExponential)
+		}
+	}
+
+	if opts.InitialDelay > 0 && opts.MaxDelay > 0 && opts.MaxDelay < opts.InitialDelay {
+		if f, ok := r.field("max_delay"); ok {
+			r.d.errorf(f.value, r.path+".max_delay", "must be at least initial_delay (%s)", opts.InitialDelay)
+		} else if f, ok := r.field("initial_delay"); ok {
+			r.d.errorf(f.value, r.path+".initial_delay", "must be at most max_delay (%s)", opts.MaxDelay)
+		}
+	}
+}
+
+func (r *objectReader) circuitBreaker(opts *policies.CircuitBreakerOptions) {
+	r.int("failure_threshold", &opts.FailureThreshold, 1)
+	r.int("success_threshold", &opts.SuccessThreshold, 1)
+	r.duration("open_timeout", &opts.OpenTimeout)
+}
+
+func (r *objectReader) timeout(opts *policies.TimeoutOptions) {
+	r.duration("duration", &opts.Duration)
+	r.nonNegativeDuration("safety_margin", &opts.SafetyMargin)
+	r.nonNegativeDuration("min_remaining", &opts.MinRemaining)
+
+	a := r.object("adaptive")
+	if a == nil {
+		return
+	}
+	adaptive := &policies.AdaptiveTimeoutOptions{}
+	if opts.Adaptive != nil {
+		*adaptive = *opts.Adaptive
+	}
+	var percentile, multiplier float64
+	if f, ok := a.float("percentile", &percentile); ok {
+		if percentile <= 0 || percentile > 1 {
+			a.d.errorf(f.value, a.path+".percentile", "must be in (0, 1], got %v", percentile)
+		} else {
+			adaptive.Percentile = percentile
+		}
+	}
+	if f, ok := a.float("multiplier", &multiplier); ok {
+		if multiplier <= 0 {
+			a.d.errorf(f.value, a.path+".multiplier", "must be positive, got %v", multiplier)
+		} else {
+			adaptive.Multiplier = multiplier
+		}
+	}
+	a.duration("min", &adaptive.Min)
+	a.duration("max", &adaptive.Max)
+	a.int("warmup_samples", &adaptive.WarmupSamples, 1)
+	a.int("window", &adaptive.Window, 1)
+	if adaptive.Min > 0 && adaptive.Max > 0 && adaptive.Max < adaptive.Min {
+		if f, ok := a.field("max"); ok {
+			a.d.errorf(f.value, a.path+".max", "must be at least min (%s)", adaptive.Min)
+		} else if f, ok := a.field("min"); ok {
+			a.d.errorf(f.value, a.path+".min", "must be at most max (%s)", adaptive.Max)
+		}
+	}
+	a.done()
+	opts.Adaptive = adaptive
+}
+
+func (r *objectReader) rateLimit(opts *policies.RateLimitOptions) {
+	var rate float64
+	if f, ok := r.float("rate", &rate); ok {
+		if rate <= 0 {
+			r.d.errorf(f.value, r.path+".rate", "must be positive, got %v", rate)
+		} else {
+			opts.Rate = rate
+		}
+	}
+	r.int("burst", &opts.Burst, 1)
+}
+
+// objectReader reads typed fields from an object node, recording an error for each
+// invalid value and, in done, for each field that was never read.
+type objectReader struct {
+	d    *decoder
+	n    *node
+	path string
+	read map[string]bool
+}
+
+func (d *decoder) object(n *node, path string) *objectReader {
+	return &objectReader{d: d, n: n, path: path, read: map[string]bool{}}
+}
+
+func (r *objectReader) field(key string) (field, bool) {
+	r.read[key] = true
+	for _, f := range r.n.fields {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// typed returns the field if present and of the wanted kind. Untyped text is
+// interpreted as the wanted kind if it can be.
+func (r *objectReader) typed(key string, want kind, what string) (field, bool) {
+	f, ok := r.field(key)
+	if !ok {
+		return f, false
+	}
+	if f.value.untyped && want != kindString {
+		if v, err := scalar(f.value.scalar, f.value.pos); err == nil && v.kind == want {
+			return field{key: f.key, pos: f.pos, value: v}, true
+		}
+		r.d.errorf(f.value, r.path+"."+key, "must be %s, got %q", what, f.value.scalar)
+		return f, false
+	}
+	if f.value.kind != want {
+		r.d.errorf(f.value, r.path+"."+key, "must be %s, got %s", what, f.value.kind)
+		return f, false
+	}
+	return f, true
+}
+
+func (r *objectReader) string(key string, dst *string) (field, bool) {
+	f, ok := r.typed(key, kindString, "a string")
+	if ok {
+		*dst = f.value.scalar
+	}
+	return f, ok
+}
+
+func (r *objectReader) bool(key string, dst *bool) (field, bool) {
+	f, ok := r.typed(key, kindBool, "a boolean")
+	if ok {
+		*dst = f.value.scalar == "true"
+	}
+	return f, ok
+}
+
+func (r *objectReader) float(key string, dst *float64) (field, bool) {
+	f, ok := r.typed(key, kindNumber, "a number")
+	if !ok {
+		return f, false
+	}
+	v, err := strconv.ParseFloat(f.value.scalar, 64)
+	if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+		r.d.errorf(f.value, r.path+"."+key, "invalid number %q", f.value.scalar)
+		return f, false
+	}
+	*dst = v
+	return f, true
+}
+
+func (r *objectReader) int(key string, dst *int, min int) (field, bool) {
+	f, ok := r.typed(key, kindNumber, "an integer")
+	if !ok {
+		return f, false
+	}
+	v, err := strconv.Atoi(f.value.scalar)
+	if err != nil {
+		r.d.errorf(f.value, r.path+"."+key, "must be an integer, got %s", f.value.scalar)
+		return f, false
+	}
+	if v < min {
+		r.d.errorf(f.value, r.path+"."+key, "must be at least %d, got %d", min, v)
+		return f, false
+	}
+	*dst = v
+	return f, true
+}
+
+// duration reads a positive Go duration string.
+func (r *objectReader) duration(key string, dst *time.Duration) (field, bool) {
+	return r.durationValue(key, dst, func(v time.Duration) bool { return v > 0 }, "must be positive")
+}
+
+func (r *objectReader) nonNegativeDuration(key string, dst *time.Duration) (field, bool) {
+	return r.durationValue(key, dst, func(v time.Duration) bool { return v >= 0 }, "must not be negative")
+}
+
+func (r *objectReader) durationValue(key string, dst *time.Duration, valid func(time.Duration) bool, msg string) (field, bool) {
+	f, ok := r.typed(key, kindString, `a duration string such as "250ms"`)
+	if !ok {
+		return f, false
+	}
+	v, err := time.ParseDuration(f.value.scalar)
+	if err != nil {
+		r.d.errorf(f.value, r.path+"."+key, "invalid duration %q", f.value.scalar)
+		return f, false
+	}
+	if !valid(v) {
+		r.d.errorf(f.value, r.path+"."+key, "%s, got %s", msg, f.value.scalar)
+		return f, false
+	}
+	*dst = v
+	return f, true
+}
+
+// object returns a reader for a nested object field, or nil if it is absent or invalid.
+func (r *objectReader) object(key string) *objectReader {
+	f, ok := r.typed(key, kindObject, "an object")
+	if !ok {
+		return nil
+	}
+	return r.d.object(f.value, r.path+"."+key)
+}
+
+func (r *objectReader) done() {
+	for _, f := range r.n.fields {
+		if !r.read[f.key] {
+			r.d.errorf(f.value, r.path+"."+f.key, "unknown field")
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gosentry"
+)
+
+var (
+	// ErrUnknownPipeline is returned by a watcher's pipeline when the current
+	// configuration no longer defines it.
+	ErrUnknownPipeline = errors.New("config: unknown pipeline")
+)
+
+type WatcherOptions struct {
+	// Interval is how often the file is checked for changes. Defaults to 1s.
+	Interval time.Duration
+
+	// Observer receives an EventReload for every reload attempt, in addition to the
+	// global observer.
+	Observer gosentry.Observer
+
+	// OnReload, if set, is called with the new set after every successful reload,
+	// e.g. to register new breakers and limiters with an admin.Registry.
+	OnReload func(*Set)
+}
+
+func DefaultWatcherOptions() WatcherOptions {
+	return WatcherOptions{
+		Interval: time.Second,
+	}
+}
+
+// Watcher keeps the pipelines of a configuration file up to date. Valid changes are
+// swapped in atomically, reusing the state of unchanged policies (see
+// Config.BuildFrom); invalid ones are reported and the previous version kept.
+type Watcher struct {
+	path   string
+	format Format
+	opts   WatcherOptions
+
+	current atomic.Pointer[Set]
+
+	mu          sync.Mutex // serializes reloads
+	data        []byte     // content of the last reload attempt
+	lastFailure string     // last read error, to report it only once
+}
+
+// NewWatcher loads the file at path and returns a watcher serving its pipelines.
+// The initial load must succeed. Call Run to start watching.
+func NewWatcher(path string, options WatcherOptions) (*Watcher, error) {
+	format, err := formatFor(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		path:   path,
+		format: format,
+		opts:   applyWatcherDefaults(options),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set, err := w.build(data)
+	if err != nil {
+		return nil, err
+	}
+	w.data = data
+	w.current.Store(set)
+	return w, nil
+}
+
+// Run checks the file every Interval until ctx is done, reloading it when its
+// content changes. It returns ctx.Err().
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// Reload reads and applies the file now, even if it has not changed, and returns
+// the reason it was rejected, if any.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		w.report(err)
+		return err
+	}
+	return w.apply(data)
+}
+
+func (w *Watcher) check() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		// Editors often replace files by renaming; report a missing file only once.
+		if err.Error() != w.lastFailure {
+			w.lastFailure = err.Error()
+			w.report(err)
+		}
+		return
+	}
+	w.lastFailure = ""
+
+	// A rejected version is not retried until the content changes again.
+	if bytes.Equal(data, w.data) {
+		return
+	}
+	_ = w.apply(data)
+}
+
+func (w *Watcher) apply(data []byte) error {
+	w.data = data
+
+	set, err := w.build(data)
+	if err != nil {
+		w.report(err)
+		return err
+	}
+	w.current.Store(set)
+	w.report(nil)
+
+	if w.opts.OnReload != nil {
+		w.opts.OnReload(set)
+	}
+	return nil
+}
+
+func (w *Watcher) build(data []byte) (*Set, error) {
+	cfg, err := parse(data, w.format, w.path)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.BuildFrom(w.current.Load())
+}
+
+func (w *Watcher) report(err error) {
+	ev := gosentry.Event{Kind: gosentry.EventReload, Err: err}
+	if err != nil {
+		ev.Reason = "invalid_config"
+	}
+
+	ctx := context.Background()
+	if w.opts.Observer != nil {
+		ctx = gosentry.WithObserver(ctx, w.opts.Observer)
+	}
+	gosentry.Emit(ctx, ev)
+}
+
+// Set returns the set built from the current configuration.
+func (w *Watcher) Set() *Set {
+	return w.current.Load()
+}
+
+// Pipeline returns a pipeline that runs the current policies of the named pipeline.
+// The policies are looked up at the start of each execution, so a reload takes
+// effect on the next call while calls in flight finish with the policies they
+// started with. If a reload removes the pipeline, executions fail with
+// ErrUnknownPipeline.
+func (w *Watcher) Pipeline(name string) *gosentry.Pipeline {
+	return gosentry.NewPipeline(name, func(next gosentry.Handler) gosentry.Handler {
+		return func(ctx context.Context) (any, error) {
+			chain, ok := w.current.Load().chains[name]
+			if !ok {
+				return nil, fmt.Errorf("%w %q", ErrUnknownPipeline, name)
+			}
+			h := next
+			for i := len(chain) - 1; i >= 0; i-- {
+				h = chain[i](h)
+			}
+			return h(ctx)
+		}
+	})
+}
+
+func applyWatcherDefaults(options WatcherOptions) WatcherOptions {
+	defaults := DefaultWatcherOptions()
+
+	if options.Interval <= 0 {
+		options.Interval = defaults.Interval
+	}
+
+	return options
+}
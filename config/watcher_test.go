@@ -0,0 +1,172 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+type reloadRecorder struct {
+	mu     sync.Mutex
+	events []gosentry.Event
+}
+
+func (r *reloadRecorder) Observe(ev gosentry.Event) {
+	if ev.Kind != gosentry.EventReload {
+		return
+	}
+	r.mu.Lock()
+	r.events = append(r.events, ev)
+	r.mu.Unlock()
+}
+
+func (r *reloadRecorder) all() []gosentry.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]gosentry.Event(nil), r.events...)
+}
+
+func writeConfig(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const breakerConfig = `
+pipelines:
+  payments:
+    - type: circuit_breaker
+      name: payments-cb
+      failure_threshold: 1
+`
+
+func TestWatcher_ReloadPreservesStateAndRejectsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gosentry.yaml")
+	writeConfig(t, path, breakerConfig)
+
+	rec := &reloadRecorder{}
+	var reloaded []*Set
+	w, err := NewWatcher(path, WatcherOptions{
+		Observer: rec,
+		OnReload: func(s *Set) { reloaded = append(reloaded, s) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	payments := w.Pipeline("payments")
+	boom := errors.New("boom")
+	payments.Execute(context.Background(), func(ctx context.Context) (any, error) { return nil, boom })
+	breaker := w.Set().Breakers()[0]
+	if breaker.State() != policies.CircuitOpen {
+		t.Fatalf("expected open breaker, got %s", breaker.State())
+	}
+
+	// Adding a policy keeps the breaker and its state.
+	writeConfig(t, path, breakerConfig+`    - type: retry
+      max_attempts: 2
+`)
+	w.check()
+	if got := w.Set().Breakers()[0]; got != breaker {
+		t.Fatal("expected the breaker to survive the reload")
+	}
+	if len(w.Set().chains["payments"]) != 2 || len(reloaded) != 1 {
+		t.Fatalf("expected the new policy to be swapped in, got %d policies", len(w.Set().chains["payments"]))
+	}
+	_, err = payments.Execute(context.Background(), func(ctx context.Context) (any, error) { return "ok", nil })
+	if !errors.Is(err, policies.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen from the preserved breaker, got %v", err)
+	}
+
+	// An invalid version is rejected, reported once and the previous one kept.
+	current := w.Set()
+	writeConfig(t, path, "pipelines:\n  payments:\n    - type: retry\n      max_attempts: 0\n")
+	w.check()
+	w.check()
+	if w.Set() != current {
+		t.Fatal("expected the previous configuration to be kept")
+	}
+
+	events := rec.all()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 reload events, got %d: %+v", len(events), events)
+	}
+	if events[0].Err != nil {
+		t.Errorf("expected first reload to succeed, got %v", events[0].Err)
+	}
+	var list ErrorList
+	if !errors.As(events[1].Err, &list) || events[1].Reason != "invalid_config" {
+		t.Errorf("expected invalid_config with an ErrorList, got %q %v", events[1].Reason, events[1].Err)
+	}
+
+	// Unchanged content is not reloaded by check, but Reload forces it.
+	if err := w.Reload(); err == nil {
+		t.Fatal("expected Reload to report the invalid file")
+	}
+}
+
+func TestWatcher_RemovedPipeline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gosentry.json")
+	writeConfig(t, path, `{"pipelines": {"a": [], "b": []}}`)
+
+	w, err := NewWatcher(path, WatcherOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := w.Pipeline("b")
+
+	writeConfig(t, path, `{"pipelines": {"a": []}}`)
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.Execute(context.Background(), func(ctx context.Context) (any, error) { return "ok", nil })
+	if !errors.Is(err, ErrUnknownPipeline) {
+		t.Fatalf("expected ErrUnknownPipeline, got %v", err)
+	}
+}
+
+func TestWatcher_InitialLoadMustSucceed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gosentry.json")
+	writeConfig(t, path, `{"pipelines": {"a": [{"type": "nope"}]}}`)
+
+	if _, err := NewWatcher(path, WatcherOptions{}); err == nil {
+		t.Fatal("expected error for invalid initial configuration")
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gosentry.json")
+	writeConfig(t, path, `{"pipelines": {"a": []}}`)
+
+	rec := &reloadRecorder{}
+	w, err := NewWatcher(path, WatcherOptions{Interval: time.Millisecond, Observer: rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	writeConfig(t, path, `{"pipelines": {"a": [], "b": []}}`)
+	deadline := time.Now().Add(2 * time.Second)
+	for len(w.Set().Names()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for reload")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML parses the block-style subset of YAML needed for configuration files:
+// nested mappings and sequences, plain and quoted scalars, empty flow collections
+// ("[]" and "{}") and comments. Anchors, tags, multi-line scalars, multiple
+// documents and non-empty flow collections are rejected.
+func parseYAML(data []byte) (*node, error) {
+	lines, err := yamlLines(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return &node{kind: kindNull, pos: Position{Line: 1, Col: 1}}, nil
+	}
+
+	p := &yamlParser{lines: lines}
+	n, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(p.lines) {
+		l := p.lines[p.i]
+		return nil, &Error{Pos: l.pos(0), Msg: "unexpected indentation"}
+	}
+	return n, nil
+}
+
+// yamlLine is a non-empty, comment-stripped source line.
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+func (l yamlLine) pos(offset int) Position {
+	return Position{Line: l.num, Col: l.indent + offset + 1}
+}
+
+func yamlLines(src string) ([]yamlLine, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(src, "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		num := i + 1
+
+		indent := 0
+		for indent < len(raw) && raw[indent] == ' ' {
+			indent++
+		}
+		if indent < len(raw) && raw[indent] == '\t' {
+			return nil, &Error{Pos: Position{Line: num, Col: indent + 1}, Msg: "tabs are not allowed for indentation"}
+		}
+
+		text := strings.TrimRight(stripComment(raw[indent:]), " ")
+		if text == "" {
+			continue
+		}
+		if text == "---" || text == "..." {
+			if len(lines) == 0 && text == "---" {
+				continue
+			}
+			return nil, &Error{Pos: Position{Line: num, Col: 1}, Msg: "multiple documents are not supported"}
+		}
+		lines = append(lines, yamlLine{num: num, indent: indent, text: text})
+	}
+	return lines, nil
+}
+
+// stripComment removes a trailing comment, ignoring '#' inside quotes or not
+// preceded by whitespace.
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || s[i-1] == ' '):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	i     int
+}
+
+// block parses the mapping or sequence whose entries start at indent.
+func (p *yamlParser) block(indent int) (*node, error) {
+	l := p.lines[p.i]
+	if isSeqEntry(l.text) {
+		return p.sequence(indent, false)
+	}
+	if _, _, ok := splitKey(l.text); ok {
+		return p.mapping(indent)
+	}
+
+	// A lone scalar document.
+	n, err := scalar(l.text, l.pos(0))
+	if err != nil {
+		return nil, err
+	}
+	p.i++
+	return n, nil
+}
+
+func isSeqEntry(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// sequence parses the entries at indent. A compact sequence sits at the same
+// indentation as its parent key and ends at the next non-entry line.
+func (p *yamlParser) sequence(indent int, compact bool) (*node, error) {
+	n := &node{kind: kindArray, pos: p.lines[p.i].pos(0)}
+
+	for p.i < len(p.lines) {
+		l := p.lines[p.i]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, &Error{Pos: l.pos(0), Msg: "unexpected indentation"}
+		}
+		if !isSeqEntry(l.text) {
+			if compact {
+				break
+			}
+			return nil, &Error{Pos: l.pos(0), Msg: "expected '- ' list entry"}
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		if rest == "" {
+			item, err := p.nested(indent, l)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+			continue
+		}
+
+		// "- key: value" starts a mapping whose entries are aligned with key.
+		offset := len(l.text) - len(rest)
+		if _, _, ok := splitKey(rest); ok && !isQuoted(rest) {
+			p.lines[p.i] = yamlLine{num: l.num, indent: l.indent + offset, text: rest}
+			item, err := p.mapping(l.indent + offset)
+			if err != nil {
+				return nil, err
+			}
+			n.items = append(n.items, item)
+			continue
+		}
+
+		item, err := scalar(rest, l.pos(offset))
+		if err != nil {
+			return nil, err
+		}
+		n.items = append(n.items, item)
+		p.i++
+	}
+	return n, nil
+}
+
+func (p *yamlParser) mapping(indent int) (*node, error) {
+	n := &node{kind: kindObject, pos: p.lines[p.i].pos(0)}
+
+	for p.i < len(p.lines) {
+		l := p.lines[p.i]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, &Error{Pos: l.pos(0), Msg: "unexpected indentation"}
+		}
+
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return nil, &Error{Pos: l.pos(0), Msg: "expected 'key: value'"}
+		}
+		for _, f := range n.fields {
+			if f.key == key {
+				return nil, &Error{Pos: l.pos(0), Msg: fmt.Sprintf("duplicate key %q", key)}
+			}
+		}
+
+		var value *node
+		if rest == "" {
+			var err error
+			value, err = p.nested(indent, l)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			var err error
+			value, err = scalar(rest, l.pos(len(l.text)-len(rest)))
+			if err != nil {
+				return nil, err
+			}
+			p.i++
+		}
+		n.fields = append(n.fields, field{key: key, pos: l.pos(0), value: value})
+	}
+	return n, nil
+}
+
+// nested parses the block under the entry on line l, or returns null if the entry
+// has no nested block. A sequence may sit at the same indentation as its key.
+func (p *yamlParser) nested(indent int, l yamlLine) (*node, error) {
+	p.i++
+	if p.i < len(p.lines) {
+		next := p.lines[p.i]
+		if next.indent > indent {
+			return p.block(next.indent)
+		}
+		if next.indent == indent && isSeqEntry(next.text) && !isSeqEntry(l.text) {
+			return p.sequence(indent, true)
+		}
+	}
+	return &node{kind: kindNull, pos: l.pos(len(l.text))}, nil
+}
+
+// splitKey splits "key: value" or "key:". Keys may be quoted.
+func splitKey(text string) (key, rest string, ok bool) {
+	if isQuoted(text) {
+		q := text[0]
+		end := strings.IndexByte(text[1:], q)
+		if end < 0 {
+			return "", "", false
+		}
+		after := text[end+2:]
+		if after != ":" && !strings.HasPrefix(after, ": ") {
+			return "", "", false
+		}
+		k, err := unquote(text[:end+2])
+		if err != nil {
+			return "", "", false
+		}
+		return k, strings.TrimSpace(strings.TrimPrefix(after, ":")), true
+	}
+
+	i := strings.Index(text, ": ")
+	if i < 0 {
+		if strings.HasSuffix(text, ":") {
+			return text[:len(text)-1], "", true
+		}
+		return "", "", false
+	}
+	return text[:i], strings.TrimSpace(text[i+2:]), true
+}
+
+func isQuoted(s string) bool {
+	return len(s) > 0 && (s[0] == '"' || s[0] == '\'')
+}
+
+func unquote(s string) (string, error) {
+	if s[0] == '\'' {
+		if len(s) < 2 || s[len(s)-1] != '\'' {
+			return "", fmt.Errorf("unterminated string")
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	return strconv.Unquote(s)
+}
+
+func scalar(text string, pos Position) (*node, error) {
+	switch {
+	case isQuoted(text):
+		s, err := unquote(text)
+		if err != nil {
+			return nil, &Error{Pos: pos, Msg: fmt.Sprintf("invalid quoted string %s", text)}
+		}
+		return &node{kind: kindString, pos: pos, scalar: s}, nil
+	case text == "[]":
+		return &node{kind: kindArray, pos: pos}, nil
+	case text == "{}":
+		return &node{kind: kindObject, pos: pos}, nil
+	case strings.ContainsAny(text[:1], "[{&*!|>%@`"):
+		return nil, &Error{Pos: pos, Msg: fmt.Sprintf("unsupported YAML syntax %q", text)}
+	}
+
+	switch text {
+	case "null", "Null", "NULL", "~":
+		return &node{kind: kindNull, pos: pos}, nil
+	case "true", "True", "TRUE":
+		return &node{kind: kindBool, pos: pos, scalar: "true"}, nil
+	case "false", "False", "FALSE":
+		return &node{kind: kindBool, pos: pos, scalar: "false"}, nil
+	}
+	if _, err := strconv.ParseFloat(text, 64); err == nil {
+		return &node{kind: kindNumber, pos: pos, scalar: text}, nil
+	}
+	return &node{kind: kindString, pos: pos, scalar: text}, nil
+}
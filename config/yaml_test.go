@@ -0,0 +1,89 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseYAML_Structure(t *testing.T) {
+	src := `
+# leading comment
+---
+pipelines:
+  api:
+  - type: "retry"   # same-indent sequence
+    name: 'it''s'
+  - type: timeout
+  empty: []
+  nothing:
+`
+	n, err := parseYAML([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pipelines := n.fields[0].value
+	if len(pipelines.fields) != 3 {
+		t.Fatalf("expected 3 fields, got %d", len(pipelines.fields))
+	}
+
+	api := pipelines.fields[0].value
+	if api.kind != kindArray || len(api.items) != 2 {
+		t.Fatalf("unexpected api node: %+v", api)
+	}
+	first := api.items[0]
+	if first.fields[0].value.scalar != "retry" || first.fields[1].value.scalar != "it's" {
+		t.Errorf("unexpected first item: %+v %+v", first.fields[0].value, first.fields[1].value)
+	}
+	if got := first.pos.String(); got != "6:5" {
+		t.Errorf("expected first item at 6:5, got %s", got)
+	}
+
+	if pipelines.fields[1].value.kind != kindArray || pipelines.fields[2].value.kind != kindNull {
+		t.Errorf("unexpected empty values: %s %s", pipelines.fields[1].value.kind, pipelines.fields[2].value.kind)
+	}
+}
+
+func TestParseYAML_Scalars(t *testing.T) {
+	n, err := parseYAML([]byte("a: 1.5\nb: true\nc: 250ms\nd: ~\ne: \"x # y\"\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []struct {
+		kind   kind
+		scalar string
+	}{
+		{kindNumber, "1.5"},
+		{kindBool, "true"},
+		{kindString, "250ms"},
+		{kindNull, ""},
+		{kindString, "x # y"},
+	}
+	for i, w := range want {
+		v := n.fields[i].value
+		if v.kind != w.kind || v.scalar != w.scalar {
+			t.Errorf("field %s: expected %s %q, got %s %q", n.fields[i].key, w.kind, w.scalar, v.kind, v.scalar)
+		}
+	}
+}
+
+func TestParseYAML_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		src  string
+		want string
+	}{
+		{"a:\n\tb: 1\n", "2:1: tabs are not allowed"},
+		{"a: 1\na: 2\n", `2:1: duplicate key "a"`},
+		{"a: 1\n   b: 2\n", "2:4: unexpected indentation"},
+		{"a: [1, 2]\n", "unsupported YAML syntax"},
+		{"a: &x 1\n", "unsupported YAML syntax"},
+		{"a: 1\n---\nb: 2\n", "multiple documents"},
+		{"- a\nb: 1\n", "expected '- ' list entry"},
+	} {
+		_, err := parseYAML([]byte(tc.src))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: expected error containing %q, got %v", tc.src, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package gosentry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kind classifies a policy. It is reported in Event.PolicyKind and used by Lint.
+type Kind string
+
+const (
+	KindRetry          Kind = "retry"
+	KindCircuitBreaker Kind = "circuit_breaker"
+	KindTimeout        Kind = "timeout"
+	KindRateLimit      Kind = "rate_limit"
+	KindBulkhead       Kind = "bulkhead"
+	KindFailover       Kind = "failover"
+	KindLoadBalancer   Kind = "load_balancer"
+	KindCoalesce       Kind = "coalesce"
+	KindCache          Kind = "cache"
+
+	// KindUnknown stands for a policy that carries no Descriptor.
+	KindUnknown Kind = "unknown"
+)
+
+// Descriptor identifies a policy without running it.
+type Descriptor struct {
+	Kind Kind
+	Name string
+
+	// Options are the effective settings of the policy, defaults applied, keyed by
+	// the snake_case field name used in configuration files.
+	Options []Attribute
+
+	// Children describes the policies a composite policy runs inside itself, such
+	// as the per-endpoint circuit breakers of a failover.
+	Children []Descriptor
+}
+
+// Option returns the value of the named option.
+func (d Descriptor) Option(key string) (any, bool) {
+	for _, a := range d.Options {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return nil, false
+}
+
+// String renders d on one line, e.g. `retry "payments" max_attempts=3 backoff=exponential`.
+func (d Descriptor) String() string {
+	if d.Kind == KindUnknown {
+		return "(undescribed policy)"
+	}
+	var b strings.Builder
+	b.WriteString(string(d.Kind))
+	if d.Name != "" {
+		fmt.Fprintf(&b, " %q", d.Name)
+	}
+	for _, a := range d.Options {
+		fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+	}
+	return b.String()
+}
+
+// MarshalJSON renders options as an object, with durations in Go syntax ("250ms").
+func (d Descriptor) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Kind     Kind           `json:"kind"`
+		Name     string         `json:"name,omitempty"`
+		Options  map[string]any `json:"options,omitempty"`
+		Children []Descriptor   `json:"children,omitempty"`
+	}{Kind: d.Kind, Name: d.Name, Children: d.Children}
+
+	if len(d.Options) > 0 {
+		out.Options = make(map[string]any, len(d.Options))
+		for _, a := range d.Options {
+			v := a.Value
+			if dur, ok := v.(time.Duration); ok {
+				v = dur.String()
+			}
+			out.Options[a.Key] = v
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON reads the form written by MarshalJSON. Options come back sorted by
+// key, with durations left as strings and numbers as float64.
+func (d *Descriptor) UnmarshalJSON(b []byte) error {
+	var in struct {
+		Kind     Kind           `json:"kind"`
+		Name     string         `json:"name"`
+		Options  map[string]any `json:"options"`
+		Children []Descriptor   `json:"children"`
+	}
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+
+	*d = Descriptor{Kind: in.Kind, Name: in.Name, Children: in.Children}
+	keys := make([]string, 0, len(in.Options))
+	for k := range in.Options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		d.Options = append(d.Options, Attr(k, in.Options[k]))
+	}
+	return nil
+}
+
+// WithDescriptor returns p labelled with d, so that Describe and Lint can see what
+// it is. The returned policy behaves exactly like p.
+func WithDescriptor(p Policy, d Descriptor) Policy {
+	return WithDescriber(p, func() Descriptor { return d })
+}
+
+// WithDescriber is like WithDescriptor for policies whose settings change at
+// runtime: describe is called each time the policy is described.
+func WithDescriber(p Policy, describe func() Descriptor) Policy {
+	return func(next Handler) Handler {
+		h := p(next)
+		return func(ctx context.Context) (any, error) {
+			if pr, ok := ctx.Value(probeKey{}).(*probe); ok {
+				pr.found = append(pr.found, describe())
+				return next(ctx)
+			}
+			return h(ctx)
+		}
+	}
+}
+
+type probeKey struct{}
+
+// probe collects the descriptors of the policies a handler passes through.
+type probe struct {
+	found []Descriptor
+}
+
+// describe returns the descriptors found in each of policies. Each policy is called
+// with an already cancelled probe context: labelled policies record themselves and
+// pass the probe on without doing any work, and policies that wrap others (such as
+// a pipeline's) expose the labelled policies inside them. Unlabelled policies find
+// nothing; they normally return at once on the cancelled context.
+func describe(policies []Policy) [][]Descriptor {
+	out := make([][]Descriptor, len(policies))
+	for i, p := range policies {
+		pr := &probe{}
+		ctx, cancel := context.WithCancel(context.WithValue(context.Background(), probeKey{}, pr))
+		cancel()
+		p(func(context.Context) (any, error) { return nil, nil })(ctx)
+		out[i] = pr.found
+	}
+	return out
+}
+
+// DescriptorOf returns the descriptor of p, if it has one. For a policy wrapping
+// several labelled policies, it returns the outermost. Like Describe, it runs p if
+// p is not labelled.
+func DescriptorOf(p Policy) (Descriptor, bool) {
+	found := describe([]Policy{p})[0]
+	if len(found) == 0 {
+		return Descriptor{}, false
+	}
+	return found[0], true
+}
+
+// PipelineDescription is the policy tree of a pipeline.
+type PipelineDescription struct {
+	Name string `json:"name"`
+
+	// Policies are listed outermost first: each wraps the ones after it.
+	Policies []Descriptor `json:"policies"`
+}
+
+// String renders the tree as indented text for logs:
+//
+//	pipeline "payments"
+//	├── timeout "payments.timeout" duration=2s
+//	└── retry "payments.retry" max_attempts=3 ...
+func (d PipelineDescription) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "pipeline %q", d.Name)
+	writeTree(&b, d.Policies, "")
+	return b.String()
+}
+
+func writeTree(b *strings.Builder, ds []Descriptor, indent string) {
+	for i, child := range ds {
+		branch, next := "├── ", "│   "
+		if i == len(ds)-1 {
+			branch, next = "└── ", "    "
+		}
+		b.WriteString("\n" + indent + branch + child.String())
+		writeTree(b, child.Children, indent+next)
+	}
+}
+
+// Describe returns the pipeline's policy tree. Policies without a Descriptor appear
+// with KindUnknown.
+//
+// Describing calls each policy once with a no-op next handler and an already
+// cancelled context. Labelled policies only record themselves, but a policy without
+// a Descriptor really runs: it normally returns at once on the cancelled context,
+// yet anything it does before checking the context, such as logging or counting
+// calls, happens. Label such policies with WithDescriptor to avoid that.
+func (p *Pipeline) Describe() PipelineDescription {
+	d := PipelineDescription{Name: p.name, Policies: []Descriptor{}}
+	for _, found := range describe(p.policies) {
+		if len(found) == 0 {
+			found = []Descriptor{{Kind: KindUnknown}}
+		}
+		d.Policies = append(d.Policies, found...)
+	}
+	return d
+}
@@ -0,0 +1,133 @@
+package gosentry
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func described(kind Kind, name string, calls *int) Policy {
+	return WithDescriptor(func(next Handler) Handler {
+		return func(ctx context.Context) (any, error) {
+			*calls++
+			return next(ctx)
+		}
+	}, Descriptor{Kind: kind, Name: name})
+}
+
+func TestWithDescriptor_BehavesLikePolicy(t *testing.T) {
+	calls := 0
+	p := described(KindRetry, "r", &calls)
+
+	res, err := Execute(context.Background(), func(ctx context.Context) (any, error) { return "ok", nil }, p)
+	if err != nil || res != "ok" || calls != 1 {
+		t.Fatalf("expected the wrapped policy to run once, got %v %v calls=%d", res, err, calls)
+	}
+}
+
+func TestDescriptorOf(t *testing.T) {
+	calls := 0
+	d, ok := DescriptorOf(described(KindTimeout, "t", &calls))
+	if !ok || d.Kind != KindTimeout || d.Name != "t" {
+		t.Fatalf("unexpected descriptor %+v %v", d, ok)
+	}
+	if calls != 0 {
+		t.Fatalf("expected describing not to run the policy, got %d calls", calls)
+	}
+
+	plain := func(next Handler) Handler { return next }
+	if _, ok := DescriptorOf(plain); ok {
+		t.Fatal("expected no descriptor for an unlabelled policy")
+	}
+}
+
+func TestDescribe_SeesThroughWrappers(t *testing.T) {
+	calls := 0
+	inner := []Policy{described(KindRetry, "r", &calls), described(KindCircuitBreaker, "cb", &calls)}
+	wrapper := func(next Handler) Handler {
+		return func(ctx context.Context) (any, error) {
+			return Execute(ctx, next, inner...)
+		}
+	}
+
+	got := describe([]Policy{described(KindTimeout, "t", &calls), wrapper})
+	if len(got) != 2 || len(got[0]) != 1 || len(got[1]) != 2 ||
+		got[0][0].Name != "t" || got[1][0].Name != "r" || got[1][1].Name != "cb" {
+		t.Fatalf("unexpected descriptors: %+v", got)
+	}
+	if calls != 0 {
+		t.Fatalf("expected no policy to run, got %d calls", calls)
+	}
+}
+
+func TestPipeline_Describe(t *testing.T) {
+	calls := 0
+	retry := WithDescriptor(func(next Handler) Handler { return next }, Descriptor{
+		Kind:    KindRetry,
+		Name:    "payments.retry",
+		Options: []Attribute{Attr("max_attempts", 3), Attr("initial_delay", 100*time.Millisecond)},
+	})
+	plain := func(next Handler) Handler { return next }
+	p := NewPipeline("payments", described(KindTimeout, "payments.timeout", &calls), retry, plain)
+
+	d := p.Describe()
+	if d.Name != "payments" || len(d.Policies) != 3 {
+		t.Fatalf("unexpected description: %+v", d)
+	}
+	if d.Policies[2].Kind != KindUnknown {
+		t.Fatalf("expected an unknown placeholder for the plain policy, got %+v", d.Policies[2])
+	}
+	if v, ok := d.Policies[1].Option("max_attempts"); !ok || v != 3 {
+		t.Fatalf("expected max_attempts=3, got %v %v", v, ok)
+	}
+
+	want := `pipeline "payments"
+├── timeout "payments.timeout"
+├── retry "payments.retry" max_attempts=3 initial_delay=100ms
+└── (undescribed policy)`
+	if got := d.String(); got != want {
+		t.Fatalf("unexpected text:\n%s\nwant:\n%s", got, want)
+	}
+	if calls != 0 {
+		t.Fatalf("expected describing not to run policies, got %d calls", calls)
+	}
+}
+
+func TestPipelineDescription_Children(t *testing.T) {
+	d := PipelineDescription{Name: "p", Policies: []Descriptor{
+		{Kind: "failover", Name: "f", Children: []Descriptor{
+			{Kind: KindCircuitBreaker, Name: "a"},
+			{Kind: KindCircuitBreaker, Name: "b"},
+		}},
+		{Kind: KindTimeout, Name: "t"},
+	}}
+
+	want := `pipeline "p"
+├── failover "f"
+│   ├── circuit_breaker "a"
+│   └── circuit_breaker "b"
+└── timeout "t"`
+	if got := d.String(); got != want {
+		t.Fatalf("unexpected text:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPipelineDescription_JSON(t *testing.T) {
+	d := PipelineDescription{Name: "p", Policies: []Descriptor{{
+		Kind:     KindRetry,
+		Name:     "r",
+		Options:  []Attribute{Attr("max_attempts", 3), Attr("max_delay", 5*time.Second)},
+		Children: []Descriptor{{Kind: KindTimeout, Name: "t"}},
+	}}}
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"p","policies":[{"kind":"retry","name":"r","options":{"max_attempts":3,"max_delay":"5s"},` +
+		`"children":[{"kind":"timeout","name":"t"}]}]}`
+	if string(b) != want {
+		t.Fatalf("unexpected JSON:\n%s\nwant:\n%s", b, want)
+	}
+}
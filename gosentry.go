@@ -1,6 +1,9 @@
 package gosentry
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Handler -> Policy -> Execute
 type Handler func(ctx context.Context) (any, error)
@@ -12,5 +15,26 @@ func Execute(ctx context.Context, handler Handler, policies ...Policy) (any, err
 	for i := len(policies) - 1; i >= 0; i-- {
 		h = policies[i](h)
 	}
-	return h(ctx)
+
+	var span Span
+	if tracerFrom(ctx) != nil {
+		var attrs []Attribute
+		if info := execInfoFrom(ctx); info != nil && info.pipeline != "" {
+			attrs = append(attrs, Attr("gosentry.pipeline", info.pipeline))
+		}
+		ctx, span = StartSpan(ctx, "gosentry.execute", attrs...)
+		defer span.End()
+	}
+
+	if !Observed(ctx) {
+		return h(ctx)
+	}
+
+	start := time.Now()
+	result, err := h(ctx)
+	if err != nil && span != nil {
+		span.RecordError(err)
+	}
+	Emit(ctx, Event{Kind: EventCompleted, Duration: time.Since(start), Err: err})
+	return result, err
 }
@@ -0,0 +1,404 @@
+package gosentryhttp
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+type MiddlewareOptions struct {
+	// RetryAfter is sent in the Retry-After header of 503 responses for requests
+	// rejected by an open circuit breaker or a full bulkhead. Defaults to 1s.
+	RetryAfter time.Duration
+
+	// MaxKeys bounds the number of per-key pipelines KeyedMiddleware keeps. The least
+	// recently used one is dropped first, losing its state. Defaults to 10000.
+	MaxKeys int
+
+	// OnReject writes the response for a request the pipeline rejected or abandoned.
+	// Retry-After and RateLimit-* headers are already set. Defaults to WriteRejection.
+	OnReject func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+func DefaultMiddlewareOptions() MiddlewareOptions {
+	return MiddlewareOptions{
+		RetryAfter: time.Second,
+		MaxKeys:    10000,
+		OnReject:   WriteRejection,
+	}
+}
+
+func applyMiddlewareDefaults(options MiddlewareOptions) MiddlewareOptions {
+	defaults := DefaultMiddlewareOptions()
+
+	if options.RetryAfter <= 0 {
+		options.RetryAfter = defaults.RetryAfter
+	}
+	if options.MaxKeys <= 0 {
+		options.MaxKeys = defaults.MaxKeys
+	}
+	if options.OnReject == nil {
+		options.OnReject = defaults.OnReject
+	}
+
+	return options
+}
+
+// Middleware runs every request through p before it reaches the wrapped handler.
+// Requests the pipeline rejects get a 429 or 503 response (see StatusCode) with a
+// Retry-After header; 429 responses also carry RateLimit-Limit, RateLimit-Remaining
+// and RateLimit-Reset headers describing the limiter that rejected them.
+//
+// A handler that responds with a 5xx status is reported to the pipeline as a
+// permanent failure, so circuit breakers and metrics see it but retries do not run
+// the handler again; nor do they once an abandoned handler has started responding. If a Timeout policy abandons the
+// handler before it has written a response, the rejection is written instead and
+// later writes by the handler are discarded.
+//
+// A panic in the handler is raised again on the request's goroutine, where
+// net/http recovers it, even if a Timeout policy ran the handler elsewhere. Once
+// the middleware returns, writes by a handler the pipeline abandoned fail with
+// http.ErrHandlerTimeout.
+//
+// For per-route pipelines, wrap each route's handler with its own middleware.
+func Middleware(p *gosentry.Pipeline, options MiddlewareOptions) func(http.Handler) http.Handler {
+	opts := applyMiddlewareDefaults(options)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			serve(p, opts, next, w, r)
+		})
+	}
+}
+
+// KeyedMiddleware is like Middleware with a separate pipeline for each key, such
+// as a client IP (see ClientIP and Header), built by newPipeline on first use. This
+// gives each client its own rate limiter or bulkhead.
+func KeyedMiddleware(key func(r *http.Request) string, newPipeline func(key string) *gosentry.Pipeline, options MiddlewareOptions) func(http.Handler) http.Handler {
+	opts := applyMiddlewareDefaults(options)
+	cache := &pipelineCache{
+		max:   opts.MaxKeys,
+		order: list.New(),
+		items: map[string]*list.Element{},
+		build: newPipeline,
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			serve(cache.get(key(r)), opts, next, w, r)
+		})
+	}
+}
+
+// ClientIP returns the host part of r.RemoteAddr. Behind a proxy, use Header with
+// the header the proxy sets instead.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Header returns a key function reading the named request header, e.g. an API key
+// or X-Real-IP set by a trusted proxy. Requests without it are keyed by ClientIP.
+func Header(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(name); v != "" {
+			return v
+		}
+		return ClientIP(r)
+	}
+}
+
+// StatusCode returns the response status for a request that failed with err:
+// 429 for rate limiting; 503 for open circuits, full bulkheads and timeouts; 500
+// otherwise.
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, policies.ErrRateLimitExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, policies.ErrCircuitOpen),
+		errors.Is(err, policies.ErrCircuitHalfOpenBusy),
+		errors.Is(err, policies.ErrBulkheadFull),
+		errors.Is(err, policies.ErrDeadlineBudgetExhausted),
+		errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// WriteRejection writes StatusCode(err) with its status text as a plain-text body.
+func WriteRejection(w http.ResponseWriter, r *http.Request, err error) {
+	code := StatusCode(err)
+	http.Error(w, http.StatusText(code), code)
+}
+
+// retryable reports whether a rejection is worth retrying after a pause.
+func retryable(err error) bool {
+	return errors.Is(err, policies.ErrCircuitOpen) ||
+		errors.Is(err, policies.ErrCircuitHalfOpenBusy) ||
+		errors.Is(err, policies.ErrBulkheadFull)
+}
+
+// handlerError reports a 5xx response written by the wrapped handler.
+type handlerError struct {
+	code int
+}
+
+func (e *handlerError) Error() string {
+	return "gosentryhttp: handler responded " + strconv.Itoa(e.code) + " " + http.StatusText(e.code)
+}
+
+// errHandlerPanicked stands in for a handler panic inside the pipeline. It is
+// permanent so that no retry runs the handler again.
+var errHandlerPanicked = policies.Permanent(errors.New("gosentryhttp: handler panicked"))
+
+// errResponseStarted stops a retry from running the handler again once an earlier
+// run, possibly abandoned by a Timeout policy, has started the response.
+var errResponseStarted = policies.Permanent(errors.New("gosentryhttp: response already started"))
+
+func serve(p *gosentry.Pipeline, opts MiddlewareOptions, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	q := &quota{}
+	gw := &guardedWriter{w: w, header: w.Header().Clone()}
+	// Once serve returns, the response belongs to net/http again; an abandoned
+	// handler must not touch it.
+	defer gw.detach()
+
+	// A Timeout policy runs the handler on another goroutine, out of reach of
+	// net/http's recovery, so a panic is carried back and raised here instead.
+	panicked := make(chan any, 1)
+	_, err := p.Execute(gosentry.WithObserver(r.Context(), q), func(ctx context.Context) (_ any, err error) {
+		if gw.status() != 0 {
+			return nil, errResponseStarted
+		}
+		q.stop()
+		defer func() {
+			if v := recover(); v != nil {
+				if v != http.ErrAbortHandler {
+					v = fmt.Sprintf("%v\n\n%s", v, strings.TrimSpace(string(debug.Stack())))
+				}
+				panicked <- v
+				err = errHandlerPanicked
+			}
+		}()
+		next.ServeHTTP(gw, r.WithContext(ctx))
+		if code := gw.status(); code >= 500 {
+			// The response is sent, so retrying cannot change it.
+			return nil, policies.Permanent(&handlerError{code: code})
+		}
+		return nil, nil
+	})
+	select {
+	case v := <-panicked:
+		panic(v)
+	default:
+	}
+	if err == nil {
+		// The handler returned; send its headers even if it wrote nothing.
+		gw.WriteHeader(http.StatusOK)
+		return
+	}
+	if !gw.claim() {
+		// The handler has already responded.
+		return
+	}
+	if r.Context().Err() != nil {
+		// The client is gone.
+		return
+	}
+
+	header := w.Header()
+	if errors.Is(err, policies.ErrRateLimitExceeded) {
+		q.setHeaders(header)
+	} else if retryable(err) {
+		header.Set("Retry-After", strconv.Itoa(seconds(opts.RetryAfter.Seconds())))
+	}
+	opts.OnReject(w, r, err)
+}
+
+// quota records the rate limiter rejection of one request, if any. Events after the
+// wrapped handler starts come from limiters used inside it and are ignored.
+type quota struct {
+	mu      sync.Mutex
+	stopped bool
+	bucket  gosentry.Event
+	seen    bool
+}
+
+func (q *quota) Observe(ev gosentry.Event) {
+	if ev.Kind != gosentry.EventRejected || ev.PolicyKind != string(gosentry.KindRateLimit) {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stopped {
+		return
+	}
+	q.bucket, q.seen = ev, true
+}
+
+func (q *quota) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.stopped = true
+}
+
+// setHeaders sets Retry-After and the RateLimit-* headers from the recorded
+// rejection. Without one, it asks the client to retry after a second.
+func (q *quota) setHeaders(h http.Header) {
+	q.mu.Lock()
+	seen, b := q.seen, q.bucket
+	q.mu.Unlock()
+
+	if !seen || b.Rate <= 0 || b.Burst <= 0 {
+		h.Set("Retry-After", "1")
+		return
+	}
+	h.Set("RateLimit-Limit", strconv.Itoa(b.Burst))
+	h.Set("RateLimit-Remaining", strconv.Itoa(int(math.Floor(b.Tokens))))
+	h.Set("RateLimit-Reset", strconv.Itoa(seconds((float64(b.Burst)-b.Tokens)/b.Rate)))
+	// Whole seconds until the limiter has a token again.
+	h.Set("Retry-After", strconv.Itoa(max(1, seconds((1-b.Tokens)/b.Rate))))
+}
+
+// seconds rounds s up to whole seconds, as HTTP headers expect.
+func seconds(s float64) int {
+	if s <= 0 {
+		return 0
+	}
+	return int(math.Ceil(s))
+}
+
+// guardedWriter lets the middleware take over the response if the pipeline gives
+// up on the handler, possibly while the handler is still running. The handler gets
+// its own header map so the two never share one.
+type guardedWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu   sync.Mutex
+	code int
+
+	// detached is set once the handler may no longer write: when the middleware
+	// claims the response or serve returns. Later writes are discarded.
+	detached bool
+}
+
+func (g *guardedWriter) Header() http.Header {
+	return g.header
+}
+
+func (g *guardedWriter) WriteHeader(code int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.writeHeaderLocked(code)
+}
+
+func (g *guardedWriter) writeHeaderLocked(code int) {
+	if g.detached || g.code != 0 {
+		return
+	}
+	g.code = code
+	dst := g.w.Header()
+	for k := range dst {
+		if _, ok := g.header[k]; !ok {
+			delete(dst, k)
+		}
+	}
+	for k, v := range g.header {
+		dst[k] = v
+	}
+	g.w.WriteHeader(code)
+}
+
+func (g *guardedWriter) Write(b []byte) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.detached {
+		return 0, http.ErrHandlerTimeout
+	}
+	g.writeHeaderLocked(http.StatusOK)
+	return g.w.Write(b)
+}
+
+func (g *guardedWriter) Flush() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.detached {
+		return
+	}
+	g.writeHeaderLocked(http.StatusOK)
+	if f, ok := g.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// status returns the status the handler wrote, 0 if none yet.
+func (g *guardedWriter) status() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.code
+}
+
+// claim takes over the response if the handler has not started it, reporting
+// whether it did. Afterwards the handler's writes are discarded.
+func (g *guardedWriter) claim() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.code != 0 {
+		return false
+	}
+	g.detached = true
+	return true
+}
+
+// detach discards every later write by the handler.
+func (g *guardedWriter) detach() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.detached = true
+}
+
+// pipelineCache holds the pipelines of KeyedMiddleware, least recently used last.
+type pipelineCache struct {
+	mu    sync.Mutex
+	max   int
+	order *list.List
+	items map[string]*list.Element
+	build func(key string) *gosentry.Pipeline
+}
+
+type cacheEntry struct {
+	key      string
+	pipeline *gosentry.Pipeline
+}
+
+func (c *pipelineCache) get(key string) *gosentry.Pipeline {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*cacheEntry).pipeline
+	}
+
+	p := c.build(key)
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, pipeline: p})
+	if c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+	return p
+}
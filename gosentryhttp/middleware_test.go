@@ -0,0 +1,269 @@
+package gosentryhttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Handler", "yes")
+	io.WriteString(w, "ok")
+})
+
+func get(h http.Handler, target string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware_PassesThrough(t *testing.T) {
+	h := Middleware(gosentry.NewPipeline("api"), MiddlewareOptions{})(ok)
+
+	rec := get(h, "/")
+	if rec.Code != 200 || rec.Body.String() != "ok" || rec.Header().Get("X-Handler") != "yes" {
+		t.Fatalf("unexpected response %d %q %v", rec.Code, rec.Body, rec.Header())
+	}
+}
+
+func TestMiddleware_RateLimitRejection(t *testing.T) {
+	limiter := policies.NewLimiter(policies.RateLimitOptions{Name: "api-rl", Rate: 0.5, Burst: 2})
+	h := Middleware(gosentry.NewPipeline("api", limiter.Policy()), MiddlewareOptions{})(ok)
+
+	get(h, "/")
+	get(h, "/")
+	rec := get(h, "/")
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	want := map[string]string{
+		"Retry-After":         "2",
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "4",
+	}
+	for k, v := range want {
+		if got := rec.Header().Get(k); got != v {
+			t.Errorf("expected %s: %s, got %q", k, v, got)
+		}
+	}
+	if rec.Header().Get("X-Handler") != "" {
+		t.Fatal("expected the handler not to run")
+	}
+}
+
+func TestMiddleware_CircuitOpenRejection(t *testing.T) {
+	breaker := policies.NewBreaker(policies.CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute})
+	failing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	h := Middleware(gosentry.NewPipeline("api", breaker.Policy()), MiddlewareOptions{RetryAfter: 30 * time.Second})(failing)
+
+	if rec := get(h, "/"); rec.Code != 500 {
+		t.Fatalf("expected the handler's 500, got %d", rec.Code)
+	}
+	if breaker.State() != policies.CircuitOpen {
+		t.Fatalf("expected the 500 to open the breaker, got %s", breaker.State())
+	}
+
+	rec := get(h, "/")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "30" {
+		t.Fatalf("expected 503 with Retry-After 30, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestMiddleware_BulkheadShedsLoad(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	p := gosentry.NewPipeline("api", policies.Bulkhead(policies.BulkheadOptions{MaxConcurrent: 1}))
+	h := Middleware(p, MiddlewareOptions{})(slow)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		get(h, "/")
+	}()
+	<-started
+
+	rec := get(h, "/")
+	close(release)
+	wg.Wait()
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("expected 503 with Retry-After 1, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestMiddleware_TimeoutTakesOverResponse(t *testing.T) {
+	release := make(chan struct{})
+	done := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		<-release
+		w.Header().Set("X-Late", "yes")
+		if _, err := io.WriteString(w, "late"); err != http.ErrHandlerTimeout {
+			t.Errorf("expected late writes to fail with ErrHandlerTimeout, got %v", err)
+		}
+	})
+	p := gosentry.NewPipeline("api", policies.Timeout(policies.TimeoutOptions{Duration: 10 * time.Millisecond}))
+	h := Middleware(p, MiddlewareOptions{})(slow)
+
+	rec := get(h, "/")
+	close(release)
+	<-done
+
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("X-Late") != "" {
+		t.Fatalf("expected a clean 503, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestMiddleware_TimeoutAfterHandlerResponded(t *testing.T) {
+	release := make(chan struct{})
+	done := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(done)
+		io.WriteString(w, "partial")
+		w.(http.Flusher).Flush()
+		<-release
+		if _, err := io.WriteString(w, "late"); err != http.ErrHandlerTimeout {
+			t.Errorf("expected late writes to fail with ErrHandlerTimeout, got %v", err)
+		}
+		w.(http.Flusher).Flush()
+	})
+	p := gosentry.NewPipeline("api", policies.Timeout(policies.TimeoutOptions{Duration: 10 * time.Millisecond}))
+	h := Middleware(p, MiddlewareOptions{})(slow)
+
+	rec := get(h, "/")
+	close(release)
+	<-done
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+		t.Fatalf("expected the handler's partial response only, got %d %q", rec.Code, rec.Body)
+	}
+}
+
+func TestMiddleware_RetryDoesNotRerunRespondedHandler(t *testing.T) {
+	calls := 0
+	failing := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, "oops;")
+	})
+	p := gosentry.NewPipeline("api", policies.Retry(policies.RetryOptions{MaxAttempts: 3, InitialDelay: time.Millisecond}))
+	h := Middleware(p, MiddlewareOptions{})(failing)
+
+	rec := get(h, "/")
+	if calls != 1 || rec.Code != http.StatusInternalServerError || rec.Body.String() != "oops;" {
+		t.Fatalf("expected one 500 response, got %d calls and %d %q", calls, rec.Code, rec.Body)
+	}
+}
+
+func TestMiddleware_RetryDoesNotRerunAfterAbandonedResponse(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	defer close(release)
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		io.WriteString(w, "partial")
+		<-release
+	})
+	p := gosentry.NewPipeline("api",
+		policies.Retry(policies.RetryOptions{MaxAttempts: 3, InitialDelay: time.Millisecond}),
+		policies.Timeout(policies.TimeoutOptions{Duration: 10 * time.Millisecond}),
+	)
+	h := Middleware(p, MiddlewareOptions{})(slow)
+
+	rec := get(h, "/")
+	if calls.Load() != 1 || rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+		t.Fatalf("expected one partial response, got %d calls and %d %q", calls.Load(), rec.Code, rec.Body)
+	}
+}
+
+func TestMiddleware_PanicReachesServeHTTPGoroutine(t *testing.T) {
+	boom := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+	p := gosentry.NewPipeline("api",
+		policies.Retry(policies.RetryOptions{MaxAttempts: 3, InitialDelay: time.Millisecond}),
+		policies.Timeout(policies.TimeoutOptions{Duration: time.Second}),
+	)
+	h := Middleware(p, MiddlewareOptions{})(boom)
+
+	defer func() {
+		v := recover()
+		if msg, _ := v.(string); !strings.HasPrefix(msg, "boom") {
+			t.Fatalf("expected the handler's panic, got %v", v)
+		}
+	}()
+	get(h, "/")
+	t.Fatal("expected ServeHTTP to panic")
+}
+
+func TestKeyedMiddleware_SeparatesClients(t *testing.T) {
+	var built []string
+	h := KeyedMiddleware(Header("X-API-Key"), func(key string) *gosentry.Pipeline {
+		built = append(built, key)
+		return gosentry.NewPipeline("api-"+key, policies.RateLimit(policies.RateLimitOptions{Name: key, Rate: 0.1, Burst: 1}))
+	}, MiddlewareOptions{MaxKeys: 2})(ok)
+
+	if get(h, "/", "X-API-Key", "a").Code != 200 || get(h, "/", "X-API-Key", "b").Code != 200 {
+		t.Fatal("expected each client's first request to pass")
+	}
+	if get(h, "/", "X-API-Key", "a").Code != http.StatusTooManyRequests {
+		t.Fatal("expected client a to be limited")
+	}
+
+	// A third key evicts the least recently used one, b.
+	get(h, "/", "X-API-Key", "c")
+	if get(h, "/", "X-API-Key", "b").Code != 200 {
+		t.Fatal("expected b to get a fresh limiter after eviction")
+	}
+	if len(built) != 4 {
+		t.Fatalf("expected 4 pipelines built, got %v", built)
+	}
+}
+
+func TestMiddleware_PerRoute(t *testing.T) {
+	strict := Middleware(gosentry.NewPipeline("admin", policies.RateLimit(policies.RateLimitOptions{Rate: 0.1, Burst: 1})), MiddlewareOptions{})
+	mux := http.NewServeMux()
+	mux.Handle("/admin", strict(ok))
+	mux.Handle("/", ok)
+
+	get(mux, "/admin")
+	if get(mux, "/admin").Code != http.StatusTooManyRequests {
+		t.Fatal("expected /admin to be limited")
+	}
+	if get(mux, "/").Code != 200 {
+		t.Fatal("expected / to be unaffected")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.0.2.7:5123"
+	if got := ClientIP(req); got != "192.0.2.7" {
+		t.Fatalf("expected the host, got %q", got)
+	}
+	if got := Header("X-Real-IP")(req); got != "192.0.2.7" {
+		t.Fatalf("expected a fallback to the client IP, got %q", got)
+	}
+	req.Header.Set("X-Real-IP", "198.51.100.1")
+	if got := Header("X-Real-IP")(req); got != "198.51.100.1" {
+		t.Fatalf("expected the header, got %q", got)
+	}
+}
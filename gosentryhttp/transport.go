@@ -0,0 +1,239 @@
+// Package gosentryhttp applies gosentry pipelines to outgoing HTTP requests.
+package gosentryhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+// ErrBodyNotRewindable is returned when a request that failed without a response
+// must be retried but its body cannot be sent again because GetBody is nil.
+var ErrBodyNotRewindable = errors.New("gosentryhttp: request body cannot be rewound for retry")
+
+// maxDrain bounds how much of a discarded response body is read so its connection
+// can be reused.
+const maxDrain = 64 << 10
+
+// StatusError is the failure reported to the pipeline for a response that Classify
+// rejects. Once the pipeline gives up, the response itself is returned to the
+// caller, not the error.
+type StatusError struct {
+	Response *http.Response
+
+	// StatusCode is Response.StatusCode.
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("gosentryhttp: %s %s: %s", e.Response.Request.Method, e.Response.Request.URL.Redacted(), e.Response.Status)
+}
+
+// RetryAfter returns the delay requested by the response's Retry-After header,
+// either in seconds or as an HTTP date; 0 if there is none. policies.Retry waits at
+// least this long before the next attempt.
+func (e *StatusError) RetryAfter() time.Duration {
+	v := e.Response.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+type TransportOptions struct {
+	// Base performs the requests. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+
+	// Classify returns the error to report to the pipeline for resp, or nil if resp
+	// is a success. Defaults to DefaultClassify.
+	Classify func(resp *http.Response) error
+}
+
+func DefaultTransportOptions() TransportOptions {
+	return TransportOptions{
+		Base:     http.DefaultTransport,
+		Classify: DefaultClassify,
+	}
+}
+
+// DefaultClassify treats 5xx and 429 Too Many Requests as retryable failures and
+// other 4xx responses as permanent failures, which are not retried.
+func DefaultClassify(resp *http.Response) error {
+	switch {
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusTooManyRequests:
+		return &StatusError{Response: resp, StatusCode: resp.StatusCode}
+	case resp.StatusCode >= 400:
+		return policies.Permanent(&StatusError{Response: resp, StatusCode: resp.StatusCode})
+	}
+	return nil
+}
+
+// Transport is an http.RoundTripper that runs each request through a pipeline.
+// Every attempt sends a fresh copy of the request, rewinding its body with GetBody;
+// responses of attempts that are retried are drained and closed.
+//
+// When the pipeline gives up on a rejected response, RoundTrip returns that
+// response with a nil error, as http.RoundTripper requires. Other failures, such as
+// an open circuit, are returned as errors.
+//
+// A Timeout policy in the pipeline bounds the time until the response headers
+// arrive; reading the body is bounded by the request's own context only.
+type Transport struct {
+	pipeline *gosentry.Pipeline
+	opts     TransportOptions
+}
+
+func NewTransport(pipeline *gosentry.Pipeline, options TransportOptions) *Transport {
+	return &Transport{pipeline: pipeline, opts: applyTransportDefaults(options)}
+}
+
+func applyTransportDefaults(options TransportOptions) TransportOptions {
+	defaults := DefaultTransportOptions()
+
+	if options.Base == nil {
+		options.Base = defaults.Base
+	}
+	if options.Classify == nil {
+		options.Classify = defaults.Classify
+	}
+
+	return options
+}
+
+// roundTrip tracks the response of the latest attempt of one request. A Timeout
+// policy may abandon an attempt that still delivers a response later, so every
+// access is locked.
+type roundTrip struct {
+	mu       sync.Mutex
+	last     *http.Response
+	finished bool
+	attempts int
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := &roundTrip{}
+	_, err := t.pipeline.Execute(req.Context(), func(ctx context.Context) (any, error) {
+		return t.attempt(ctx, req, rt)
+	})
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.finished = true
+	if rt.attempts == 0 && req.Body != nil {
+		// A RoundTripper must close the body even if it never sends the request.
+		_ = req.Body.Close()
+	}
+
+	var serr *StatusError
+	if err == nil || (errors.As(err, &serr) && serr.Response == rt.last) {
+		if rt.last != nil {
+			return rt.last, nil
+		}
+	}
+	if rt.last != nil {
+		discard(rt.last)
+	}
+	if err == nil {
+		err = errors.New("gosentryhttp: pipeline returned without calling the transport")
+	}
+	return nil, err
+}
+
+func (t *Transport) attempt(ctx context.Context, req *http.Request, rt *roundTrip) (any, error) {
+	rt.mu.Lock()
+	prev := rt.last
+	first := rt.attempts == 0
+	rt.attempts++
+	rt.mu.Unlock()
+
+	body := req.Body
+	if !first && req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			// Keep the previous response: the caller gets it as if retries had run out.
+			if prev != nil {
+				return nil, policies.Permanent(&StatusError{Response: prev, StatusCode: prev.StatusCode})
+			}
+			return nil, policies.Permanent(ErrBodyNotRewindable)
+		}
+		var err error
+		if body, err = req.GetBody(); err != nil {
+			return nil, policies.Permanent(fmt.Errorf("gosentryhttp: rewinding request body: %w", err))
+		}
+	}
+
+	rt.mu.Lock()
+	if rt.last != nil {
+		discard(rt.last)
+		rt.last = nil
+	}
+	rt.mu.Unlock()
+
+	// The request is cancelled when the attempt's context is done before the headers
+	// arrive; afterwards only by closing the body or by the caller's context.
+	reqCtx, cancel := context.WithCancel(req.Context())
+	stop := context.AfterFunc(ctx, cancel)
+
+	out := req.Clone(reqCtx)
+	out.Body = body
+	resp, err := t.opts.Base.RoundTrip(out)
+	if !stop() {
+		if resp != nil {
+			discard(resp)
+		}
+		cancel()
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if rt.finished {
+		// The pipeline already gave up on this attempt.
+		discard(resp)
+		return nil, context.Canceled
+	}
+	rt.last = resp
+	return resp, t.opts.Classify(resp)
+}
+
+// discard drains and closes the body of a response that will not be returned.
+func discard(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrain))
+	_ = resp.Body.Close()
+}
+
+// cancelBody releases the request's context when the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,221 @@
+package gosentryhttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+func retryPipeline(attempts int) *gosentry.Pipeline {
+	return gosentry.NewPipeline("http", policies.Retry(policies.RetryOptions{
+		MaxAttempts:  attempts,
+		InitialDelay: time.Millisecond,
+		MaxDelay:     time.Second,
+		Backoff:      policies.BackoffFixed,
+	}))
+}
+
+// trackingBody records whether a response body was closed.
+type trackingBody struct {
+	io.Reader
+	closed *atomic.Int32
+}
+
+func (b trackingBody) Close() error {
+	b.closed.Add(1)
+	return nil
+}
+
+// scripted answers each request with the next status code.
+type scripted struct {
+	codes  []int
+	header http.Header
+	calls  atomic.Int32
+	closed atomic.Int32
+	bodies []string
+}
+
+func (s *scripted) RoundTrip(req *http.Request) (*http.Response, error) {
+	n := int(s.calls.Add(1)) - 1
+	if req.Body != nil {
+		b, _ := io.ReadAll(req.Body)
+		req.Body.Close()
+		s.bodies = append(s.bodies, string(b))
+	}
+	header := http.Header{}
+	if s.header != nil && n < len(s.codes)-1 {
+		header = s.header.Clone()
+	}
+	return &http.Response{
+		StatusCode: s.codes[n],
+		Status:     http.StatusText(s.codes[n]),
+		Header:     header,
+		Body:       trackingBody{Reader: strings.NewReader("body"), closed: &s.closed},
+		Request:    req,
+	}, nil
+}
+
+func TestTransport_RetriesServerErrors(t *testing.T) {
+	base := &scripted{codes: []int{503, 500, 200}}
+	client := &http.Client{Transport: NewTransport(retryPipeline(3), TransportOptions{Base: base})}
+
+	resp, err := client.Post("http://example.test/", "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 || base.calls.Load() != 3 {
+		t.Fatalf("expected 200 after 3 calls, got %d after %d", resp.StatusCode, base.calls.Load())
+	}
+	if base.closed.Load() != 2 {
+		t.Fatalf("expected the 2 discarded bodies to be closed, got %d", base.closed.Load())
+	}
+	for i, b := range base.bodies {
+		if b != "payload" {
+			t.Fatalf("attempt %d sent body %q, expected it rewound", i+1, b)
+		}
+	}
+}
+
+func TestTransport_ClientErrorsAreNotRetried(t *testing.T) {
+	base := &scripted{codes: []int{404, 200}}
+	client := &http.Client{Transport: NewTransport(retryPipeline(3), TransportOptions{Base: base})}
+
+	resp, err := client.Get("http://example.test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 404 || base.calls.Load() != 1 {
+		t.Fatalf("expected the 404 after 1 call, got %d after %d", resp.StatusCode, base.calls.Load())
+	}
+	if b, _ := io.ReadAll(resp.Body); string(b) != "body" {
+		t.Fatalf("expected the response body to be readable, got %q", b)
+	}
+}
+
+func TestTransport_ReturnsLastResponseWhenRetriesRunOut(t *testing.T) {
+	base := &scripted{codes: []int{503, 502}}
+	client := &http.Client{Transport: NewTransport(retryPipeline(2), TransportOptions{Base: base})}
+
+	resp, err := client.Get("http://example.test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 502 {
+		t.Fatalf("expected the last response, got %d", resp.StatusCode)
+	}
+	if base.closed.Load() != 2 {
+		t.Fatalf("expected both bodies closed, got %d", base.closed.Load())
+	}
+}
+
+func TestTransport_RespectsRetryAfter(t *testing.T) {
+	base := &scripted{codes: []int{429, 200}, header: http.Header{"Retry-After": {"1"}}}
+	client := &http.Client{Transport: NewTransport(retryPipeline(2), TransportOptions{Base: base})}
+
+	start := time.Now()
+	resp, err := client.Get("http://example.test/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("expected to wait for Retry-After, returned after %s", elapsed)
+	}
+}
+
+func TestTransport_BodyWithoutGetBody(t *testing.T) {
+	base := &scripted{codes: []int{503, 200}}
+	client := &http.Client{Transport: NewTransport(retryPipeline(3), TransportOptions{Base: base})}
+
+	// Hiding the reader's type stops http.NewRequest from setting GetBody.
+	req, _ := http.NewRequest("POST", "http://example.test/", io.MultiReader(strings.NewReader("payload")))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != 503 || base.calls.Load() != 1 {
+		t.Fatalf("expected the 503 without a retry, got %d after %d calls", resp.StatusCode, base.calls.Load())
+	}
+}
+
+func TestTransport_PipelineRejection(t *testing.T) {
+	breaker := policies.NewBreaker(policies.CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute})
+	breaker.ForceOpen()
+	base := &scripted{codes: []int{200}}
+	client := &http.Client{Transport: NewTransport(gosentry.NewPipeline("http", breaker.Policy()), TransportOptions{Base: base})}
+
+	_, err := client.Get("http://example.test/")
+	if !errors.Is(err, policies.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if base.calls.Load() != 0 {
+		t.Fatalf("expected no request to be sent, got %d", base.calls.Load())
+	}
+}
+
+func TestTransport_TimeoutBoundsHeadersOnly(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		w.(http.Flusher).Flush()
+		time.Sleep(50 * time.Millisecond)
+		io.WriteString(w, "late body")
+	}))
+	defer srv.Close()
+
+	p := gosentry.NewPipeline("http", policies.Timeout(policies.TimeoutOptions{Duration: 20 * time.Millisecond}))
+	client := &http.Client{Transport: NewTransport(p, TransportOptions{})}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if b, err := io.ReadAll(resp.Body); err != nil || string(b) != "late body" {
+		t.Fatalf("expected the body to outlive the timeout, got %q %v", b, err)
+	}
+}
+
+func TestStatusError_RetryAfter(t *testing.T) {
+	at := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	tests := []struct {
+		header string
+		min    time.Duration
+		max    time.Duration
+	}{
+		{"", 0, 0},
+		{"2", 2 * time.Second, 2 * time.Second},
+		{"-1", 0, 0},
+		{"soon", 0, 0},
+		{at, 59 * time.Minute, time.Hour},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if tt.header != "" {
+			resp.Header.Set("Retry-After", tt.header)
+		}
+		got := (&StatusError{Response: resp}).RetryAfter()
+		if got < tt.min || got > tt.max {
+			t.Errorf("Retry-After %q: got %s, want between %s and %s", tt.header, got, tt.min, tt.max)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+// Package gosentrynet applies gosentry pipelines to establishing network connections.
+package gosentrynet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+// AddrError is the outcome of one connection attempt to a resolved address.
+type AddrError struct {
+	// Addr is the address dialled, "ip:port".
+	Addr string
+	Err  error
+}
+
+// DialError is returned when no resolved address of a dial could be reached. It
+// lists every address tried, including those skipped because their circuit
+// breaker was open (with policies.ErrCircuitOpen).
+type DialError struct {
+	Network string
+	Address string
+
+	// Attempts are in the order they were started. Empty if resolution failed.
+	Attempts []AddrError
+
+	// Err is the resolution error, if the address could not be resolved.
+	Err error
+}
+
+func (e *DialError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("gosentrynet: dial %s %s: %v", e.Network, e.Address, e.Err)
+	}
+	parts := make([]string, len(e.Attempts))
+	for i, a := range e.Attempts {
+		parts[i] = a.Addr + ": " + a.Err.Error()
+	}
+	return fmt.Sprintf("gosentrynet: dial %s %s: %s", e.Network, e.Address, strings.Join(parts, "; "))
+}
+
+// Unwrap returns the resolution error or the error of every attempt.
+func (e *DialError) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Err}
+	}
+	errs := make([]error, len(e.Attempts))
+	for i, a := range e.Attempts {
+		errs[i] = a.Err
+	}
+	return errs
+}
+
+// Tried returns the addresses that were dialled, leaving out those skipped by an
+// open circuit breaker.
+func (e *DialError) Tried() []string {
+	var addrs []string
+	for _, a := range e.Attempts {
+		if !errors.Is(a.Err, policies.ErrCircuitOpen) && !errors.Is(a.Err, policies.ErrCircuitHalfOpenBusy) {
+			addrs = append(addrs, a.Addr)
+		}
+	}
+	return addrs
+}
+
+type DialerOptions struct {
+	// Dial makes a single connection attempt to an "ip:port" address. Defaults to
+	// the DialContext of a zero net.Dialer.
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+
+	// Resolve looks up the IP addresses of host; network is "ip", "ip4" or "ip6".
+	// Defaults to net.DefaultResolver.LookupNetIP.
+	Resolve func(ctx context.Context, network, host string) ([]netip.Addr, error)
+
+	// Pipeline returns the pipeline for dials to address, as passed to DialContext.
+	// It is called once per address. Nil applies no policies.
+	Pipeline func(address string) *gosentry.Pipeline
+
+	// Breaker, if set, gives every resolved address its own circuit breaker with
+	// these options; addresses whose breaker is open are skipped. Breakers are named
+	// "<Name>/<ip:port>", or just the address if Name is empty. Cancelled dials do
+	// not count as failures.
+	Breaker *policies.CircuitBreakerOptions
+
+	// HappyEyeballs races the resolved addresses, alternating between IPv6 and IPv4:
+	// the next attempt starts when the previous fails or after FallbackDelay,
+	// whichever comes first. Without it, addresses are tried one after another.
+	HappyEyeballs bool
+
+	// FallbackDelay is the head start of each racing attempt. Defaults to 300ms.
+	FallbackDelay time.Duration
+}
+
+func DefaultDialerOptions() DialerOptions {
+	return DialerOptions{
+		Dial:          (&net.Dialer{}).DialContext,
+		Resolve:       net.DefaultResolver.LookupNetIP,
+		FallbackDelay: 300 * time.Millisecond,
+	}
+}
+
+func applyDialerDefaults(options DialerOptions) DialerOptions {
+	defaults := DefaultDialerOptions()
+
+	if options.Dial == nil {
+		options.Dial = defaults.Dial
+	}
+	if options.Resolve == nil {
+		options.Resolve = defaults.Resolve
+	}
+	if options.FallbackDelay <= 0 {
+		options.FallbackDelay = defaults.FallbackDelay
+	}
+
+	return options
+}
+
+// Dialer establishes connections through pipelines. Its DialContext can be used
+// wherever a net.Dialer's can, e.g. in http.Transport.
+type Dialer struct {
+	opts DialerOptions
+
+	mu        sync.Mutex
+	pipelines map[string]*gosentry.Pipeline
+	breakers  map[string]*policies.Breaker
+}
+
+func NewDialer(options DialerOptions) *Dialer {
+	return &Dialer{
+		opts:      applyDialerDefaults(options),
+		pipelines: map[string]*gosentry.Pipeline{},
+		breakers:  map[string]*policies.Breaker{},
+	}
+}
+
+// Breakers returns the per-address breakers created so far, for inspection or
+// registration with an admin.Registry.
+func (d *Dialer) Breakers() []*policies.Breaker {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]*policies.Breaker, 0, len(d.breakers))
+	for _, b := range d.breakers {
+		out = append(out, b)
+	}
+	return out
+}
+
+func (d *Dialer) pipeline(address string) *gosentry.Pipeline {
+	if d.opts.Pipeline == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p, ok := d.pipelines[address]
+	if !ok {
+		p = d.opts.Pipeline(address)
+		d.pipelines[address] = p
+	}
+	return p
+}
+
+func (d *Dialer) breaker(addr string) *policies.Breaker {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	b, ok := d.breakers[addr]
+	if !ok {
+		opts := *d.opts.Breaker
+		if opts.Name == "" {
+			opts.Name = addr
+		} else {
+			opts.Name += "/" + addr
+		}
+		// Dials cancelled by the caller, or by a race already won, say nothing
+		// about the address.
+		shouldTrip := opts.ShouldTrip
+		opts.ShouldTrip = func(err error) bool {
+			if errors.Is(err, context.Canceled) {
+				return false
+			}
+			return shouldTrip == nil || shouldTrip(err)
+		}
+		b = policies.NewBreaker(opts)
+		d.breakers[addr] = b
+	}
+	return b
+}
+
+// dialCall collects the connections made for one DialContext call, so that any
+// made by attempts a Timeout policy abandoned are closed.
+type dialCall struct {
+	mu       sync.Mutex
+	conns    []net.Conn
+	finished bool
+}
+
+// keep records conn, or closes it and reports false if the call is over.
+func (c *dialCall) keep(conn net.Conn) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.finished {
+		_ = conn.Close()
+		return false
+	}
+	c.conns = append(c.conns, conn)
+	return true
+}
+
+// finish closes every connection but the one returned.
+func (c *dialCall) finish(returned net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finished = true
+	for _, conn := range c.conns {
+		if conn != returned {
+			_ = conn.Close()
+		}
+	}
+}
+
+// DialContext connects to address on network through the address's pipeline.
+// Each pipeline attempt resolves address and dials its IPs; if none can be
+// reached, the attempt fails with a *DialError.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	p := d.pipeline(address)
+	if p == nil {
+		return d.dial(ctx, network, address)
+	}
+
+	call := &dialCall{}
+	res, err := p.Execute(ctx, func(ctx context.Context) (any, error) {
+		conn, err := d.dial(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		if !call.keep(conn) {
+			return nil, context.Canceled
+		}
+		return conn, nil
+	})
+	conn, _ := res.(net.Conn)
+	call.finish(conn)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// dial makes one attempt at every resolved address of address.
+func (d *Dialer) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	addrs, err := d.resolve(ctx, network, address)
+	if err != nil {
+		return nil, &DialError{Network: network, Address: address, Err: err}
+	}
+	if d.opts.HappyEyeballs && len(addrs) > 1 {
+		return d.race(ctx, network, address, interleave(addrs))
+	}
+
+	derr := &DialError{Network: network, Address: address}
+	for _, addr := range addrs {
+		conn, err := d.dialAddr(ctx, network, addr)
+		if err == nil {
+			return conn, nil
+		}
+		derr.Attempts = append(derr.Attempts, AddrError{Addr: addr.String(), Err: err})
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, derr
+}
+
+func (d *Dialer) resolve(ctx context.Context, network, address string) ([]netip.AddrPort, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := net.DefaultResolver.LookupPort(ctx, network, portStr)
+	if err != nil {
+		return nil, err
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return []netip.AddrPort{netip.AddrPortFrom(ip, uint16(port))}, nil
+	}
+
+	ipNetwork := "ip"
+	switch {
+	case strings.HasSuffix(network, "4"):
+		ipNetwork = "ip4"
+	case strings.HasSuffix(network, "6"):
+		ipNetwork = "ip6"
+	}
+	ips, err := d.opts.Resolve(ctx, ipNetwork, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses for %s", host)
+	}
+	addrs := make([]netip.AddrPort, len(ips))
+	for i, ip := range ips {
+		addrs[i] = netip.AddrPortFrom(ip.Unmap(), uint16(port))
+	}
+	return addrs, nil
+}
+
+// dialAddr dials one resolved address through its breaker, if any.
+func (d *Dialer) dialAddr(ctx context.Context, network string, addr netip.AddrPort) (net.Conn, error) {
+	dial := func(ctx context.Context) (any, error) {
+		return d.opts.Dial(ctx, network, addr.String())
+	}
+	if d.opts.Breaker == nil {
+		conn, err := dial(ctx)
+		c, _ := conn.(net.Conn)
+		return c, err
+	}
+	conn, err := d.breaker(addr.String()).Policy()(dial)(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn.(net.Conn), nil
+}
+
+// race dials addrs with staggered starts and returns the first connection made.
+func (d *Dialer) race(ctx context.Context, network, address string, addrs []netip.AddrPort) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		i    int
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, len(addrs))
+	start := func(i int) {
+		go func() {
+			conn, err := d.dialAddr(ctx, network, addrs[i])
+			results <- result{i: i, conn: conn, err: err}
+		}()
+	}
+
+	errs := make([]error, len(addrs))
+	started, pending := 1, 1
+	start(0)
+	timer := time.NewTimer(d.opts.FallbackDelay)
+	defer timer.Stop()
+
+	var won net.Conn
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				if won == nil {
+					won = r.conn
+					cancel()
+				} else {
+					_ = r.conn.Close()
+				}
+				continue
+			}
+			errs[r.i] = r.err
+			if won == nil && started < len(addrs) && ctx.Err() == nil {
+				start(started)
+				started++
+				pending++
+				timer.Reset(d.opts.FallbackDelay)
+			}
+		case <-timer.C:
+			if won == nil && started < len(addrs) {
+				start(started)
+				started++
+				pending++
+				timer.Reset(d.opts.FallbackDelay)
+			}
+		}
+	}
+	if won != nil {
+		return won, nil
+	}
+
+	derr := &DialError{Network: network, Address: address}
+	for i := 0; i < started; i++ {
+		derr.Attempts = append(derr.Attempts, AddrError{Addr: addrs[i].String(), Err: errs[i]})
+	}
+	return nil, derr
+}
+
+// interleave orders addrs alternating between address families, starting with the
+// family of the first, as RFC 8305 recommends.
+func interleave(addrs []netip.AddrPort) []netip.AddrPort {
+	var first, second []netip.AddrPort
+	for _, a := range addrs {
+		if a.Addr().Is4() == addrs[0].Addr().Is4() {
+			first = append(first, a)
+		} else {
+			second = append(second, a)
+		}
+	}
+	out := make([]netip.AddrPort, 0, len(addrs))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			out = append(out, first[i])
+		}
+		if i < len(second) {
+			out = append(out, second[i])
+		}
+	}
+	return out
+}
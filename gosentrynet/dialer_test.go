@@ -0,0 +1,271 @@
+package gosentrynet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"slices"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+// network fakes dialling: addresses in up connect, addresses in hang block until
+// cancelled, and every other address refuses.
+type network struct {
+	mu    sync.Mutex
+	up    map[string]bool
+	hang  map[string]bool
+	dials []string
+}
+
+func (n *network) dial(ctx context.Context, _, address string) (net.Conn, error) {
+	n.mu.Lock()
+	n.dials = append(n.dials, address)
+	up, hang := n.up[address], n.hang[address]
+	n.mu.Unlock()
+
+	switch {
+	case up:
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	case hang:
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return nil, fmt.Errorf("dial %s: %w", address, syscall.ECONNREFUSED)
+}
+
+func (n *network) dialled() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return slices.Clone(n.dials)
+}
+
+func resolveTo(ips ...string) func(context.Context, string, string) ([]netip.Addr, error) {
+	return func(context.Context, string, string) ([]netip.Addr, error) {
+		addrs := make([]netip.Addr, len(ips))
+		for i, ip := range ips {
+			addrs[i] = netip.MustParseAddr(ip)
+		}
+		return addrs, nil
+	}
+}
+
+func TestDialer_FallsBackToNextAddress(t *testing.T) {
+	n := &network{up: map[string]bool{"10.0.0.2:80": true}}
+	d := NewDialer(DialerOptions{Dial: n.dial, Resolve: resolveTo("10.0.0.1", "10.0.0.2")})
+
+	conn, err := d.DialContext(context.Background(), "tcp", "example.test:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if got := n.dialled(); !slices.Equal(got, []string{"10.0.0.1:80", "10.0.0.2:80"}) {
+		t.Fatalf("unexpected dials %v", got)
+	}
+}
+
+func TestDialer_ErrorListsAddressesTried(t *testing.T) {
+	n := &network{}
+	d := NewDialer(DialerOptions{Dial: n.dial, Resolve: resolveTo("10.0.0.1", "10.0.0.2")})
+
+	_, err := d.DialContext(context.Background(), "tcp", "example.test:80")
+	var derr *DialError
+	if !errors.As(err, &derr) {
+		t.Fatalf("expected a *DialError, got %v", err)
+	}
+	if !slices.Equal(derr.Tried(), []string{"10.0.0.1:80", "10.0.0.2:80"}) {
+		t.Fatalf("unexpected addresses tried %v", derr.Tried())
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("expected the attempt errors to be wrapped, got %v", err)
+	}
+}
+
+func TestDialer_ResolutionError(t *testing.T) {
+	errNoHost := errors.New("no such host")
+	d := NewDialer(DialerOptions{
+		Dial: (&network{}).dial,
+		Resolve: func(context.Context, string, string) ([]netip.Addr, error) {
+			return nil, errNoHost
+		},
+	})
+
+	_, err := d.DialContext(context.Background(), "tcp", "example.test:80")
+	var derr *DialError
+	if !errors.As(err, &derr) || !errors.Is(err, errNoHost) || len(derr.Attempts) != 0 {
+		t.Fatalf("expected a resolution error, got %v", err)
+	}
+}
+
+func TestDialer_SkipsAddressesWithOpenBreaker(t *testing.T) {
+	n := &network{up: map[string]bool{"10.0.0.2:80": true}}
+	d := NewDialer(DialerOptions{
+		Dial:    n.dial,
+		Resolve: resolveTo("10.0.0.1", "10.0.0.2"),
+		Breaker: &policies.CircuitBreakerOptions{Name: "db", FailureThreshold: 1, OpenTimeout: time.Minute},
+	})
+
+	for range 2 {
+		conn, err := d.DialContext(context.Background(), "tcp", "example.test:80")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+	}
+	if got := n.dialled(); !slices.Equal(got, []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.2:80"}) {
+		t.Fatalf("expected the failed address to be skipped, got %v", got)
+	}
+
+	n.mu.Lock()
+	n.up = nil
+	n.mu.Unlock()
+	_, err := d.DialContext(context.Background(), "tcp", "example.test:80")
+	var derr *DialError
+	if !errors.As(err, &derr) || !errors.Is(derr.Attempts[0].Err, policies.ErrCircuitOpen) {
+		t.Fatalf("expected the open breaker in the error, got %v", err)
+	}
+	if !slices.Equal(derr.Tried(), []string{"10.0.0.2:80"}) {
+		t.Fatalf("unexpected addresses tried %v", derr.Tried())
+	}
+
+	names := map[string]policies.CircuitBreakerState{}
+	for _, b := range d.Breakers() {
+		names[b.Name()] = b.State()
+	}
+	if names["db/10.0.0.1:80"] != policies.CircuitOpen || names["db/10.0.0.2:80"] != policies.CircuitOpen {
+		t.Fatalf("unexpected breakers %v", names)
+	}
+}
+
+func TestDialer_HappyEyeballs(t *testing.T) {
+	n := &network{
+		hang: map[string]bool{"[2001:db8::1]:443": true},
+		up:   map[string]bool{"10.0.0.1:443": true},
+	}
+	d := NewDialer(DialerOptions{
+		Dial:          n.dial,
+		Resolve:       resolveTo("2001:db8::1", "2001:db8::2", "10.0.0.1"),
+		Breaker:       &policies.CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute},
+		HappyEyeballs: true,
+		FallbackDelay: 10 * time.Millisecond,
+	})
+
+	start := time.Now()
+	conn, err := d.DialContext(context.Background(), "tcp", "example.test:443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the hanging address to be raced, took %v", elapsed)
+	}
+	if got := n.dialled(); !slices.Equal(got, []string{"[2001:db8::1]:443", "10.0.0.1:443"}) {
+		t.Fatalf("expected the families to alternate, got %v", got)
+	}
+	for _, b := range d.Breakers() {
+		if b.State() != policies.CircuitClosed {
+			t.Fatalf("expected the cancelled loser not to trip %s", b.Name())
+		}
+	}
+}
+
+func TestDialer_RunsPipelinePerAddress(t *testing.T) {
+	n := &network{}
+	var built []string
+	d := NewDialer(DialerOptions{
+		Dial:    n.dial,
+		Resolve: resolveTo("10.0.0.1"),
+		Pipeline: func(address string) *gosentry.Pipeline {
+			built = append(built, address)
+			return gosentry.NewPipeline("dial "+address, policies.Retry(policies.RetryOptions{
+				MaxAttempts:  3,
+				InitialDelay: time.Millisecond,
+				Backoff:      policies.BackoffFixed,
+			}))
+		},
+	})
+
+	if _, err := d.DialContext(context.Background(), "tcp", "example.test:80"); err == nil {
+		t.Fatal("expected the dial to fail")
+	}
+	if got := len(n.dialled()); got != 3 {
+		t.Fatalf("expected 3 attempts, got %d", got)
+	}
+	if _, err := d.DialContext(context.Background(), "tcp", "example.test:80"); err == nil {
+		t.Fatal("expected the dial to fail")
+	}
+	if !slices.Equal(built, []string{"example.test:80"}) {
+		t.Fatalf("expected one pipeline per address, built %v", built)
+	}
+}
+
+func TestDialer_ClosesConnectionsOfAbandonedAttempts(t *testing.T) {
+	release := make(chan struct{})
+	conns := make(chan net.Conn, 1)
+	d := NewDialer(DialerOptions{
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			<-release
+			client, server := net.Pipe()
+			conns <- server
+			return client, nil
+		},
+		Pipeline: func(string) *gosentry.Pipeline {
+			return gosentry.NewPipeline("dial", policies.Timeout(policies.TimeoutOptions{Duration: 10 * time.Millisecond}))
+		},
+	})
+
+	if _, err := d.DialContext(context.Background(), "tcp", "127.0.0.1:80"); err == nil {
+		t.Fatal("expected a timeout")
+	}
+	close(release)
+	server := <-conns
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := server.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected the late connection to be closed, got %v", err)
+	}
+}
+
+func TestDialer_Listener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+	go func() {
+		if c, err := ln.Accept(); err == nil {
+			c.Close()
+		}
+	}()
+
+	d := NewDialer(DefaultDialerOptions())
+	conn, err := d.DialContext(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestInterleave(t *testing.T) {
+	var addrs []netip.AddrPort
+	for _, ip := range []string{"2001:db8::1", "2001:db8::2", "2001:db8::3", "10.0.0.1"} {
+		addrs = append(addrs, netip.AddrPortFrom(netip.MustParseAddr(ip), 80))
+	}
+	var got []string
+	for _, a := range interleave(addrs) {
+		got = append(got, a.Addr().String())
+	}
+	want := []string{"2001:db8::1", "10.0.0.1", "2001:db8::2", "2001:db8::3"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("interleave = %v, want %v", got, want)
+	}
+}
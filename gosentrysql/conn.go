@@ -0,0 +1,529 @@
+package gosentrysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+
+	"gosentry"
+)
+
+var errNamedArgs = errors.New("gosentrysql: driver does not support named arguments")
+
+// conn wraps a driver connection, replacing it after transient failures outside
+// transactions. database/sql does not use a connection concurrently, but a Timeout
+// policy may abandon an attempt that is still running when the next one starts, so
+// every operation holds sem.
+type conn struct {
+	connector *Connector
+	sem       chan struct{}
+
+	// inner is nil after a transient failure, until the next attempt reconnects.
+	inner driver.Conn
+
+	// gen counts reconnects, so statements know when to prepare again.
+	gen int
+
+	inTx bool
+
+	// broken is set when a transient error occurs inside a transaction; the
+	// connection is dropped once the transaction ends.
+	broken bool
+}
+
+func newConn(connector *Connector, inner driver.Conn) *conn {
+	return &conn{connector: connector, sem: make(chan struct{}, 1), inner: inner}
+}
+
+func (c *conn) lock(ctx context.Context) error {
+	select {
+	case c.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *conn) unlock() {
+	<-c.sem
+}
+
+// ensure reconnects if the connection was dropped.
+func (c *conn) ensure(ctx context.Context) error {
+	if c.inner != nil {
+		return nil
+	}
+	if c.inTx {
+		return driver.ErrBadConn
+	}
+	inner, err := c.connector.connect(ctx)
+	if err != nil {
+		return err
+	}
+	c.inner = inner
+	c.gen++
+	return nil
+}
+
+// fail drops the connection after a transient error, or marks it broken inside a
+// transaction.
+func (c *conn) fail(err error) {
+	if err == nil || !c.connector.opts.IsTransient(err) {
+		return
+	}
+	if c.inTx {
+		c.broken = true
+		return
+	}
+	c.drop()
+}
+
+func (c *conn) drop() {
+	if c.inner != nil {
+		_ = c.inner.Close()
+		c.inner = nil
+	}
+}
+
+// run calls fn with the live connection through p. Inside a transaction every
+// error is final, so nothing is retried. A driver.ErrSkip from fn is passed back
+// without counting as a failure.
+func (c *conn) run(ctx context.Context, p *gosentry.Pipeline, fn func(ctx context.Context, inner driver.Conn) error) error {
+	skipped := false
+	err := c.connector.run(ctx, p, c.inTx, func(ctx context.Context) error {
+		if err := c.lock(ctx); err != nil {
+			return err
+		}
+		defer c.unlock()
+
+		if err := c.ensure(ctx); err != nil {
+			return err
+		}
+		err := fn(ctx, c.inner)
+		if errors.Is(err, driver.ErrSkip) {
+			skipped = true
+			return nil
+		}
+		c.fail(err)
+		return err
+	})
+	if skipped {
+		return driver.ErrSkip
+	}
+	return err
+}
+
+// supports reports whether the live connection, if any, implements an optional
+// interface; a dropped connection is assumed to, and checked again once reconnected.
+func supports[T any](c *conn) bool {
+	if err := c.lock(context.Background()); err != nil {
+		return false
+	}
+	defer c.unlock()
+	if c.inner == nil {
+		return true
+	}
+	_, ok := c.inner.(T)
+	return ok
+}
+
+// call collects the result of the latest attempt of one operation. A Timeout policy
+// may abandon an attempt that still returns later, so every access is locked, and
+// results that are not returned are released.
+type call[T any] struct {
+	mu       sync.Mutex
+	last     T
+	held     bool
+	finished bool
+
+	// release frees a result that is not returned; nil if there is nothing to free.
+	release func(T)
+}
+
+func newCall[T any](release func(T)) *call[T] {
+	return &call[T]{release: release}
+}
+
+func closeRows(rows driver.Rows) {
+	_ = rows.Close()
+}
+
+// keep records v as the latest result, or releases it and reports false if the
+// operation is over.
+func (c *call[T]) keep(v T) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.finished {
+		c.free(v)
+		return false
+	}
+	if c.held {
+		c.free(c.last)
+	}
+	c.last, c.held = v, true
+	return true
+}
+
+// finish ends the operation and returns the latest result, which is released
+// instead if the operation failed.
+func (c *call[T]) finish(err error) T {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finished = true
+	var zero T
+	if !c.held {
+		return zero
+	}
+	if err != nil {
+		c.free(c.last)
+		return zero
+	}
+	return c.last
+}
+
+func (c *call[T]) free(v T) {
+	if c.release != nil {
+		c.release(v)
+	}
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !supports[driver.QueryerContext](c) {
+		return nil, driver.ErrSkip
+	}
+	call := newCall(closeRows)
+	err := c.run(ctx, c.connector.opts.Query, func(ctx context.Context, inner driver.Conn) error {
+		q, ok := inner.(driver.QueryerContext)
+		if !ok {
+			return driver.ErrSkip
+		}
+		rows, err := q.QueryContext(ctx, query, args)
+		if err != nil {
+			return err
+		}
+		if !call.keep(rows) {
+			return context.Canceled
+		}
+		return nil
+	})
+	return call.finish(err), err
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if !supports[driver.ExecerContext](c) {
+		return nil, driver.ErrSkip
+	}
+	call := newCall[driver.Result](nil)
+	err := c.run(ctx, c.connector.opts.Exec, func(ctx context.Context, inner driver.Conn) error {
+		e, ok := inner.(driver.ExecerContext)
+		if !ok {
+			return driver.ErrSkip
+		}
+		res, err := e.ExecContext(ctx, query, args)
+		if err != nil {
+			return err
+		}
+		if !call.keep(res) {
+			return context.Canceled
+		}
+		return nil
+	})
+	return call.finish(err), err
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer c.unlock()
+
+	if err := c.ensure(ctx); err != nil {
+		return nil, err
+	}
+	s := &stmt{conn: c, query: query}
+	if err := s.prepare(ctx); err != nil {
+		c.fail(err)
+		return nil, err
+	}
+	return s, nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer c.unlock()
+
+	if err := c.ensure(ctx); err != nil {
+		return nil, err
+	}
+	var inner driver.Tx
+	var err error
+	if b, ok := c.inner.(driver.ConnBeginTx); ok {
+		inner, err = b.BeginTx(ctx, opts)
+	} else if opts != (driver.TxOptions{}) {
+		err = errors.New("gosentrysql: driver does not support transaction options")
+	} else {
+		inner, err = c.inner.Begin()
+	}
+	if err != nil {
+		c.fail(err)
+		return nil, err
+	}
+	c.inTx = true
+	return &tx{conn: c, inner: inner}, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if err := c.lock(ctx); err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	if err := c.ensure(ctx); err != nil {
+		return err
+	}
+	p, ok := c.inner.(driver.Pinger)
+	if !ok {
+		return nil
+	}
+	err := p.Ping(ctx)
+	c.fail(err)
+	return err
+}
+
+// ResetSession implements driver.SessionResetter. A dropped connection reports
+// driver.ErrBadConn so that database/sql discards it.
+func (c *conn) ResetSession(ctx context.Context) error {
+	if err := c.lock(ctx); err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	if c.inner == nil || c.broken {
+		return driver.ErrBadConn
+	}
+	if r, ok := c.inner.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid implements driver.Validator.
+func (c *conn) IsValid() bool {
+	if err := c.lock(context.Background()); err != nil {
+		return false
+	}
+	defer c.unlock()
+
+	if c.inner == nil || c.broken {
+		return false
+	}
+	if v, ok := c.inner.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if err := c.lock(context.Background()); err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	if ch, ok := c.inner.(driver.NamedValueChecker); ok {
+		return ch.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *conn) Close() error {
+	if err := c.lock(context.Background()); err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	if c.inner == nil {
+		return nil
+	}
+	err := c.inner.Close()
+	c.inner = nil
+	return err
+}
+
+// stmt is a prepared statement that is prepared again after its connection was
+// replaced.
+type stmt struct {
+	conn     *conn
+	query    string
+	inner    driver.Stmt
+	gen      int
+	numInput int
+}
+
+// prepare prepares the statement on the live connection; the caller holds the lock.
+func (s *stmt) prepare(ctx context.Context) error {
+	var inner driver.Stmt
+	var err error
+	if p, ok := s.conn.inner.(driver.ConnPrepareContext); ok {
+		inner, err = p.PrepareContext(ctx, s.query)
+	} else {
+		inner, err = s.conn.inner.Prepare(s.query)
+	}
+	if err != nil {
+		return err
+	}
+	s.inner, s.gen, s.numInput = inner, s.conn.gen, inner.NumInput()
+	return nil
+}
+
+// current returns the statement prepared on the live connection.
+func (s *stmt) current(ctx context.Context) (driver.Stmt, error) {
+	if s.gen != s.conn.gen || s.inner == nil {
+		if err := s.prepare(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return s.inner, nil
+}
+
+func (s *stmt) NumInput() int {
+	return s.numInput
+}
+
+func (s *stmt) Close() error {
+	if err := s.conn.lock(context.Background()); err != nil {
+		return err
+	}
+	defer s.conn.unlock()
+
+	// A statement of a replaced connection went away with it.
+	if s.inner == nil || s.gen != s.conn.gen || s.conn.inner == nil {
+		return nil
+	}
+	return s.inner.Close()
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	call := newCall[driver.Result](nil)
+	err := s.conn.run(ctx, s.conn.connector.opts.Exec, func(ctx context.Context, _ driver.Conn) error {
+		inner, err := s.current(ctx)
+		if err != nil {
+			return err
+		}
+		var res driver.Result
+		if e, ok := inner.(driver.StmtExecContext); ok {
+			res, err = e.ExecContext(ctx, args)
+		} else {
+			var values []driver.Value
+			if values, err = plainValues(args); err != nil {
+				return err
+			}
+			res, err = inner.Exec(values)
+		}
+		if err != nil {
+			return err
+		}
+		if !call.keep(res) {
+			return context.Canceled
+		}
+		return nil
+	})
+	return call.finish(err), err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	call := newCall(closeRows)
+	err := s.conn.run(ctx, s.conn.connector.opts.Query, func(ctx context.Context, _ driver.Conn) error {
+		inner, err := s.current(ctx)
+		if err != nil {
+			return err
+		}
+		var rows driver.Rows
+		if q, ok := inner.(driver.StmtQueryContext); ok {
+			rows, err = q.QueryContext(ctx, args)
+		} else {
+			var values []driver.Value
+			if values, err = plainValues(args); err != nil {
+				return err
+			}
+			rows, err = inner.Query(values)
+		}
+		if err != nil {
+			return err
+		}
+		if !call.keep(rows) {
+			return context.Canceled
+		}
+		return nil
+	})
+	return call.finish(err), err
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func plainValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, errNamedArgs
+		}
+		values[i] = a.Value
+	}
+	return values, nil
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+// tx ends the connection's transaction, dropping the connection if it failed
+// during the transaction.
+type tx struct {
+	conn  *conn
+	inner driver.Tx
+}
+
+func (t *tx) Commit() error {
+	return t.end(t.inner.Commit)
+}
+
+func (t *tx) Rollback() error {
+	return t.end(t.inner.Rollback)
+}
+
+func (t *tx) end(fn func() error) error {
+	c := t.conn
+	if err := c.lock(context.Background()); err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	err := fn()
+	c.inTx = false
+	if c.broken {
+		c.broken = false
+		c.drop()
+	}
+	c.fail(err)
+	return err
+}
@@ -0,0 +1,179 @@
+// Package gosentrysql applies gosentry pipelines to database/sql drivers.
+package gosentrysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+type ConnectorOptions struct {
+	// Connect protects opening connections. Nil applies no policies.
+	Connect *gosentry.Pipeline
+
+	// Query protects queries, including QueryContext on prepared statements. Nil
+	// applies no policies.
+	Query *gosentry.Pipeline
+
+	// Exec protects exec calls. Use a pipeline without retries here if statements
+	// are not idempotent: a statement cut off by a connection reset may have run.
+	// Nil applies no policies.
+	Exec *gosentry.Pipeline
+
+	// IsTransient reports whether an error may go away on a new attempt. Other
+	// errors are not retried. Defaults to IsTransient.
+	IsTransient func(err error) bool
+}
+
+func DefaultConnectorOptions() ConnectorOptions {
+	return ConnectorOptions{
+		IsTransient: IsTransient,
+	}
+}
+
+func applyConnectorDefaults(options ConnectorOptions) ConnectorOptions {
+	defaults := DefaultConnectorOptions()
+
+	if options.IsTransient == nil {
+		options.IsTransient = defaults.IsTransient
+	}
+
+	return options
+}
+
+// IsTransient reports whether err is a broken or unreachable connection:
+// driver.ErrBadConn, an unexpected EOF, a connection reset, refused or aborted, a
+// broken pipe, or a network timeout. Drivers report server-side transient errors,
+// such as serialization failures, in their own types; wrap IsTransient to add them.
+func IsTransient(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout()
+}
+
+// Connector is a driver.Connector whose connections run through pipelines. A
+// connection that fails with a transient error outside a transaction is closed and
+// replaced, through the Connect pipeline, before the next attempt; its prepared
+// statements are prepared again. Inside a transaction nothing is retried, and a
+// connection that failed is discarded once the transaction ends.
+type Connector struct {
+	base   driver.Connector
+	driver driver.Driver
+	opts   ConnectorOptions
+}
+
+// NewConnector wraps base. Use it with sql.OpenDB.
+func NewConnector(base driver.Connector, options ConnectorOptions) *Connector {
+	return &Connector{base: base, opts: applyConnectorDefaults(options)}
+}
+
+// Connect implements driver.Connector.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	inner, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newConn(c, inner), nil
+}
+
+// connect opens a connection of the base connector through the Connect pipeline.
+func (c *Connector) connect(ctx context.Context) (driver.Conn, error) {
+	var inner driver.Conn
+	err := c.run(ctx, c.opts.Connect, false, func(ctx context.Context) error {
+		var err error
+		inner, err = c.base.Connect(ctx)
+		return err
+	})
+	return inner, err
+}
+
+// Driver implements driver.Connector.
+func (c *Connector) Driver() driver.Driver {
+	if c.driver != nil {
+		return c.driver
+	}
+	return c.base.Driver()
+}
+
+// run calls fn through p. Errors that are not transient, or every error if final is
+// set, are marked permanent so that retries stop; the mark is removed again before
+// run returns.
+func (c *Connector) run(ctx context.Context, p *gosentry.Pipeline, final bool, fn func(ctx context.Context) error) error {
+	if p == nil {
+		return fn(ctx)
+	}
+	_, err := p.Execute(ctx, func(ctx context.Context) (any, error) {
+		err := fn(ctx)
+		if err != nil && (final || !c.opts.IsTransient(err)) {
+			err = policies.Permanent(err)
+		}
+		return nil, err
+	})
+	var perm *policies.PermanentError
+	if errors.As(err, &perm) {
+		return perm.Err
+	}
+	return err
+}
+
+// Driver wraps a driver.Driver so that sql.Open uses pipelines; see Connector.
+type Driver struct {
+	base driver.Driver
+	opts ConnectorOptions
+}
+
+// Wrap returns base with its connections running through pipelines. Register the
+// result with sql.Register under a new name, or prefer NewConnector with sql.OpenDB.
+func Wrap(base driver.Driver, options ConnectorOptions) *Driver {
+	return &Driver{base: base, opts: applyConnectorDefaults(options)}
+}
+
+// Open implements driver.Driver.
+func (d *Driver) Open(name string) (driver.Conn, error) {
+	c, err := d.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.Connect(context.Background())
+}
+
+// OpenConnector implements driver.DriverContext.
+func (d *Driver) OpenConnector(name string) (driver.Connector, error) {
+	var base driver.Connector = dsnConnector{driver: d.base, name: name}
+	if dc, ok := d.base.(driver.DriverContext); ok {
+		var err error
+		if base, err = dc.OpenConnector(name); err != nil {
+			return nil, err
+		}
+	}
+	c := NewConnector(base, d.opts)
+	c.driver = d
+	return c, nil
+}
+
+// dsnConnector adapts a driver without DriverContext, like database/sql does.
+type dsnConnector struct {
+	driver driver.Driver
+	name   string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.name)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
@@ -0,0 +1,194 @@
+package gosentrysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+func retrying(name string) *gosentry.Pipeline {
+	return gosentry.NewPipeline(name, policies.Retry(policies.RetryOptions{
+		MaxAttempts:  3,
+		InitialDelay: time.Millisecond,
+		Backoff:      policies.BackoffFixed,
+	}))
+}
+
+func open(t *testing.T, db *fakeDB, opts ConnectorOptions) *sql.DB {
+	t.Helper()
+	sqlDB := sql.OpenDB(NewConnector(db, opts))
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return sqlDB
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{driver.ErrBadConn, true},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{errors.New("syntax error"), false},
+		{context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.want {
+			t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestConnector_RetriesTransientErrorsOnNewConnection(t *testing.T) {
+	db := &fakeDB{}
+	sqlDB := open(t, db, ConnectorOptions{Query: retrying("query")})
+	if err := sqlDB.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	db.fail(fmt.Errorf("read: %w", syscall.ECONNRESET))
+	var id int64
+	if err := sqlDB.QueryRow("SELECT conn").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	if id != 2 || db.connects != 2 || db.closed != 1 {
+		t.Fatalf("expected the retry on a second connection, got conn %d, %d connects, %d closed", id, db.connects, db.closed)
+	}
+}
+
+func TestConnector_DoesNotRetryPermanentErrors(t *testing.T) {
+	db := &fakeDB{}
+	sqlDB := open(t, db, ConnectorOptions{Exec: retrying("exec")})
+
+	errSyntax := errors.New("syntax error")
+	db.fail(errSyntax, errSyntax)
+	_, err := sqlDB.Exec("INSERT")
+	if !errors.Is(err, errSyntax) {
+		t.Fatalf("expected the syntax error, got %v", err)
+	}
+	var perm *policies.PermanentError
+	if errors.As(err, &perm) {
+		t.Fatal("expected the permanent mark to be removed")
+	}
+	if len(db.failures) != 1 {
+		t.Fatalf("expected a single attempt, %d failures left", len(db.failures))
+	}
+}
+
+func TestConnector_NoRetriesInsideTransaction(t *testing.T) {
+	db := &fakeDB{}
+	sqlDB := open(t, db, ConnectorOptions{Exec: retrying("exec")})
+
+	tx, err := sqlDB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.fail(fmt.Errorf("write: %w", syscall.EPIPE))
+	if _, err := tx.Exec("UPDATE"); !errors.Is(err, syscall.EPIPE) {
+		t.Fatalf("expected the transient error to be returned, got %v", err)
+	}
+	if len(db.execs) != 0 || db.connects != 1 {
+		t.Fatalf("expected no retry and no reconnect, got %v with %d connects", db.execs, db.connects)
+	}
+	tx.Rollback()
+
+	// The failed connection is discarded after the transaction.
+	if _, err := sqlDB.Exec("INSERT"); err != nil {
+		t.Fatal(err)
+	}
+	if db.connects != 2 || db.closed != 1 {
+		t.Fatalf("expected a fresh connection, got %d connects, %d closed", db.connects, db.closed)
+	}
+}
+
+func TestConnector_PreparedStatementsSurviveReconnect(t *testing.T) {
+	db := &fakeDB{}
+	sqlDB := open(t, db, ConnectorOptions{Exec: retrying("exec")})
+
+	stmt, err := sqlDB.Prepare("INSERT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+
+	db.fail(driver.ErrBadConn)
+	if _, err := stmt.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if db.connects != 2 || db.prepares != 2 || len(db.execs) != 1 {
+		t.Fatalf("expected a reconnect and a second prepare, got %d connects, %d prepares, %v", db.connects, db.prepares, db.execs)
+	}
+}
+
+func TestConnector_ClosesRowsOfAbandonedQueries(t *testing.T) {
+	db := &fakeDB{delay: 20 * time.Millisecond}
+	sqlDB := open(t, db, ConnectorOptions{
+		Query: gosentry.NewPipeline("query", policies.Timeout(policies.TimeoutOptions{Duration: 5 * time.Millisecond})),
+	})
+	if err := sqlDB.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := sqlDB.Query("SELECT conn"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the query to time out, got %v", err)
+	}
+	// The next query waits for the abandoned one to leave the connection.
+	db.mu.Lock()
+	db.delay = 0
+	db.mu.Unlock()
+	var id int64
+	if err := sqlDB.QueryRow("SELECT conn").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.openRows != 0 {
+		t.Fatalf("expected every result set to be closed, %d open", db.openRows)
+	}
+}
+
+func TestConnector_RetriesConnect(t *testing.T) {
+	db := &fakeDB{connectErrs: []error{fmt.Errorf("dial: %w", syscall.ECONNREFUSED)}}
+	sqlDB := open(t, db, ConnectorOptions{Connect: retrying("connect")})
+
+	if err := sqlDB.Ping(); err != nil {
+		t.Fatalf("expected the connect to be retried, got %v", err)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	db := &fakeDB{}
+	sql.Register("gosentrysql-test", Wrap(fakeDriver{db}, ConnectorOptions{Query: retrying("query")}))
+	sqlDB, err := sql.Open("gosentrysql-test", "dsn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+
+	db.fail(driver.ErrBadConn)
+	var id int64
+	if err := sqlDB.QueryRow("SELECT conn").Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	if db.connects < 2 {
+		t.Fatalf("expected a reconnect, got %d connects", db.connects)
+	}
+}
+
+// fakeDriver exposes a fakeDB as a driver without DriverContext.
+type fakeDriver struct {
+	db *fakeDB
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return d.db.Connect(context.Background())
+}
@@ -0,0 +1,157 @@
+package gosentrysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"time"
+)
+
+// fakeDB is an in-memory driver whose calls fail with queued errors.
+type fakeDB struct {
+	mu          sync.Mutex
+	failures    []error
+	connectErrs []error
+	connects    int
+	closed      int
+	execs       []string
+	prepares    int
+
+	// delay holds up every query without watching its context.
+	delay    time.Duration
+	openRows int
+}
+
+func (db *fakeDB) fail(errs ...error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.failures = append(db.failures, errs...)
+}
+
+func (db *fakeDB) next() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.failures) == 0 {
+		return nil
+	}
+	err := db.failures[0]
+	db.failures = db.failures[1:]
+	return err
+}
+
+func (db *fakeDB) Connect(context.Context) (driver.Conn, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.connectErrs) > 0 {
+		err := db.connectErrs[0]
+		db.connectErrs = db.connectErrs[1:]
+		return nil, err
+	}
+	db.connects++
+	return &fakeConn{db: db, id: db.connects}, nil
+}
+
+func (db *fakeDB) Driver() driver.Driver { return nil }
+
+type fakeConn struct {
+	db *fakeDB
+	id int
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *fakeConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	c.db.mu.Lock()
+	c.db.prepares++
+	c.db.mu.Unlock()
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.closed++
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.db.next(); err != nil {
+		return nil, err
+	}
+	c.db.mu.Lock()
+	c.db.execs = append(c.db.execs, query)
+	c.db.mu.Unlock()
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.db.next(); err != nil {
+		return nil, err
+	}
+	c.db.mu.Lock()
+	delay := c.db.delay
+	c.db.mu.Unlock()
+	time.Sleep(delay)
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.openRows++
+	return &fakeRows{db: c.db, value: int64(c.id)}, nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, nil)
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, nil)
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+// fakeRows yields a single row with one column holding the connection id.
+type fakeRows struct {
+	db    *fakeDB
+	value int64
+	done  bool
+}
+
+func (r *fakeRows) Columns() []string { return []string{"conn"} }
+
+func (r *fakeRows) Close() error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	r.db.openRows--
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
@@ -0,0 +1,131 @@
+package gosentry
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// LintWarning describes a policy ordering that is likely a mistake.
+type LintWarning struct {
+	// Pipeline is the name of the linted pipeline, if any.
+	Pipeline string
+
+	// Rule is a stable identifier of the anti-pattern, e.g. "retry-inside-circuit-breaker".
+	Rule string
+
+	// Outer and Inner are the policies involved, Outer wrapping Inner.
+	Outer Descriptor
+	Inner Descriptor
+
+	// Message explains the problem and how to fix it.
+	Message string
+}
+
+func (w LintWarning) String() string {
+	if w.Pipeline != "" {
+		return "pipeline " + strconv.Quote(w.Pipeline) + ": " + w.Rule + ": " + w.Message
+	}
+	return w.Rule + ": " + w.Message
+}
+
+// lintRule matches an outer policy wrapping an inner one. check returns the
+// explanation, or "" if the pair is fine; rest holds the policies inside inner.
+type lintRule struct {
+	name  string
+	outer Kind
+	inner Kind
+	check func(outer, inner Descriptor, rest []Descriptor) string
+}
+
+var lintRules = []lintRule{
+	{
+		name:  "retry-inside-circuit-breaker",
+		outer: KindCircuitBreaker,
+		inner: KindRetry,
+		check: func(outer, inner Descriptor, _ []Descriptor) string {
+			return fmt.Sprintf("circuit breaker %q wraps retry %q: the breaker sees all attempts of a call as one "+
+				"failure, so it trips later than configured and its rejections cannot stop the retries. "+
+				"Place the retry outside the circuit breaker.", outer.Name, inner.Name)
+		},
+	},
+	{
+		name:  "timeout-outside-retry",
+		outer: KindTimeout,
+		inner: KindRetry,
+		check: func(outer, inner Descriptor, rest []Descriptor) string {
+			if disabled(outer) {
+				return ""
+			}
+			for _, d := range rest {
+				if d.Kind == KindTimeout && !disabled(d) {
+					// Both an overall and a per-attempt timeout: intended.
+					return ""
+				}
+			}
+			return fmt.Sprintf("timeout %q wraps retry %q: it bounds all attempts together, so later attempts "+
+				"only get the time left over. If each attempt needs its own deadline, place a timeout inside the retry.",
+				outer.Name, inner.Name)
+		},
+	},
+	{
+		name:  "nested-retry",
+		outer: KindRetry,
+		inner: KindRetry,
+		check: func(outer, inner Descriptor, _ []Descriptor) string {
+			return fmt.Sprintf("retry %q wraps retry %q: their attempts multiply, so a single call can hit the "+
+				"dependency far more often than either policy allows. Keep a single retry.", outer.Name, inner.Name)
+		},
+	},
+	{
+		name:  "rate-limit-inside-retry",
+		outer: KindRetry,
+		inner: KindRateLimit,
+		check: func(outer, inner Descriptor, _ []Descriptor) string {
+			return fmt.Sprintf("retry %q wraps rate limiter %q: rejected calls are retried after a short backoff, "+
+				"adding load to a saturated limiter and delaying the rejection. Place the rate limiter outside the retry.",
+				outer.Name, inner.Name)
+		},
+	},
+}
+
+// disabled reports whether d describes a policy switched off by its options, such
+// as a timeout with a negative duration.
+func disabled(d Descriptor) bool {
+	v, _ := d.Option("disabled")
+	return v == true
+}
+
+// Lint checks policies, given outermost first as for Execute, for orderings that
+// are likely mistakes and returns a warning for each. Only policies that carry a
+// Descriptor are checked; like Describe, Lint runs the others to find out.
+func Lint(policies ...Policy) []LintWarning {
+	var ds []Descriptor
+	for _, found := range describe(policies) {
+		ds = append(ds, found...)
+	}
+
+	var warnings []LintWarning
+	for i, outer := range ds {
+		for j := i + 1; j < len(ds); j++ {
+			inner := ds[j]
+			for _, rule := range lintRules {
+				if rule.outer != outer.Kind || rule.inner != inner.Kind {
+					continue
+				}
+				if msg := rule.check(outer, inner, ds[j+1:]); msg != "" {
+					warnings = append(warnings, LintWarning{Rule: rule.name, Outer: outer, Inner: inner, Message: msg})
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// Lint checks the pipeline's policies; see Lint.
+func (p *Pipeline) Lint() []LintWarning {
+	warnings := Lint(p.policies...)
+	for i := range warnings {
+		warnings[i].Pipeline = p.name
+	}
+	return warnings
+}
@@ -0,0 +1,68 @@
+package gosentry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	calls := 0
+	retry := described(KindRetry, "retry", &calls)
+	breaker := described(KindCircuitBreaker, "cb", &calls)
+	timeout := described(KindTimeout, "timeout", &calls)
+	attemptTimeout := described(KindTimeout, "attempt-timeout", &calls)
+	limiter := described(KindRateLimit, "rl", &calls)
+	noTimeout := WithDescriptor(func(next Handler) Handler { return next },
+		Descriptor{Kind: KindTimeout, Name: "off", Options: []Attribute{Attr("disabled", true)}})
+
+	tests := []struct {
+		name     string
+		policies []Policy
+		want     []string
+	}{
+		{"recommended order", []Policy{limiter, timeout, retry, breaker, attemptTimeout}, nil},
+		{"retry inside breaker", []Policy{breaker, retry}, []string{"retry-inside-circuit-breaker"}},
+		{"overall timeout only", []Policy{timeout, retry}, []string{"timeout-outside-retry"}},
+		{"disabled timeout outside retry", []Policy{noTimeout, retry}, nil},
+		{"disabled timeout inside retry", []Policy{timeout, retry, noTimeout}, []string{"timeout-outside-retry"}},
+		{"nested retries", []Policy{retry, retry}, []string{"nested-retry"}},
+		{"limiter inside retry", []Policy{retry, limiter}, []string{"rate-limit-inside-retry"}},
+		{
+			"several problems",
+			[]Policy{timeout, breaker, retry},
+			[]string{"timeout-outside-retry", "retry-inside-circuit-breaker"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, w := range Lint(tt.policies...) {
+				got = append(got, w.Rule)
+				if w.Message == "" {
+					t.Errorf("expected an explanation for %s", w.Rule)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPipeline_Lint(t *testing.T) {
+	calls := 0
+	p := NewPipeline("payments", described(KindCircuitBreaker, "cb", &calls), described(KindRetry, "retry", &calls))
+
+	warnings := p.Lint()
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %v", warnings)
+	}
+	w := warnings[0]
+	if w.Pipeline != "payments" || w.Outer.Name != "cb" || w.Inner.Name != "retry" {
+		t.Fatalf("unexpected warning: %+v", w)
+	}
+	if !strings.HasPrefix(w.String(), `pipeline "payments": retry-inside-circuit-breaker: circuit breaker "cb" wraps retry "retry"`) {
+		t.Fatalf("unexpected message: %s", w)
+	}
+}
@@ -0,0 +1,174 @@
+// Package logging writes gosentry events as structured log/slog records.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+
+	"gosentry"
+)
+
+// Attribute keys used on every record, so log queries stay stable across policies.
+const (
+	KeyEvent      = "event"
+	KeyPipeline   = "pipeline"
+	KeyPolicy     = "policy"
+	KeyPolicyKind = "policy_kind"
+	KeyAttempt    = "attempt"
+	KeyDuration   = "duration"
+	KeyError      = "error"
+	KeyReason     = "reason"
+	KeyFrom       = "from"
+	KeyTo         = "to"
+	KeyTokens     = "tokens"
+	KeyEndpoint   = "endpoint"
+	KeySampleRate = "sample_rate"
+)
+
+type ObserverOptions struct {
+	// Logger receives the records. If nil, slog.Default() is used.
+	Logger *slog.Logger
+
+	// Levels sets the level per event kind. Kinds not listed use the defaults from
+	// DefaultObserverOptions. Rejected configuration reloads are always logged at
+	// least at error level.
+	Levels map[gosentry.EventKind]slog.Level
+
+	// SampleEvery logs only one in every N events of the given kind, for high-volume
+	// kinds such as successes. Kinds not listed, or with N <= 1, are always logged.
+	SampleEvery map[gosentry.EventKind]int
+}
+
+func DefaultObserverOptions() ObserverOptions {
+	return ObserverOptions{
+		Levels: map[gosentry.EventKind]slog.Level{
+			gosentry.EventSuccess:     slog.LevelDebug,
+			gosentry.EventFailure:     slog.LevelDebug,
+			gosentry.EventRetry:       slog.LevelInfo,
+			gosentry.EventRejected:    slog.LevelWarn,
+			gosentry.EventTimeout:     slog.LevelWarn,
+			gosentry.EventStateChange: slog.LevelWarn,
+			gosentry.EventCompleted:   slog.LevelInfo,
+			gosentry.EventReload:      slog.LevelInfo,
+		},
+	}
+}
+
+// Observer is a gosentry.Observer that logs every event it receives.
+type Observer struct {
+	opts     ObserverOptions
+	samplers map[gosentry.EventKind]*sampler
+}
+
+type sampler struct {
+	every uint64
+	seen  atomic.Uint64
+}
+
+// keep reports whether the current event should be logged: the first of every N.
+func (s *sampler) keep() bool {
+	return (s.seen.Add(1)-1)%s.every == 0
+}
+
+func NewObserver(options ObserverOptions) *Observer {
+	opts := applyObserverDefaults(options)
+
+	samplers := map[gosentry.EventKind]*sampler{}
+	for kind, n := range opts.SampleEvery {
+		if n > 1 {
+			samplers[kind] = &sampler{every: uint64(n)}
+		}
+	}
+
+	return &Observer{opts: opts, samplers: samplers}
+}
+
+// Observe implements gosentry.Observer.
+func (o *Observer) Observe(ev gosentry.Event) {
+	ctx := context.Background()
+
+	level, ok := o.opts.Levels[ev.Kind]
+	if !ok {
+		level = slog.LevelInfo
+	}
+	if ev.Kind == gosentry.EventReload && ev.Err != nil && level < slog.LevelError {
+		// A rejected configuration needs attention whatever the level of routine reloads.
+		level = slog.LevelError
+	}
+	if !o.opts.Logger.Enabled(ctx, level) {
+		return
+	}
+
+	s := o.samplers[ev.Kind]
+	if s != nil && !s.keep() {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 8)
+	attrs = append(attrs, slog.String(KeyEvent, string(ev.Kind)))
+	if ev.Pipeline != "" {
+		attrs = append(attrs, slog.String(KeyPipeline, ev.Pipeline))
+	}
+	if ev.Policy != "" {
+		attrs = append(attrs, slog.String(KeyPolicy, ev.Policy))
+	}
+	if ev.PolicyKind != "" {
+		attrs = append(attrs, slog.String(KeyPolicyKind, ev.PolicyKind))
+	}
+	if ev.Attempt > 0 {
+		attrs = append(attrs, slog.Int(KeyAttempt, ev.Attempt))
+	}
+	if ev.Duration > 0 {
+		attrs = append(attrs, slog.Duration(KeyDuration, ev.Duration))
+	}
+	if ev.Reason != "" {
+		attrs = append(attrs, slog.String(KeyReason, ev.Reason))
+	}
+	if ev.Endpoint != "" {
+		attrs = append(attrs, slog.String(KeyEndpoint, ev.Endpoint))
+	}
+	if ev.Kind == gosentry.EventStateChange {
+		attrs = append(attrs, slog.String(KeyFrom, ev.From), slog.String(KeyTo, ev.To))
+	}
+	if ev.PolicyKind == string(gosentry.KindRateLimit) {
+		attrs = append(attrs, slog.Float64(KeyTokens, ev.Tokens))
+	}
+	if ev.Err != nil {
+		attrs = append(attrs, slog.String(KeyError, ev.Err.Error()))
+	}
+	if s != nil {
+		attrs = append(attrs, slog.Uint64(KeySampleRate, s.every))
+	}
+
+	o.opts.Logger.LogAttrs(ctx, level, "gosentry "+string(ev.Kind), attrs...)
+}
+
+// Policy returns a policy that attaches o to every execution it wraps. Place it
+// first in a pipeline to log the events of every policy after it.
+func (o *Observer) Policy() gosentry.Policy {
+	return func(next gosentry.Handler) gosentry.Handler {
+		return func(ctx context.Context) (any, error) {
+			return next(gosentry.WithObserver(ctx, o))
+		}
+	}
+}
+
+func applyObserverDefaults(options ObserverOptions) ObserverOptions {
+	defaults := DefaultObserverOptions()
+
+	if options.Logger == nil {
+		options.Logger = slog.Default()
+	}
+
+	levels := make(map[gosentry.EventKind]slog.Level, len(defaults.Levels))
+	for kind, level := range defaults.Levels {
+		levels[kind] = level
+	}
+	for kind, level := range options.Levels {
+		levels[kind] = level
+	}
+	options.Levels = levels
+
+	return options
+}
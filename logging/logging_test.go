@@ -0,0 +1,143 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+func newTestObserver(buf *bytes.Buffer, opts ObserverOptions) *Observer {
+	opts.Logger = slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return NewObserver(opts)
+}
+
+func records(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid JSON record %q: %v", line, err)
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+func TestObserver_LogsRetriesAndOutcomeWithConsistentKeys(t *testing.T) {
+	var buf bytes.Buffer
+	o := newTestObserver(&buf, ObserverOptions{})
+
+	attempts := 0
+	p := gosentry.NewPipeline("payments",
+		o.Policy(),
+		policies.Retry(policies.RetryOptions{MaxAttempts: 2, InitialDelay: time.Millisecond, Backoff: policies.BackoffFixed}),
+	)
+	p.Execute(context.Background(), func(ctx context.Context) (any, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("flaky")
+		}
+		return "ok", nil
+	})
+
+	recs := records(t, &buf)
+	var retry map[string]any
+	for _, rec := range recs {
+		if rec[KeyEvent] == "retry" {
+			retry = rec
+		}
+	}
+	if retry == nil {
+		t.Fatalf("expected a retry record, got %v", recs)
+	}
+	if retry["level"] != "INFO" || retry[KeyPipeline] != "payments" || retry[KeyPolicy] != "retry" {
+		t.Fatalf("unexpected retry record: %v", retry)
+	}
+	if retry[KeyAttempt] != float64(1) || retry[KeyError] != "flaky" {
+		t.Fatalf("expected attempt and error attributes, got %v", retry)
+	}
+}
+
+func TestObserver_LevelOverrides(t *testing.T) {
+	var buf bytes.Buffer
+	o := newTestObserver(&buf, ObserverOptions{
+		Levels: map[gosentry.EventKind]slog.Level{gosentry.EventRejected: slog.LevelError},
+	})
+
+	o.Observe(gosentry.Event{Kind: gosentry.EventRejected, Policy: "cb", Reason: "circuit_open"})
+	o.Observe(gosentry.Event{Kind: gosentry.EventStateChange, Policy: "cb", From: "closed", To: "open"})
+
+	recs := records(t, &buf)
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(recs))
+	}
+	if recs[0]["level"] != "ERROR" || recs[0][KeyReason] != "circuit_open" {
+		t.Fatalf("expected rejection at ERROR, got %v", recs[0])
+	}
+	if recs[1]["level"] != "WARN" || recs[1][KeyFrom] != "closed" || recs[1][KeyTo] != "open" {
+		t.Fatalf("expected default WARN state change, got %v", recs[1])
+	}
+}
+
+func TestObserver_RejectedReloadLoggedAsError(t *testing.T) {
+	var buf bytes.Buffer
+	o := newTestObserver(&buf, ObserverOptions{})
+
+	o.Observe(gosentry.Event{Kind: gosentry.EventReload})
+	o.Observe(gosentry.Event{Kind: gosentry.EventReload, Reason: "invalid_config", Err: errors.New("bad")})
+
+	recs := records(t, &buf)
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(recs))
+	}
+	if recs[0]["level"] != "INFO" {
+		t.Fatalf("expected successful reload at INFO, got %v", recs[0])
+	}
+	if recs[1]["level"] != "ERROR" || recs[1][KeyError] != "bad" {
+		t.Fatalf("expected rejected reload at ERROR, got %v", recs[1])
+	}
+}
+
+func TestObserver_SamplesHighVolumeEvents(t *testing.T) {
+	var buf bytes.Buffer
+	o := newTestObserver(&buf, ObserverOptions{
+		SampleEvery: map[gosentry.EventKind]int{gosentry.EventSuccess: 10},
+	})
+
+	for i := 0; i < 25; i++ {
+		o.Observe(gosentry.Event{Kind: gosentry.EventSuccess, Policy: "rl"})
+	}
+	o.Observe(gosentry.Event{Kind: gosentry.EventFailure, Policy: "rl"})
+
+	recs := records(t, &buf)
+	if len(recs) != 4 {
+		t.Fatalf("expected 3 sampled successes and 1 failure, got %d", len(recs))
+	}
+	if recs[0][KeySampleRate] != float64(10) {
+		t.Fatalf("expected sample rate attribute, got %v", recs[0])
+	}
+}
+
+func TestObserver_SkipsDisabledLevels(t *testing.T) {
+	var buf bytes.Buffer
+	o := NewObserver(ObserverOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})),
+	})
+
+	o.Observe(gosentry.Event{Kind: gosentry.EventSuccess})
+	if buf.Len() != 0 {
+		t.Fatalf("expected debug events to be dropped, got %s", buf.String())
+	}
+}
@@ -0,0 +1,318 @@
+// Package metrics records gosentry events as Prometheus metrics and serves them in
+// the Prometheus text exposition format, using only the standard library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+// Circuit breaker states exported by the state gauge. They match policies.CircuitBreakerState.
+var breakerStates = []string{"closed", "open", "half-open"}
+
+type CollectorOptions struct {
+	// Namespace prefixes every metric name. Defaults to "gosentry".
+	Namespace string
+
+	// Buckets are the upper bounds, in seconds, of the latency histograms.
+	Buckets []float64
+}
+
+func DefaultCollectorOptions() CollectorOptions {
+	return CollectorOptions{
+		Namespace: "gosentry",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}
+}
+
+// Collector is a gosentry.Observer that aggregates events into counters, gauges and
+// histograms. It is also an http.Handler serving them in the Prometheus text format.
+// The rate limiter tokens gauge is read from the limiters registered with
+// RegisterLimiter when metrics are written, so it includes refills.
+type Collector struct {
+	opts CollectorOptions
+
+	mu sync.Mutex
+
+	calls      map[series]float64
+	successes  map[series]float64
+	failures   map[series]float64
+	retries    map[series]float64
+	rejections map[series]float64
+	breakers   map[series]string
+	limiters   map[string]*policies.Limiter
+	lookups    map[series]float64
+	latency    map[series]*histogram
+	executions map[series]float64
+	execTime   map[series]*histogram
+}
+
+// series identifies one label set. extra holds the family-specific label
+// (reason, result) when there is one.
+type series struct {
+	pipeline string
+	policy   string
+	extra    string
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func NewCollector(options CollectorOptions) *Collector {
+	return &Collector{
+		opts:       applyCollectorDefaults(options),
+		calls:      map[series]float64{},
+		successes:  map[series]float64{},
+		failures:   map[series]float64{},
+		retries:    map[series]float64{},
+		rejections: map[series]float64{},
+		breakers:   map[series]string{},
+		limiters:   map[string]*policies.Limiter{},
+		lookups:    map[series]float64{},
+		latency:    map[series]*histogram{},
+		executions: map[series]float64{},
+		execTime:   map[series]*histogram{},
+	}
+}
+
+// Observe implements gosentry.Observer.
+func (c *Collector) Observe(ev gosentry.Event) {
+	s := series{pipeline: ev.Pipeline, policy: ev.Policy}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ev.PolicyKind == string(gosentry.KindCircuitBreaker) {
+		if _, ok := c.breakers[s]; !ok {
+			c.breakers[s] = "closed"
+		}
+	}
+
+	switch ev.Kind {
+	case gosentry.EventSuccess:
+		c.calls[s]++
+		c.successes[s]++
+		c.observeLatency(c.latency, s, ev)
+	case gosentry.EventFailure, gosentry.EventTimeout:
+		c.calls[s]++
+		c.failures[s]++
+		c.observeLatency(c.latency, s, ev)
+	case gosentry.EventRetry:
+		c.retries[s]++
+	case gosentry.EventRejected:
+		c.rejections[series{pipeline: ev.Pipeline, policy: ev.Policy, extra: ev.Reason}]++
+	case gosentry.EventStateChange:
+		if ev.PolicyKind == string(gosentry.KindCircuitBreaker) {
+			c.breakers[s] = ev.To
+		}
+	case gosentry.EventCompleted:
+		result := "success"
+		if ev.Err != nil {
+			result = "failure"
+		}
+		c.executions[series{pipeline: ev.Pipeline, extra: result}]++
+		c.observeLatency(c.execTime, series{pipeline: ev.Pipeline}, ev)
+	}
+
+	if ev.PolicyKind == string(gosentry.KindCache) && (ev.Kind == gosentry.EventSuccess || ev.Kind == gosentry.EventFailure) {
+		c.lookups[series{pipeline: ev.Pipeline, policy: ev.Policy, extra: ev.Reason}]++
+	}
+}
+
+func (c *Collector) observeLatency(m map[series]*histogram, s series, ev gosentry.Event) {
+	h, ok := m[s]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(c.opts.Buckets))}
+		m[s] = h
+	}
+
+	v := ev.Duration.Seconds()
+	for i, le := range c.opts.Buckets {
+		if v <= le {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// RegisterLimiter reports the tokens of l under l.Name(), replacing any limiter of
+// that name.
+func (c *Collector) RegisterLimiter(l *policies.Limiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limiters[l.Name()] = l
+}
+
+// ServeHTTP writes all metrics in the Prometheus text exposition format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = c.Write(w)
+}
+
+// Write writes all metrics in the Prometheus text exposition format to w.
+func (c *Collector) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	c.mu.Lock()
+	ns := c.opts.Namespace
+	writeCounter(bw, ns+"_calls_total", "Calls that ran through a policy.", c.calls, "")
+	writeCounter(bw, ns+"_successes_total", "Calls through a policy that succeeded.", c.successes, "")
+	writeCounter(bw, ns+"_failures_total", "Calls through a policy that failed or timed out.", c.failures, "")
+	writeCounter(bw, ns+"_retries_total", "Attempts that were retried.", c.retries, "")
+	writeCounter(bw, ns+"_rejections_total", "Calls rejected by a policy without running.", c.rejections, "reason")
+	c.writeBreakers(bw, ns+"_circuit_breaker_state")
+	tokens := make(map[series]float64, len(c.limiters))
+	for name, l := range c.limiters {
+		tokens[series{policy: name}] = l.Tokens()
+	}
+	writeGauge(bw, ns+"_rate_limiter_tokens", "Tokens currently in a rate limiter bucket.", tokens)
+	writeCounter(bw, ns+"_cache_lookups_total", "Calls through a cache by result (hit, miss or stale).", c.lookups, "result")
+	c.writeHistogram(bw, ns+"_call_duration_seconds", "Latency of calls through a policy.", c.latency)
+	writeCounter(bw, ns+"_executions_total", "Completed executions by result.", c.executions, "result")
+	c.writeHistogram(bw, ns+"_execution_duration_seconds", "Latency of complete executions.", c.execTime)
+	c.mu.Unlock()
+
+	return bw.Flush()
+}
+
+func writeCounter(w io.Writer, name, help string, m map[series]float64, extra string) {
+	writeFamily(w, name, help, "counter", m, extra)
+}
+
+func writeGauge(w io.Writer, name, help string, m map[series]float64) {
+	writeFamily(w, name, help, "gauge", m, "")
+}
+
+func writeFamily(w io.Writer, name, help, typ string, m map[series]float64, extra string) {
+	if len(m) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	for _, s := range sortedSeries(m) {
+		fmt.Fprintf(w, "%s%s %s\n", name, labels(s, extra), formatValue(m[s]))
+	}
+}
+
+func (c *Collector) writeBreakers(w io.Writer, name string) {
+	if len(c.breakers) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s Current circuit breaker state (1 for the active state).\n# TYPE %s gauge\n", name, name)
+	for _, s := range sortedSeries(c.breakers) {
+		for _, state := range breakerStates {
+			v := 0
+			if c.breakers[s] == state {
+				v = 1
+			}
+			fmt.Fprintf(w, "%s%s %d\n", name, labels(series{pipeline: s.pipeline, policy: s.policy, extra: state}, "state"), v)
+		}
+	}
+}
+
+func (c *Collector) writeHistogram(w io.Writer, name, help string, m map[series]*histogram) {
+	if len(m) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for _, s := range sortedSeries(m) {
+		h := m[s]
+		base := labels(s, "")
+		for i, le := range c.opts.Buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(base, "le", formatValue(le)), h.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(base, "le", "+Inf"), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, base, formatValue(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", name, base, h.count)
+	}
+}
+
+func sortedSeries[V any](m map[series]V) []series {
+	out := make([]series, 0, len(m))
+	for s := range m {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].pipeline != out[j].pipeline {
+			return out[i].pipeline < out[j].pipeline
+		}
+		if out[i].policy != out[j].policy {
+			return out[i].policy < out[j].policy
+		}
+		return out[i].extra < out[j].extra
+	})
+	return out
+}
+
+// labels renders the label set of s. Pipeline and policy labels are omitted when empty;
+// extra names the label holding s.extra, if any.
+func labels(s series, extra string) string {
+	var parts []string
+	if s.pipeline != "" {
+		parts = append(parts, label("pipeline", s.pipeline))
+	}
+	if s.policy != "" {
+		parts = append(parts, label("policy", s.policy))
+	}
+	if extra != "" {
+		parts = append(parts, label(extra, s.extra))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func withLabel(base, name, value string) string {
+	l := label(name, value)
+	if base == "" {
+		return "{" + l + "}"
+	}
+	return base[:len(base)-1] + "," + l + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func label(name, value string) string {
+	return name + `="` + labelEscaper.Replace(value) + `"`
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func applyCollectorDefaults(options CollectorOptions) CollectorOptions {
+	defaults := DefaultCollectorOptions()
+
+	if options.Namespace == "" {
+		options.Namespace = defaults.Namespace
+	}
+	if len(options.Buckets) == 0 {
+		options.Buckets = defaults.Buckets
+	}
+	options.Buckets = append([]float64(nil), options.Buckets...)
+	sort.Float64s(options.Buckets)
+
+	return options
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+func scrape(t *testing.T, c *Collector) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	return rec.Body.String()
+}
+
+func expectLine(t *testing.T, body, line string) {
+	t.Helper()
+	for _, l := range strings.Split(body, "\n") {
+		if l == line {
+			return
+		}
+	}
+	t.Fatalf("expected line %q in output:\n%s", line, body)
+}
+
+func TestCollector_RecordsPolicyEvents(t *testing.T) {
+	c := NewCollector(CollectorOptions{})
+	p := gosentry.NewPipeline("payments",
+		policies.Retry(policies.RetryOptions{MaxAttempts: 2, InitialDelay: time.Millisecond, Backoff: policies.BackoffFixed}),
+		policies.CircuitBreaker(policies.CircuitBreakerOptions{FailureThreshold: 2, OpenTimeout: time.Minute}),
+	).WithObserver(c)
+
+	failing := func(ctx context.Context) (any, error) { return nil, errors.New("boom") }
+	p.Execute(context.Background(), failing)
+	p.Execute(context.Background(), failing)
+
+	body := scrape(t, c)
+
+	expectLine(t, body, `# TYPE gosentry_calls_total counter`)
+	expectLine(t, body, `gosentry_calls_total{pipeline="payments",policy="circuit_breaker"} 2`)
+	expectLine(t, body, `gosentry_failures_total{pipeline="payments",policy="retry"} 2`)
+	expectLine(t, body, `gosentry_retries_total{pipeline="payments",policy="retry"} 2`)
+	expectLine(t, body, `gosentry_rejections_total{pipeline="payments",policy="circuit_breaker",reason="circuit_open"} 2`)
+	expectLine(t, body, `gosentry_circuit_breaker_state{pipeline="payments",policy="circuit_breaker",state="open"} 1`)
+	expectLine(t, body, `gosentry_circuit_breaker_state{pipeline="payments",policy="circuit_breaker",state="closed"} 0`)
+	expectLine(t, body, `gosentry_executions_total{pipeline="payments",result="failure"} 2`)
+	expectLine(t, body, `gosentry_call_duration_seconds_count{pipeline="payments",policy="circuit_breaker"} 2`)
+	expectLine(t, body, `gosentry_call_duration_seconds_bucket{pipeline="payments",policy="circuit_breaker",le="+Inf"} 2`)
+}
+
+func TestCollector_RecordsRateLimiterTokens(t *testing.T) {
+	c := NewCollector(CollectorOptions{})
+	ctx := gosentry.WithObserver(context.Background(), c)
+	now := time.Now()
+	limiter := policies.NewLimiter(policies.RateLimitOptions{Rate: 1, Burst: 2, Now: func() time.Time { return now }})
+	c.RegisterLimiter(limiter)
+	rl := limiter.Policy()
+	ok := func(ctx context.Context) (any, error) { return "ok", nil }
+
+	gosentry.Execute(ctx, ok, rl)
+	gosentry.Execute(ctx, ok, rl)
+	gosentry.Execute(ctx, ok, rl)
+
+	body := scrape(t, c)
+	expectLine(t, body, `gosentry_rate_limiter_tokens{policy="rate_limit"} 0`)
+	expectLine(t, body, `gosentry_rejections_total{policy="rate_limit",reason="rate_limited"} 1`)
+	expectLine(t, body, `gosentry_successes_total{policy="rate_limit"} 2`)
+
+	// An idle limiter refills between scrapes.
+	now = now.Add(time.Second)
+	expectLine(t, scrape(t, c), `gosentry_rate_limiter_tokens{policy="rate_limit"} 1`)
+}
+
+func TestCollector_RecordsCacheLookups(t *testing.T) {
+	c := NewCollector(CollectorOptions{})
+	ctx := gosentry.WithObserver(policies.WithKey(context.Background(), "k"), c)
+	cache := policies.Cache(policies.CacheOptions{})
+	ok := func(ctx context.Context) (any, error) { return "ok", nil }
+
+	gosentry.Execute(ctx, ok, cache)
+	gosentry.Execute(ctx, ok, cache)
+	gosentry.Execute(ctx, ok, cache)
+
+	body := scrape(t, c)
+	expectLine(t, body, `gosentry_cache_lookups_total{policy="cache",result="miss"} 1`)
+	expectLine(t, body, `gosentry_cache_lookups_total{policy="cache",result="hit"} 2`)
+}
+
+func TestCollector_HistogramBucketsAreCumulative(t *testing.T) {
+	c := NewCollector(CollectorOptions{Buckets: []float64{0.1, 1}})
+	c.Observe(gosentry.Event{Kind: gosentry.EventSuccess, Policy: "p", Duration: 50 * time.Millisecond})
+	c.Observe(gosentry.Event{Kind: gosentry.EventSuccess, Policy: "p", Duration: 500 * time.Millisecond})
+	c.Observe(gosentry.Event{Kind: gosentry.EventSuccess, Policy: "p", Duration: 5 * time.Second})
+
+	body := scrape(t, c)
+	expectLine(t, body, `gosentry_call_duration_seconds_bucket{policy="p",le="0.1"} 1`)
+	expectLine(t, body, `gosentry_call_duration_seconds_bucket{policy="p",le="1"} 2`)
+	expectLine(t, body, `gosentry_call_duration_seconds_bucket{policy="p",le="+Inf"} 3`)
+	expectLine(t, body, `gosentry_call_duration_seconds_sum{policy="p"} 5.55`)
+}
+
+func TestCollector_EscapesLabelValues(t *testing.T) {
+	c := NewCollector(CollectorOptions{Namespace: "app"})
+	c.Observe(gosentry.Event{Kind: gosentry.EventRetry, Policy: "a\"b\\c\nd"})
+
+	body := scrape(t, c)
+	expectLine(t, body, `app_retries_total{policy="a\"b\\c\nd"} 1`)
+}
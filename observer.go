@@ -0,0 +1,224 @@
+package gosentry
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// EventKind identifies what happened inside a policy.
+type EventKind string
+
+const (
+	// EventSuccess is emitted when a policy's wrapped call returns without error.
+	EventSuccess EventKind = "success"
+
+	// EventFailure is emitted when a policy's wrapped call returns an error.
+	EventFailure EventKind = "failure"
+
+	// EventRetry is emitted when a failed attempt is about to be retried.
+	EventRetry EventKind = "retry"
+
+	// EventRejected is emitted when a policy refuses a call without running it.
+	EventRejected EventKind = "rejected"
+
+	// EventTimeout is emitted when a call is abandoned because a deadline was reached.
+	EventTimeout EventKind = "timeout"
+
+	// EventStateChange is emitted when a stateful policy (e.g. a circuit breaker) changes state.
+	EventStateChange EventKind = "state_change"
+
+	// EventCompleted is emitted once per execution with its final outcome.
+	EventCompleted EventKind = "completed"
+
+	// EventReload is emitted when new configuration is loaded. Err is set if it was
+	// rejected and the previous configuration kept.
+	EventReload EventKind = "reload"
+)
+
+// Event describes something that happened while executing a handler.
+type Event struct {
+	// Time is when the event was emitted.
+	Time time.Time
+
+	// Pipeline is the name of the pipeline the event belongs to, if any.
+	Pipeline string
+
+	// Policy is the name of the policy that emitted the event. Empty for EventCompleted.
+	Policy string
+
+	// PolicyKind is the Kind of the policy that emitted the event, e.g. "retry" or
+	// "circuit_breaker". Unlike Policy it does not change when a policy is renamed.
+	PolicyKind string
+
+	Kind EventKind
+
+	// Attempt is the 1-based attempt number, for policies that make several attempts.
+	Attempt int
+
+	// Duration is the latency of the call for success, failure, timeout and completed
+	// events, and the delay before the next attempt for retry events.
+	Duration time.Duration
+
+	// Err is the error that caused the event, if any.
+	Err error
+
+	// Reason is a short machine-readable cause for rejections, e.g. "circuit_open".
+	Reason string
+
+	// From and To are the previous and new state for state change events.
+	From string
+	To   string
+
+	// Tokens is set by rate limiters: the tokens left in the bucket after the
+	// admission decision for the call.
+	Tokens float64
+
+	// Rate and Burst are set by rate limiters along with Tokens: the refill rate,
+	// in tokens per second, and the bucket size at the admission decision.
+	Rate  float64
+	Burst int
+
+	// Endpoint is set by policies that choose between several targets, such as
+	// failover: the endpoint the call or attempt went to.
+	Endpoint string
+}
+
+// Observer receives events emitted by policies.
+// Observe is called synchronously on the calling goroutine and must not block.
+type Observer interface {
+	Observe(ev Event)
+}
+
+// ObserverFunc adapts a function to the Observer interface.
+type ObserverFunc func(ev Event)
+
+func (f ObserverFunc) Observe(ev Event) {
+	f(ev)
+}
+
+// NopObserver discards every event.
+var NopObserver Observer = ObserverFunc(func(Event) {})
+
+type multiObserver []Observer
+
+func (m multiObserver) Observe(ev Event) {
+	for _, o := range m {
+		o.Observe(ev)
+	}
+}
+
+// MultiObserver fans every event out to all of the given observers, in order.
+// Nil observers are skipped.
+func MultiObserver(observers ...Observer) Observer {
+	var m multiObserver
+	for _, o := range observers {
+		if o != nil {
+			m = append(m, o)
+		}
+	}
+	switch len(m) {
+	case 0:
+		return NopObserver
+	case 1:
+		return m[0]
+	}
+	return m
+}
+
+// combineObservers is like MultiObserver but returns nil when both are nil, so that
+// Observed stays false.
+func combineObservers(a, b Observer) Observer {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	}
+	return MultiObserver(a, b)
+}
+
+type observerHolder struct {
+	observer Observer
+}
+
+var globalObserver atomic.Pointer[observerHolder]
+
+// SetObserver installs an observer that receives events from every execution in the
+// process, in addition to any observer attached to the context or pipeline.
+// Passing nil removes it.
+func SetObserver(o Observer) {
+	if o == nil {
+		globalObserver.Store(nil)
+		return
+	}
+	globalObserver.Store(&observerHolder{observer: o})
+}
+
+type execInfoKey struct{}
+
+// execInfo is the per-execution state carried in the context.
+type execInfo struct {
+	pipeline string
+	observer Observer
+	tracer   Tracer
+}
+
+func execInfoFrom(ctx context.Context) *execInfo {
+	info, _ := ctx.Value(execInfoKey{}).(*execInfo)
+	return info
+}
+
+// WithObserver returns a context whose executions report events to o, in addition
+// to any observer already attached to ctx.
+func WithObserver(ctx context.Context, o Observer) context.Context {
+	info := execInfo{observer: o}
+	if parent := execInfoFrom(ctx); parent != nil {
+		info = *parent
+		info.observer = combineObservers(parent.observer, o)
+	}
+	return context.WithValue(ctx, execInfoKey{}, &info)
+}
+
+// Observed reports whether events emitted on ctx would reach any observer or span.
+// Policies use it to skip measuring when nobody is listening.
+func Observed(ctx context.Context) bool {
+	if globalObserver.Load() != nil {
+		return true
+	}
+	info := execInfoFrom(ctx)
+	if info != nil && info.observer != nil {
+		return true
+	}
+	return spanFrom(ctx) != nil
+}
+
+// Emit delivers ev to the observer attached to ctx and to the global observer, and
+// records it as an event on the current span, if any.
+// Time and Pipeline are filled in if unset.
+func Emit(ctx context.Context, ev Event) {
+	info := execInfoFrom(ctx)
+	global := globalObserver.Load()
+	span := spanFrom(ctx)
+	if global == nil && (info == nil || info.observer == nil) && span == nil {
+		return
+	}
+
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	if info != nil {
+		if ev.Pipeline == "" {
+			ev.Pipeline = info.pipeline
+		}
+		if info.observer != nil {
+			info.observer.Observe(ev)
+		}
+	}
+	if global != nil {
+		global.observer.Observe(ev)
+	}
+	if span != nil {
+		span.AddEvent("gosentry."+string(ev.Kind), eventAttributes(ev)...)
+	}
+}
@@ -0,0 +1,125 @@
+package gosentry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) Observe(ev Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+}
+
+func (r *recorder) kinds() []EventKind {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var kinds []EventKind
+	for _, ev := range r.events {
+		kinds = append(kinds, ev.Kind)
+	}
+	return kinds
+}
+
+func TestEmit_NoObserverIsNoop(t *testing.T) {
+	if Observed(context.Background()) {
+		t.Fatal("expected background context not to be observed")
+	}
+	Emit(context.Background(), Event{Kind: EventSuccess})
+}
+
+func TestEmit_DeliversToContextAndGlobalObservers(t *testing.T) {
+	global := &recorder{}
+	SetObserver(global)
+	defer SetObserver(nil)
+
+	local := &recorder{}
+	ctx := WithObserver(context.Background(), local)
+
+	Emit(ctx, Event{Kind: EventRetry, Policy: "retry"})
+
+	if len(local.events) != 1 || len(global.events) != 1 {
+		t.Fatalf("expected one event each, got local=%d global=%d", len(local.events), len(global.events))
+	}
+	if local.events[0].Time.IsZero() {
+		t.Fatal("expected Time to be filled in")
+	}
+}
+
+func TestMultiObserver_FansOut(t *testing.T) {
+	a, b := &recorder{}, &recorder{}
+	m := MultiObserver(a, nil, b)
+
+	m.Observe(Event{Kind: EventFailure})
+
+	if len(a.events) != 1 || len(b.events) != 1 {
+		t.Fatalf("expected both observers to receive the event, got a=%d b=%d", len(a.events), len(b.events))
+	}
+}
+
+func TestWithObserver_StacksObservers(t *testing.T) {
+	a, b := &recorder{}, &recorder{}
+	ctx := WithObserver(WithObserver(context.Background(), a), b)
+
+	Emit(ctx, Event{Kind: EventSuccess})
+
+	if len(a.events) != 1 || len(b.events) != 1 {
+		t.Fatalf("expected both observers to receive the event, got a=%d b=%d", len(a.events), len(b.events))
+	}
+}
+
+func TestExecute_EmitsCompleted(t *testing.T) {
+	rec := &recorder{}
+	ctx := WithObserver(context.Background(), rec)
+	boom := errors.New("boom")
+
+	_, err := Execute(ctx, func(ctx context.Context) (any, error) {
+		return nil, boom
+	})
+	if err != boom {
+		t.Fatalf("expected boom, got %v", err)
+	}
+
+	kinds := rec.kinds()
+	if len(kinds) != 1 || kinds[0] != EventCompleted {
+		t.Fatalf("expected a single completed event, got %v", kinds)
+	}
+	if rec.events[0].Err != boom {
+		t.Fatalf("expected completed event to carry the error, got %v", rec.events[0].Err)
+	}
+}
+
+func TestPipeline_AttachesNameAndObserver(t *testing.T) {
+	rec := &recorder{}
+	emitting := func(next Handler) Handler {
+		return func(ctx context.Context) (any, error) {
+			Emit(ctx, Event{Kind: EventRetry, Policy: "custom"})
+			return next(ctx)
+		}
+	}
+
+	p := NewPipeline("payments", emitting).WithObserver(rec)
+	res, err := p.Execute(context.Background(), func(ctx context.Context) (any, error) {
+		return "ok", nil
+	})
+	if err != nil || res != "ok" {
+		t.Fatalf("expected ok, got %v, %v", res, err)
+	}
+
+	kinds := rec.kinds()
+	if len(kinds) != 2 || kinds[0] != EventRetry || kinds[1] != EventCompleted {
+		t.Fatalf("expected retry then completed, got %v", kinds)
+	}
+	for _, ev := range rec.events {
+		if ev.Pipeline != "payments" {
+			t.Fatalf("expected pipeline name on every event, got %q", ev.Pipeline)
+		}
+	}
+}
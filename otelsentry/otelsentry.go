@@ -0,0 +1,80 @@
+// Package otelsentry adapts an OpenTelemetry tracer to gosentry.Tracer.
+//
+// It lives in its own module so that gosentry itself does not depend on OpenTelemetry.
+package otelsentry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"gosentry"
+)
+
+// NewTracer returns a gosentry.Tracer that creates OpenTelemetry spans with t.
+func NewTracer(t trace.Tracer) gosentry.Tracer {
+	return tracer{t: t}
+}
+
+type tracer struct {
+	t trace.Tracer
+}
+
+func (t tracer) Start(ctx context.Context, name string, attrs ...gosentry.Attribute) (context.Context, gosentry.Span) {
+	ctx, s := t.t.Start(ctx, name, trace.WithAttributes(convert(attrs)...))
+	return ctx, span{s: s}
+}
+
+type span struct {
+	s trace.Span
+}
+
+func (s span) SetAttributes(attrs ...gosentry.Attribute) {
+	s.s.SetAttributes(convert(attrs)...)
+}
+
+func (s span) AddEvent(name string, attrs ...gosentry.Attribute) {
+	s.s.AddEvent(name, trace.WithAttributes(convert(attrs)...))
+}
+
+func (s span) RecordError(err error) {
+	s.s.RecordError(err)
+	s.s.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() {
+	s.s.End()
+}
+
+func convert(attrs []gosentry.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		kvs = append(kvs, keyValue(a))
+	}
+	return kvs
+}
+
+func keyValue(a gosentry.Attribute) attribute.KeyValue {
+	switch v := a.Value.(type) {
+	case string:
+		return attribute.String(a.Key, v)
+	case bool:
+		return attribute.Bool(a.Key, v)
+	case int:
+		return attribute.Int(a.Key, v)
+	case int64:
+		return attribute.Int64(a.Key, v)
+	case float64:
+		return attribute.Float64(a.Key, v)
+	case time.Duration:
+		return attribute.String(a.Key, v.String())
+	case fmt.Stringer:
+		return attribute.String(a.Key, v.String())
+	default:
+		return attribute.String(a.Key, fmt.Sprint(v))
+	}
+}
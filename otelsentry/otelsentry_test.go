@@ -0,0 +1,49 @@
+package otelsentry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+func TestTracer_ExportsExecutionAndAttemptSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(context.Background())
+
+	p := gosentry.NewPipeline("payments",
+		policies.Retry(policies.RetryOptions{MaxAttempts: 2, InitialDelay: time.Millisecond, Backoff: policies.BackoffFixed}),
+	).WithTracer(NewTracer(provider.Tracer("test")))
+
+	p.Execute(context.Background(), func(ctx context.Context) (any, error) {
+		return nil, errors.New("down")
+	})
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+
+	var exec tracetest.SpanStub
+	for _, s := range spans {
+		if s.Name == "gosentry.execute" {
+			exec = s
+		}
+	}
+	if exec.Status.Code != codes.Error {
+		t.Fatalf("expected execution span to be marked as error, got %v", exec.Status)
+	}
+	for _, s := range spans {
+		if s.Name == "gosentry.retry.attempt" && s.Parent.SpanID() != exec.SpanContext.SpanID() {
+			t.Fatalf("expected attempt spans to be children of the execution span")
+		}
+	}
+}
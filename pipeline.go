@@ -0,0 +1,73 @@
+package gosentry
+
+import "context"
+
+// Pipeline is a named, reusable sequence of policies. Policies are applied in order,
+// the first one being the outermost.
+type Pipeline struct {
+	name     string
+	policies []Policy
+	observer Observer
+	tracer   Tracer
+}
+
+func NewPipeline(name string, policies ...Policy) *Pipeline {
+	return &Pipeline{
+		name:     name,
+		policies: policies,
+	}
+}
+
+// WithObserver attaches an observer that receives the events of every execution
+// of this pipeline. It returns p to allow chaining.
+func (p *Pipeline) WithObserver(o Observer) *Pipeline {
+	p.observer = o
+	return p
+}
+
+// WithTracer traces every execution of this pipeline with t, overriding the global
+// tracer. It returns p to allow chaining.
+func (p *Pipeline) WithTracer(t Tracer) *Pipeline {
+	p.tracer = t
+	return p
+}
+
+func (p *Pipeline) Name() string {
+	return p.name
+}
+
+// Policies returns the policies of the pipeline, outermost first.
+func (p *Pipeline) Policies() []Policy {
+	return append([]Policy(nil), p.policies...)
+}
+
+// Execute runs handler through the pipeline's policies.
+func (p *Pipeline) Execute(ctx context.Context, handler Handler) (any, error) {
+	info := execInfo{pipeline: p.name, observer: p.observer, tracer: p.tracer}
+	if parent := execInfoFrom(ctx); parent != nil {
+		info.observer = combineObservers(parent.observer, p.observer)
+		if info.tracer == nil {
+			info.tracer = parent.tracer
+		}
+	}
+	ctx = context.WithValue(ctx, execInfoKey{}, &info)
+
+	return Execute(ctx, handler, p.policies...)
+}
+
+// ExecuteAsync runs handler through the pipeline in a new goroutine.
+func (p *Pipeline) ExecuteAsync(ctx context.Context, handler Handler) *Future {
+	return startFuture(func() (any, error) {
+		return p.Execute(ctx, handler)
+	})
+}
+
+// ExecuteAll runs every handler through the pipeline concurrently; see ExecuteAll.
+func (p *Pipeline) ExecuteAll(ctx context.Context, handlers []Handler) ([]Result, error) {
+	return p.ExecuteBatch(ctx, handlers, DefaultBatchOptions())
+}
+
+// ExecuteBatch runs every handler through the pipeline; see ExecuteBatch.
+func (p *Pipeline) ExecuteBatch(ctx context.Context, handlers []Handler, options BatchOptions) ([]Result, error) {
+	return runBatch(ctx, handlers, options, p.Execute)
+}
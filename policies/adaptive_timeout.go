@@ -0,0 +1,179 @@
+package policies
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type AdaptiveTimeoutOptions struct {
+	// Percentile is the latency percentile to track, in (0, 1]. e.g. 0.99. Calls cut
+	// off by the timeout count as taking as long as the timeout.
+	Percentile float64
+
+	// Multiplier is applied to the tracked percentile to obtain the timeout.
+	Multiplier float64
+
+	// Min is the lower bound for the adaptive timeout.
+	Min time.Duration
+
+	// Max is the upper bound for the adaptive timeout. If zero, TimeoutOptions.Duration is used.
+	Max time.Duration
+
+	// WarmupSamples is the number of successful calls to observe before the adaptive
+	// timeout is used. Until then TimeoutOptions.Duration applies.
+	WarmupSamples int
+
+	// Window is the approximate number of recent samples that dominate the estimate.
+	// Older samples decay so the timeout follows shifts in latency.
+	Window int
+}
+
+func DefaultAdaptiveTimeoutOptions() AdaptiveTimeoutOptions {
+	return AdaptiveTimeoutOptions{
+		Percentile:    0.99,
+		Multiplier:    1.5,
+		Min:           time.Millisecond,
+		WarmupSamples: 20,
+		Window:        1000,
+	}
+}
+
+func applyAdaptiveTimeoutDefaults(options AdaptiveTimeoutOptions, static time.Duration) AdaptiveTimeoutOptions {
+	defaults := DefaultAdaptiveTimeoutOptions()
+
+	if options.Percentile <= 0 || options.Percentile > 1 {
+		options.Percentile = defaults.Percentile
+	}
+	if options.Multiplier <= 0 {
+		options.Multiplier = defaults.Multiplier
+	}
+	if options.Min <= 0 {
+		options.Min = defaults.Min
+	}
+	if options.Max <= 0 {
+		options.Max = static
+	}
+	if options.Max < options.Min {
+		options.Max = options.Min
+	}
+	if options.WarmupSamples <= 0 {
+		options.WarmupSamples = defaults.WarmupSamples
+	}
+	if options.Window <= 0 {
+		options.Window = defaults.Window
+	}
+
+	return options
+}
+
+// adaptiveTimeout derives a timeout from the observed latency of successful and
+// timed-out calls.
+type adaptiveTimeout struct {
+	opts   AdaptiveTimeoutOptions
+	static time.Duration
+
+	mu      sync.Mutex
+	hist    latencyHistogram
+	samples int
+}
+
+func newAdaptiveTimeout(opts AdaptiveTimeoutOptions, static time.Duration) *adaptiveTimeout {
+	return &adaptiveTimeout{
+		opts:   opts,
+		static: static,
+	}
+}
+
+func (a *adaptiveTimeout) observe(d time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.samples++
+	a.hist.add(d)
+	if a.hist.total > float64(a.opts.Window) {
+		a.hist.decay()
+	}
+}
+
+func (a *adaptiveTimeout) timeout() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.samples < a.opts.WarmupSamples {
+		return a.static
+	}
+
+	d := time.Duration(float64(a.hist.quantile(a.opts.Percentile)) * a.opts.Multiplier)
+	if d < a.opts.Min {
+		d = a.opts.Min
+	}
+	if d > a.opts.Max {
+		d = a.opts.Max
+	}
+	return d
+}
+
+const (
+	// histogramBase is the upper bound of the first bucket.
+	histogramBase = 10 * time.Microsecond
+
+	// histogramGrowth is the ratio between consecutive bucket bounds (~5% relative error).
+	histogramGrowth = 1.1
+
+	// histogramBuckets covers histogramBase up to roughly one hour.
+	histogramBuckets = 210
+)
+
+// latencyHistogram is a streaming histogram with logarithmically sized buckets.
+// Counts are float64 so they can be decayed without losing small buckets entirely.
+type latencyHistogram struct {
+	counts [histogramBuckets]float64
+	total  float64
+}
+
+func (h *latencyHistogram) add(d time.Duration) {
+	h.counts[bucketFor(d)]++
+	h.total++
+}
+
+// decay halves every bucket so recent samples outweigh older ones.
+func (h *latencyHistogram) decay() {
+	h.total = 0
+	for i := range h.counts {
+		h.counts[i] /= 2
+		h.total += h.counts[i]
+	}
+}
+
+// quantile returns the upper bound of the bucket containing the q-th quantile.
+func (h *latencyHistogram) quantile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+
+	target := q * h.total
+	var seen float64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= target {
+			return bucketUpperBound(i)
+		}
+	}
+	return bucketUpperBound(histogramBuckets - 1)
+}
+
+func bucketFor(d time.Duration) int {
+	if d <= histogramBase {
+		return 0
+	}
+	i := int(math.Ceil(math.Log(float64(d)/float64(histogramBase)) / math.Log(histogramGrowth)))
+	if i >= histogramBuckets {
+		return histogramBuckets - 1
+	}
+	return i
+}
+
+func bucketUpperBound(i int) time.Duration {
+	return time.Duration(float64(histogramBase) * math.Pow(histogramGrowth, float64(i)))
+}
@@ -0,0 +1,163 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram_Quantile(t *testing.T) {
+	var h latencyHistogram
+	for i := 1; i <= 100; i++ {
+		h.add(time.Duration(i) * time.Millisecond)
+	}
+
+	p50 := h.quantile(0.5)
+	if p50 < 50*time.Millisecond || p50 > 55*time.Millisecond {
+		t.Fatalf("expected p50 around 50ms, got %v", p50)
+	}
+
+	p99 := h.quantile(0.99)
+	if p99 < 99*time.Millisecond || p99 > 105*time.Millisecond {
+		t.Fatalf("expected p99 around 99ms, got %v", p99)
+	}
+}
+
+func TestLatencyHistogram_DecayFavoursRecentSamples(t *testing.T) {
+	a := newAdaptiveTimeout(applyAdaptiveTimeoutDefaults(AdaptiveTimeoutOptions{
+		Percentile:    0.5,
+		Multiplier:    1,
+		WarmupSamples: 1,
+		Window:        50,
+	}, time.Second), time.Second)
+
+	for i := 0; i < 200; i++ {
+		a.observe(100 * time.Millisecond)
+	}
+	for i := 0; i < 200; i++ {
+		a.observe(time.Millisecond)
+	}
+
+	if got := a.timeout(); got > 2*time.Millisecond {
+		t.Fatalf("expected timeout to follow recent latency, got %v", got)
+	}
+}
+
+func TestAdaptiveTimeout_UsesStaticDurationDuringWarmup(t *testing.T) {
+	a := newAdaptiveTimeout(applyAdaptiveTimeoutDefaults(AdaptiveTimeoutOptions{
+		WarmupSamples: 5,
+	}, time.Second), time.Second)
+
+	for i := 0; i < 4; i++ {
+		a.observe(time.Millisecond)
+	}
+	if got := a.timeout(); got != time.Second {
+		t.Fatalf("expected static timeout during warm-up, got %v", got)
+	}
+
+	a.observe(time.Millisecond)
+	if got := a.timeout(); got >= time.Second {
+		t.Fatalf("expected adaptive timeout after warm-up, got %v", got)
+	}
+}
+
+func TestAdaptiveTimeout_ClampsToBounds(t *testing.T) {
+	a := newAdaptiveTimeout(applyAdaptiveTimeoutDefaults(AdaptiveTimeoutOptions{
+		Multiplier:    10,
+		Min:           50 * time.Millisecond,
+		Max:           200 * time.Millisecond,
+		WarmupSamples: 1,
+	}, time.Second), time.Second)
+
+	a.observe(time.Millisecond)
+	if got := a.timeout(); got != 50*time.Millisecond {
+		t.Fatalf("expected timeout clamped to Min, got %v", got)
+	}
+
+	for i := 0; i < 100; i++ {
+		a.observe(100 * time.Millisecond)
+	}
+	if got := a.timeout(); got != 200*time.Millisecond {
+		t.Fatalf("expected timeout clamped to Max, got %v", got)
+	}
+}
+
+func TestTimeout_AdaptiveShrinksAfterWarmup(t *testing.T) {
+	p := Timeout(TimeoutOptions{
+		Duration: time.Second,
+		Adaptive: &AdaptiveTimeoutOptions{
+			Percentile:    0.99,
+			Multiplier:    2,
+			Min:           20 * time.Millisecond,
+			WarmupSamples: 3,
+		},
+	})
+
+	slow := false
+	h := p(func(ctx context.Context) (any, error) {
+		if !slow {
+			return "ok", nil
+		}
+		select {
+		case <-time.After(500 * time.Millisecond):
+			return "late", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := h(context.Background()); err != nil {
+			t.Fatalf("expected warm-up call to succeed, got %v", err)
+		}
+	}
+
+	slow = true
+	_, err := h(context.Background())
+
+	var terr *TimeoutError
+	if !errors.As(err, &terr) {
+		t.Fatalf("expected *TimeoutError, got %v", err)
+	}
+	if terr.Duration != 20*time.Millisecond {
+		t.Fatalf("expected adaptive timeout of 20ms, got %v", terr.Duration)
+	}
+}
+
+func TestTimeout_AdaptiveGrowsBackAfterTimeouts(t *testing.T) {
+	p := Timeout(TimeoutOptions{
+		Duration: time.Second,
+		Adaptive: &AdaptiveTimeoutOptions{
+			Percentile:    0.99,
+			Multiplier:    2,
+			Min:           20 * time.Millisecond,
+			WarmupSamples: 3,
+		},
+	})
+
+	slow := false
+	h := p(func(ctx context.Context) (any, error) {
+		if !slow {
+			return "ok", nil
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	for i := 0; i < 3; i++ {
+		h(context.Background())
+	}
+
+	slow = true
+	var durations []time.Duration
+	for i := 0; i < 2; i++ {
+		var terr *TimeoutError
+		if _, err := h(context.Background()); !errors.As(err, &terr) {
+			t.Fatalf("expected *TimeoutError, got %v", err)
+		}
+		durations = append(durations, terr.Duration)
+	}
+	if durations[0] != 20*time.Millisecond || durations[1] <= durations[0] {
+		t.Fatalf("expected timed-out calls to raise the timeout, got %v", durations)
+	}
+}
@@ -0,0 +1,486 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"gosentry"
+)
+
+var (
+	// ErrNoHealthyBackend is returned when every backend of a balancer is ejected.
+	ErrNoHealthyBackend = errors.New("no healthy backend")
+)
+
+type BalanceStrategy string
+
+const (
+	// BalanceRoundRobin cycles through the backends.
+	BalanceRoundRobin BalanceStrategy = "round_robin"
+
+	// BalanceRandom picks a backend at random.
+	BalanceRandom BalanceStrategy = "random"
+
+	// BalanceLeastInFlight picks the backend with the fewest calls running.
+	BalanceLeastInFlight BalanceStrategy = "least_in_flight"
+
+	// BalancePowerOfTwo picks two backends at random and takes the cheaper one, the
+	// cost being the latency EWMA times the calls running plus one.
+	BalancePowerOfTwo BalanceStrategy = "p2c"
+)
+
+// OutlierOptions configure ejecting failing backends from a balancer, like Envoy's
+// outlier detection. Zero fields select the defaults.
+type OutlierOptions struct {
+	// ConsecutiveFailures ejects a backend after this many failures in a row.
+	// Defaults to 5; negative disables the check.
+	ConsecutiveFailures int
+
+	// ErrorRate ejects a backend whose share of failed calls in the current Interval
+	// reaches it, once it has served MinRequests calls. Zero disables the check.
+	ErrorRate float64
+
+	// MinRequests is the number of calls in an Interval before ErrorRate applies.
+	// Defaults to 10.
+	MinRequests int
+
+	// Interval is the window of the error rate. A backend's ejection multiplier
+	// also goes down by one for every Interval it is not ejected. Defaults to 10s.
+	Interval time.Duration
+
+	// BaseEjectionTime is how long a backend is ejected the first time; the n-th
+	// ejection in a row lasts n times as long. Defaults to 30s.
+	BaseEjectionTime time.Duration
+
+	// MaxEjectionTime caps the ejection time. Defaults to 5m.
+	MaxEjectionTime time.Duration
+
+	// MaxEjectionPercent is the largest share of the backends ejected at once.
+	// Defaults to 50.
+	MaxEjectionPercent int
+}
+
+type BalancerOptions struct {
+	// Name identifies the policy in events. Defaults to "balancer".
+	Name string
+
+	// Backends are the targets. Handlers read the backend they are called for with
+	// Endpoint.
+	Backends []string
+
+	// Strategy chooses a backend for each call. Defaults to BalanceRoundRobin.
+	Strategy BalanceStrategy
+
+	// Outlier, if set, ejects backends that keep failing.
+	Outlier *OutlierOptions
+
+	// LatencyDecay is the time constant of the latency EWMA used by
+	// BalancePowerOfTwo. Defaults to 10s.
+	LatencyDecay time.Duration
+
+	// FailurePenalty is the latency BalancePowerOfTwo records for a failed call
+	// that returned sooner, so that a backend failing fast does not look like the
+	// fastest one. Defaults to 1s.
+	FailurePenalty time.Duration
+
+	// IsFailure reports whether err counts against the backend. If nil, every error
+	// except a cancellation and a PermanentError does.
+	IsFailure func(err error) bool
+
+	// Now is used for time; if nil, time.Now is used.
+	Now func() time.Time
+}
+
+func DefaultOutlierOptions() OutlierOptions {
+	return OutlierOptions{
+		ConsecutiveFailures: 5,
+		MinRequests:         10,
+		Interval:            10 * time.Second,
+		BaseEjectionTime:    30 * time.Second,
+		MaxEjectionTime:     5 * time.Minute,
+		MaxEjectionPercent:  50,
+	}
+}
+
+func DefaultBalancerOptions() BalancerOptions {
+	return BalancerOptions{
+		Name:           "balancer",
+		Strategy:       BalanceRoundRobin,
+		LatencyDecay:   10 * time.Second,
+		FailurePenalty: time.Second,
+		IsFailure: func(err error) bool {
+			var perm *PermanentError
+			return !errors.Is(err, context.Canceled) && !errors.As(err, &perm)
+		},
+		Now: time.Now,
+	}
+}
+
+func applyOutlierDefaults(options OutlierOptions) OutlierOptions {
+	defaults := DefaultOutlierOptions()
+
+	if options.ConsecutiveFailures == 0 {
+		options.ConsecutiveFailures = defaults.ConsecutiveFailures
+	}
+	if options.MinRequests <= 0 {
+		options.MinRequests = defaults.MinRequests
+	}
+	if options.Interval <= 0 {
+		options.Interval = defaults.Interval
+	}
+	if options.BaseEjectionTime <= 0 {
+		options.BaseEjectionTime = defaults.BaseEjectionTime
+	}
+	if options.MaxEjectionTime <= 0 {
+		options.MaxEjectionTime = defaults.MaxEjectionTime
+	}
+	if options.MaxEjectionTime < options.BaseEjectionTime {
+		options.MaxEjectionTime = options.BaseEjectionTime
+	}
+	if options.MaxEjectionPercent <= 0 || options.MaxEjectionPercent > 100 {
+		options.MaxEjectionPercent = defaults.MaxEjectionPercent
+	}
+
+	return options
+}
+
+func applyBalancerDefaults(options BalancerOptions) BalancerOptions {
+	defaults := DefaultBalancerOptions()
+
+	if options.Name == "" {
+		options.Name = defaults.Name
+	}
+	switch options.Strategy {
+	case BalanceRoundRobin, BalanceRandom, BalanceLeastInFlight, BalancePowerOfTwo:
+	default:
+		options.Strategy = defaults.Strategy
+	}
+	if options.Outlier != nil {
+		outlier := applyOutlierDefaults(*options.Outlier)
+		options.Outlier = &outlier
+	}
+	if options.LatencyDecay <= 0 {
+		options.LatencyDecay = defaults.LatencyDecay
+	}
+	if options.FailurePenalty <= 0 {
+		options.FailurePenalty = defaults.FailurePenalty
+	}
+	if options.IsFailure == nil {
+		options.IsFailure = defaults.IsFailure
+	}
+	if options.Now == nil {
+		options.Now = defaults.Now
+	}
+
+	return options
+}
+
+// BackendStats is a snapshot of one backend of a balancer.
+type BackendStats struct {
+	Backend  string
+	InFlight int
+
+	// Requests and Failures count every call since the balancer was created.
+	Requests uint64
+	Failures uint64
+
+	ConsecutiveFailures int
+
+	// Latency is the EWMA of call latency, counting failed calls as taking at least
+	// FailurePenalty; zero before the first sample.
+	Latency time.Duration
+
+	// Ejected is set while the backend is ejected, until EjectedUntil.
+	Ejected      bool
+	EjectedUntil time.Time
+
+	// Ejections counts every ejection since the balancer was created.
+	Ejections int
+}
+
+type backend struct {
+	name string
+
+	inFlight            int
+	requests, failures  uint64
+	consecutiveFailures int
+	latency             float64 // EWMA in nanoseconds
+	latencyAt           time.Time
+
+	// Error rate window.
+	windowStart                    time.Time
+	windowRequests, windowFailures int
+
+	ejectedUntil time.Time
+	ejections    int
+
+	// multiplier scales the next ejection time; it decays while the backend is in.
+	multiplier int
+}
+
+// LoadBalancer returns a policy that spreads calls across backends.
+func LoadBalancer(options BalancerOptions) gosentry.Policy {
+	return NewBalancer(options).Policy()
+}
+
+// Balancer spreads calls across backends, ejecting those that keep failing. Every
+// policy returned by Policy shares the backends' state. It makes one call per
+// execution; place a Retry outside it to try another backend after a failure.
+type Balancer struct {
+	opts BalancerOptions
+
+	mu       sync.Mutex
+	backends []*backend
+	next     int
+}
+
+// NewBalancer returns a Balancer without validating options; see NewLoadBalancer.
+func NewBalancer(options BalancerOptions) *Balancer {
+	opts := applyBalancerDefaults(options)
+	b := &Balancer{opts: opts}
+	for _, name := range opts.Backends {
+		b.backends = append(b.backends, &backend{name: name})
+	}
+	return b
+}
+
+func (b *Balancer) Name() string {
+	return b.opts.Name
+}
+
+// Stats returns a snapshot of every backend, in the order of Backends.
+func (b *Balancer) Stats() []BackendStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.opts.Now()
+	stats := make([]BackendStats, len(b.backends))
+	for i, be := range b.backends {
+		stats[i] = BackendStats{
+			Backend:             be.name,
+			InFlight:            be.inFlight,
+			Requests:            be.requests,
+			Failures:            be.failures,
+			ConsecutiveFailures: be.consecutiveFailures,
+			Latency:             time.Duration(be.latency),
+			Ejected:             be.ejectedAt(now),
+			Ejections:           be.ejections,
+		}
+		if stats[i].Ejected {
+			stats[i].EjectedUntil = be.ejectedUntil
+		}
+	}
+	return stats
+}
+
+func (be *backend) ejectedAt(now time.Time) bool {
+	return now.Before(be.ejectedUntil)
+}
+
+// Policy returns a policy that runs each call on one of the backends.
+func (b *Balancer) Policy() gosentry.Policy {
+	policy := func(next gosentry.Handler) gosentry.Handler {
+		return func(ctx context.Context) (any, error) {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			obs := observe(ctx, gosentry.KindLoadBalancer, b.opts.Name)
+			be := b.pick()
+			if be == nil {
+				obs.reject("no_healthy_backend", ErrNoHealthyBackend)
+				return nil, ErrNoHealthyBackend
+			}
+
+			start := b.opts.Now()
+			result, err := next(context.WithValue(ctx, endpointKey{}, be.name))
+			if b.done(be, start, err) {
+				obs.emit(gosentry.Event{Kind: gosentry.EventStateChange, From: "healthy", To: "ejected", Endpoint: be.name, Err: err})
+			}
+			obs.finishWith(gosentry.Event{Endpoint: be.name}, err)
+			return result, err
+		}
+	}
+	return gosentry.WithDescriptor(policy, gosentry.Descriptor{
+		Kind:    gosentry.KindLoadBalancer,
+		Name:    b.opts.Name,
+		Options: b.attrs(),
+	})
+}
+
+func (b *Balancer) attrs() []gosentry.Attribute {
+	attrs := []gosentry.Attribute{
+		gosentry.Attr("backends", strings.Join(b.opts.Backends, ",")),
+		gosentry.Attr("strategy", string(b.opts.Strategy)),
+	}
+	if o := b.opts.Outlier; o != nil {
+		attrs = append(attrs,
+			gosentry.Attr("outlier.consecutive_failures", o.ConsecutiveFailures),
+			gosentry.Attr("outlier.error_rate", o.ErrorRate),
+			gosentry.Attr("outlier.base_ejection_time", o.BaseEjectionTime),
+			gosentry.Attr("outlier.max_ejection_time", o.MaxEjectionTime),
+			gosentry.Attr("outlier.max_ejection_percent", o.MaxEjectionPercent),
+		)
+	}
+	return attrs
+}
+
+// pick chooses a backend that is not ejected and counts the call as running on
+// it, or returns nil if there is none.
+func (b *Balancer) pick() *backend {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.opts.Now()
+	available := make([]*backend, 0, len(b.backends))
+	for _, be := range b.backends {
+		if !be.ejectedAt(now) {
+			available = append(available, be)
+		}
+	}
+	if len(available) == 0 {
+		return nil
+	}
+
+	var be *backend
+	switch b.opts.Strategy {
+	case BalanceRandom:
+		be = available[rand.IntN(len(available))]
+	case BalanceLeastInFlight:
+		// Start after the last pick so that ties rotate.
+		for i := range available {
+			c := available[(b.next+i)%len(available)]
+			if be == nil || c.inFlight < be.inFlight {
+				be = c
+			}
+		}
+		b.next++
+	case BalancePowerOfTwo:
+		be = available[0]
+		if len(available) > 1 {
+			i := rand.IntN(len(available))
+			j := rand.IntN(len(available) - 1)
+			if j >= i {
+				j++
+			}
+			be = available[i]
+			if b.cost(available[j], now) < b.cost(be, now) {
+				be = available[j]
+			}
+		}
+	default:
+		be = available[b.next%len(available)]
+		b.next++
+	}
+
+	be.inFlight++
+	return be
+}
+
+// cost estimates how long a new call to be would take. Backends without a latency
+// sample cost nothing, so they are tried early; failures count as slow calls.
+func (b *Balancer) cost(be *backend, now time.Time) float64 {
+	return b.decayed(be, now) * float64(be.inFlight+1)
+}
+
+// decayed returns the latency EWMA of be as of now. Without samples it decays
+// towards zero, so an idle backend is tried again.
+func (b *Balancer) decayed(be *backend, now time.Time) float64 {
+	if be.latencyAt.IsZero() {
+		return 0
+	}
+	return be.latency * math.Exp(-float64(now.Sub(be.latencyAt))/float64(b.opts.LatencyDecay))
+}
+
+// done records the outcome of a call on be and reports whether it ejected be.
+func (b *Balancer) done(be *backend, start time.Time, err error) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.opts.Now()
+	be.inFlight--
+	be.requests++
+	failed := err != nil && b.opts.IsFailure(err)
+
+	if err == nil || failed {
+		sample := float64(now.Sub(start))
+		if failed {
+			sample = max(sample, float64(b.opts.FailurePenalty))
+		}
+		if be.latencyAt.IsZero() {
+			be.latency = sample
+		} else {
+			w := math.Exp(-float64(now.Sub(be.latencyAt)) / float64(b.opts.LatencyDecay))
+			be.latency = be.latency*w + sample*(1-w)
+		}
+		be.latencyAt = now
+	}
+
+	if failed {
+		be.failures++
+		be.consecutiveFailures++
+	} else if err == nil {
+		be.consecutiveFailures = 0
+	}
+
+	o := b.opts.Outlier
+	if o == nil || be.ejectedAt(now) {
+		return false
+	}
+	if now.Sub(be.windowStart) >= o.Interval {
+		be.windowStart, be.windowRequests, be.windowFailures = now, 0, 0
+	}
+	if err == nil || failed {
+		be.windowRequests++
+	}
+	if failed {
+		be.windowFailures++
+	}
+
+	outlier := o.ConsecutiveFailures > 0 && be.consecutiveFailures >= o.ConsecutiveFailures
+	if o.ErrorRate > 0 && be.windowRequests >= o.MinRequests &&
+		float64(be.windowFailures)/float64(be.windowRequests) >= o.ErrorRate {
+		outlier = true
+	}
+	if !outlier || !b.mayEject(now) {
+		return false
+	}
+	b.eject(be, now)
+	return true
+}
+
+// mayEject reports whether one more backend may be ejected without exceeding
+// MaxEjectionPercent.
+func (b *Balancer) mayEject(now time.Time) bool {
+	ejected := 0
+	for _, be := range b.backends {
+		if be.ejectedAt(now) {
+			ejected++
+		}
+	}
+	return (ejected+1)*100 <= b.opts.Outlier.MaxEjectionPercent*len(b.backends)
+}
+
+func (b *Balancer) eject(be *backend, now time.Time) {
+	o := b.opts.Outlier
+	if !be.ejectedUntil.IsZero() {
+		be.multiplier -= int(now.Sub(be.ejectedUntil) / o.Interval)
+		if be.multiplier < 0 {
+			be.multiplier = 0
+		}
+	}
+	be.multiplier++
+	be.ejections++
+
+	d := time.Duration(be.multiplier) * o.BaseEjectionTime
+	if d > o.MaxEjectionTime || d <= 0 {
+		d = o.MaxEjectionTime
+	}
+	be.ejectedUntil = now.Add(d)
+	be.consecutiveFailures = 0
+	be.windowStart, be.windowRequests, be.windowFailures = now, 0, 0
+}
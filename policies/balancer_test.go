@@ -0,0 +1,267 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gosentry"
+)
+
+// fakeClock is a manually advanced clock for balancers.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1000, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// serve returns a handler that fails on the backends in down and counts calls per
+// backend.
+func serve(down map[string]bool, calls map[string]int) gosentry.Handler {
+	var mu sync.Mutex
+	return func(ctx context.Context) (any, error) {
+		backend := Endpoint(ctx)
+		mu.Lock()
+		calls[backend]++
+		failing := down[backend]
+		mu.Unlock()
+		if failing {
+			return nil, errors.New(backend + " down")
+		}
+		return backend, nil
+	}
+}
+
+func TestBalancer_RoundRobin(t *testing.T) {
+	calls := map[string]int{}
+	h := LoadBalancer(BalancerOptions{Backends: []string{"a", "b", "c"}})(serve(nil, calls))
+
+	for range 6 {
+		if _, err := h(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls["a"] != 2 || calls["b"] != 2 || calls["c"] != 2 {
+		t.Fatalf("expected an even spread, got %v", calls)
+	}
+}
+
+func TestBalancer_Random(t *testing.T) {
+	calls := map[string]int{}
+	h := LoadBalancer(BalancerOptions{Backends: []string{"a", "b"}, Strategy: BalanceRandom})(serve(nil, calls))
+
+	for range 200 {
+		h(context.Background())
+	}
+	if calls["a"] == 0 || calls["b"] == 0 || calls["a"]+calls["b"] != 200 {
+		t.Fatalf("expected both backends to be used, got %v", calls)
+	}
+}
+
+func TestBalancer_LeastInFlight(t *testing.T) {
+	b := NewBalancer(BalancerOptions{Backends: []string{"a", "b"}, Strategy: BalanceLeastInFlight})
+	p := b.Policy()
+
+	release := make(chan struct{})
+	started := make(chan string)
+	go p(func(ctx context.Context) (any, error) {
+		started <- Endpoint(ctx)
+		<-release
+		return nil, nil
+	})(context.Background())
+	busy := <-started
+
+	for range 3 {
+		res, _ := p(func(ctx context.Context) (any, error) { return Endpoint(ctx), nil })(context.Background())
+		if res == busy {
+			t.Fatalf("expected the idle backend, got the busy %v", res)
+		}
+	}
+	close(release)
+}
+
+func TestBalancer_PowerOfTwoPrefersFastBackend(t *testing.T) {
+	clock := newFakeClock()
+	b := NewBalancer(BalancerOptions{Backends: []string{"fast", "slow"}, Strategy: BalancePowerOfTwo, Now: clock.Now})
+	p := b.Policy()
+
+	calls := map[string]int{}
+	h := p(func(ctx context.Context) (any, error) {
+		backend := Endpoint(ctx)
+		calls[backend]++
+		if backend == "slow" {
+			clock.Advance(100 * time.Millisecond)
+		} else {
+			clock.Advance(time.Millisecond)
+		}
+		return nil, nil
+	})
+	for range 100 {
+		h(context.Background())
+	}
+	if calls["fast"] < 90 {
+		t.Fatalf("expected the fast backend to take most calls, got %v", calls)
+	}
+
+	stats := b.Stats()
+	if stats[0].Latency <= 0 || stats[0].Latency >= stats[1].Latency {
+		t.Fatalf("expected latency EWMAs to reflect the backends, got %v and %v", stats[0].Latency, stats[1].Latency)
+	}
+}
+
+func TestBalancer_PowerOfTwoAvoidsFastFailingBackend(t *testing.T) {
+	clock := newFakeClock()
+	b := NewBalancer(BalancerOptions{Backends: []string{"a", "b", "c"}, Strategy: BalancePowerOfTwo, Now: clock.Now})
+
+	calls := map[string]int{}
+	h := b.Policy()(func(ctx context.Context) (any, error) {
+		backend := Endpoint(ctx)
+		calls[backend]++
+		if backend == "c" {
+			return nil, errors.New("c down")
+		}
+		clock.Advance(10 * time.Millisecond)
+		return nil, nil
+	})
+	for range 300 {
+		h(context.Background())
+	}
+	if calls["c"] > 10 {
+		t.Fatalf("expected the failing backend to get few calls, got %v", calls)
+	}
+	if stats := b.Stats(); stats[2].Latency < time.Second/2 {
+		t.Fatalf("expected failures to raise the latency EWMA, got %v", stats[2].Latency)
+	}
+}
+
+func TestBalancer_EjectsAfterConsecutiveFailures(t *testing.T) {
+	clock := newFakeClock()
+	b := NewBalancer(BalancerOptions{
+		Backends: []string{"a", "b"},
+		Outlier:  &OutlierOptions{ConsecutiveFailures: 2, BaseEjectionTime: 30 * time.Second},
+		Now:      clock.Now,
+	})
+	calls := map[string]int{}
+	down := map[string]bool{"a": true}
+	h := b.Policy()(serve(down, calls))
+
+	for range 6 {
+		h(context.Background())
+	}
+	// a fails twice (calls 1 and 3), then b takes every call.
+	if calls["a"] != 2 || calls["b"] != 4 {
+		t.Fatalf("expected a to be ejected, got %v", calls)
+	}
+	stats := b.Stats()
+	if !stats[0].Ejected || stats[0].Ejections != 1 || !stats[0].EjectedUntil.Equal(clock.Now().Add(30*time.Second)) {
+		t.Fatalf("unexpected stats %+v", stats[0])
+	}
+
+	// After the ejection time, a is back; failing again ejects it for longer.
+	clock.Advance(31 * time.Second)
+	for range 4 {
+		h(context.Background())
+	}
+	stats = b.Stats()
+	if stats[0].Ejections != 2 || !stats[0].EjectedUntil.Equal(clock.Now().Add(60*time.Second)) {
+		t.Fatalf("expected a longer second ejection, got %+v", stats[0])
+	}
+}
+
+func TestBalancer_EjectsOnErrorRate(t *testing.T) {
+	clock := newFakeClock()
+	b := NewBalancer(BalancerOptions{
+		Backends: []string{"a", "b"},
+		Outlier:  &OutlierOptions{ConsecutiveFailures: -1, ErrorRate: 0.5, MinRequests: 4},
+		Now:      clock.Now,
+	})
+	fail := false
+	h := b.Policy()(func(ctx context.Context) (any, error) {
+		if Endpoint(ctx) == "a" {
+			fail = !fail
+			if fail {
+				return nil, errors.New("flaky")
+			}
+		}
+		return nil, nil
+	})
+
+	for range 8 {
+		h(context.Background())
+	}
+	if stats := b.Stats(); !stats[0].Ejected || stats[1].Ejected {
+		t.Fatalf("expected only a to be ejected, got %+v", stats)
+	}
+}
+
+func TestBalancer_MaxEjectionPercent(t *testing.T) {
+	b := NewBalancer(BalancerOptions{
+		Backends: []string{"a", "b"},
+		Outlier:  &OutlierOptions{ConsecutiveFailures: 1},
+	})
+	h := b.Policy()(serve(map[string]bool{"a": true, "b": true}, map[string]int{}))
+
+	for range 4 {
+		if _, err := h(context.Background()); errors.Is(err, ErrNoHealthyBackend) {
+			t.Fatal("expected at least half of the backends to stay in")
+		}
+	}
+	ejected := 0
+	for _, s := range b.Stats() {
+		if s.Ejected {
+			ejected++
+		}
+	}
+	if ejected != 1 {
+		t.Fatalf("expected one ejected backend, got %d", ejected)
+	}
+}
+
+func TestBalancer_NoHealthyBackend(t *testing.T) {
+	rec := &eventRecorder{}
+	ctx := gosentry.WithObserver(context.Background(), rec)
+	b := NewBalancer(BalancerOptions{
+		Backends: []string{"a"},
+		Outlier:  &OutlierOptions{ConsecutiveFailures: 1, MaxEjectionPercent: 100},
+	})
+	h := b.Policy()(serve(map[string]bool{"a": true}, map[string]int{}))
+
+	h(ctx)
+	if _, err := h(ctx); !errors.Is(err, ErrNoHealthyBackend) {
+		t.Fatalf("expected ErrNoHealthyBackend, got %v", err)
+	}
+	changes, rejections := rec.ofKind(gosentry.EventStateChange), rec.ofKind(gosentry.EventRejected)
+	if len(changes) != 1 || changes[0].Endpoint != "a" || changes[0].To != "ejected" {
+		t.Fatalf("expected an ejection event, got %+v", changes)
+	}
+	if len(rejections) != 1 || rejections[0].Reason != "no_healthy_backend" {
+		t.Fatalf("expected a rejection, got %+v", rejections)
+	}
+}
+
+func TestBalancer_Describe(t *testing.T) {
+	d, ok := gosentry.DescriptorOf(LoadBalancer(BalancerOptions{Backends: []string{"a", "b"}, Strategy: BalancePowerOfTwo}))
+	if !ok || d.Kind != gosentry.KindLoadBalancer {
+		t.Fatalf("unexpected descriptor %+v", d)
+	}
+	if v, _ := d.Option("strategy"); v != "p2c" {
+		t.Errorf("expected the strategy, got %v", v)
+	}
+}
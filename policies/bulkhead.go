@@ -0,0 +1,163 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"gosentry"
+)
+
+var (
+	// ErrBulkheadFull is returned when all slots are taken and no more calls may wait.
+	ErrBulkheadFull = errors.New("bulkhead is full")
+)
+
+type BulkheadOptions struct {
+	// Name identifies the policy in events. Defaults to "bulkhead".
+	Name string
+
+	// MaxConcurrent is the number of calls allowed to run at once.
+	MaxConcurrent int
+
+	// MaxWaiting is the number of calls allowed to wait for a slot. Further calls are
+	// rejected at once with ErrBulkheadFull, shedding load. Zero means no call waits.
+	MaxWaiting int
+
+	// MaxWait bounds how long a call waits for a slot before it is rejected. Zero
+	// means it waits until its context is done.
+	MaxWait time.Duration
+}
+
+func DefaultBulkheadOptions() BulkheadOptions {
+	return BulkheadOptions{
+		Name:          "bulkhead",
+		MaxConcurrent: 10,
+	}
+}
+
+// Bulkhead returns a policy that limits how many calls run at once.
+func Bulkhead(options BulkheadOptions) gosentry.Policy {
+	return NewBulkheadPool(options).Policy()
+}
+
+// BulkheadPool holds the slots of a bulkhead. Every policy returned by Policy
+// shares the same slots.
+type BulkheadPool struct {
+	opts    BulkheadOptions
+	slots   chan struct{}
+	waiting atomic.Int64
+}
+
+// NewBulkheadPool returns a BulkheadPool without validating options; see
+// NewBulkhead.
+func NewBulkheadPool(options BulkheadOptions) *BulkheadPool {
+	opts := applyBulkheadDefaults(options)
+	return &BulkheadPool{
+		opts:  opts,
+		slots: make(chan struct{}, opts.MaxConcurrent),
+	}
+}
+
+// Policy returns a policy that runs calls in the bulkhead's slots.
+func (b *BulkheadPool) Policy() gosentry.Policy {
+	policy := func(next gosentry.Handler) gosentry.Handler {
+		return func(ctx context.Context) (any, error) {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			obs := observe(ctx, gosentry.KindBulkhead, b.opts.Name)
+			if err := b.acquire(ctx); err != nil {
+				if errors.Is(err, ErrBulkheadFull) {
+					obs.reject("bulkhead_full", err)
+				}
+				return nil, err
+			}
+			defer b.release()
+
+			result, err := next(ctx)
+			obs.finish(err)
+			return result, err
+		}
+	}
+	return gosentry.WithDescriptor(policy, gosentry.Descriptor{
+		Kind: gosentry.KindBulkhead,
+		Name: b.opts.Name,
+		Options: []gosentry.Attribute{
+			gosentry.Attr("max_concurrent", b.opts.MaxConcurrent),
+			gosentry.Attr("max_waiting", b.opts.MaxWaiting),
+			gosentry.Attr("max_wait", b.opts.MaxWait),
+		},
+	})
+}
+
+func (b *BulkheadPool) acquire(ctx context.Context) error {
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if b.waiting.Add(1) > int64(b.opts.MaxWaiting) {
+		b.waiting.Add(-1)
+		return ErrBulkheadFull
+	}
+	defer b.waiting.Add(-1)
+
+	var timeout <-chan time.Time
+	if b.opts.MaxWait > 0 {
+		timer := time.NewTimer(b.opts.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+		return nil
+	case <-timeout:
+		return ErrBulkheadFull
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *BulkheadPool) release() {
+	<-b.slots
+}
+
+func (b *BulkheadPool) Name() string {
+	return b.opts.Name
+}
+
+// InFlight returns the number of calls running.
+func (b *BulkheadPool) InFlight() int {
+	return len(b.slots)
+}
+
+// Capacity returns MaxConcurrent.
+func (b *BulkheadPool) Capacity() int {
+	return b.opts.MaxConcurrent
+}
+
+// Waiting returns the number of calls waiting for a slot.
+func (b *BulkheadPool) Waiting() int {
+	return int(b.waiting.Load())
+}
+
+func applyBulkheadDefaults(options BulkheadOptions) BulkheadOptions {
+	defaults := DefaultBulkheadOptions()
+
+	if options.Name == "" {
+		options.Name = defaults.Name
+	}
+	if options.MaxConcurrent <= 0 {
+		options.MaxConcurrent = defaults.MaxConcurrent
+	}
+	if options.MaxWaiting < 0 {
+		options.MaxWaiting = 0
+	}
+
+	return options
+}
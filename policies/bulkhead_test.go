@@ -0,0 +1,111 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gosentry"
+)
+
+// occupy starts n calls through p that block until release is closed, and waits
+// until they all run.
+func occupy(t *testing.T, p gosentry.Policy, n int, release chan struct{}) *sync.WaitGroup {
+	t.Helper()
+	started := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p(func(ctx context.Context) (any, error) {
+				started <- struct{}{}
+				<-release
+				return nil, nil
+			})(context.Background())
+		}()
+	}
+	for i := 0; i < n; i++ {
+		<-started
+	}
+	return &wg
+}
+
+func TestBulkhead_ShedsLoadWhenFull(t *testing.T) {
+	b := NewBulkheadPool(BulkheadOptions{MaxConcurrent: 2})
+	p := b.Policy()
+	release := make(chan struct{})
+	wg := occupy(t, p, 2, release)
+
+	if b.InFlight() != 2 || b.Capacity() != 2 {
+		t.Fatalf("expected 2/2 in flight, got %d/%d", b.InFlight(), b.Capacity())
+	}
+	_, err := p(func(ctx context.Context) (any, error) { return "ok", nil })(context.Background())
+	if !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("expected ErrBulkheadFull, got %v", err)
+	}
+
+	close(release)
+	wg.Wait()
+	if res, err := p(func(ctx context.Context) (any, error) { return "ok", nil })(context.Background()); err != nil || res != "ok" {
+		t.Fatalf("expected a free slot after release, got %v %v", res, err)
+	}
+}
+
+func TestBulkhead_QueuedCallWaitsForSlot(t *testing.T) {
+	b := NewBulkheadPool(BulkheadOptions{MaxConcurrent: 1, MaxWaiting: 1})
+	p := b.Policy()
+	release := make(chan struct{})
+	wg := occupy(t, p, 1, release)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p(func(ctx context.Context) (any, error) { return nil, nil })(context.Background())
+		done <- err
+	}()
+	for b.Waiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The queue is full too.
+	if _, err := p(func(ctx context.Context) (any, error) { return nil, nil })(context.Background()); !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("expected ErrBulkheadFull with a full queue, got %v", err)
+	}
+
+	close(release)
+	wg.Wait()
+	if err := <-done; err != nil {
+		t.Fatalf("expected the queued call to run, got %v", err)
+	}
+}
+
+func TestBulkhead_MaxWait(t *testing.T) {
+	b := NewBulkheadPool(BulkheadOptions{MaxConcurrent: 1, MaxWaiting: 1, MaxWait: 10 * time.Millisecond})
+	p := b.Policy()
+	release := make(chan struct{})
+	wg := occupy(t, p, 1, release)
+	defer func() { close(release); wg.Wait() }()
+
+	_, err := p(func(ctx context.Context) (any, error) { return nil, nil })(context.Background())
+	if !errors.Is(err, ErrBulkheadFull) {
+		t.Fatalf("expected ErrBulkheadFull after MaxWait, got %v", err)
+	}
+}
+
+func TestBulkhead_EmitsRejection(t *testing.T) {
+	rec := &eventRecorder{}
+	ctx := gosentry.WithObserver(context.Background(), rec)
+	b := NewBulkheadPool(BulkheadOptions{Name: "uploads", MaxConcurrent: 1})
+	p := b.Policy()
+	release := make(chan struct{})
+	wg := occupy(t, p, 1, release)
+	defer func() { close(release); wg.Wait() }()
+
+	p(func(ctx context.Context) (any, error) { return nil, nil })(ctx)
+	rejections := rec.ofKind(gosentry.EventRejected)
+	if len(rejections) != 1 || rejections[0].Reason != "bulkhead_full" || rejections[0].Policy != "uploads" {
+		t.Fatalf("expected one bulkhead_full rejection, got %+v", rejections)
+	}
+}
@@ -0,0 +1,322 @@
+package policies
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"gosentry"
+)
+
+// CacheEntry is a result stored by the cache policy.
+type CacheEntry struct {
+	Value any
+
+	// Err is set for negatively cached errors.
+	Err error
+
+	// Expires is when the entry stops being fresh.
+	Expires time.Time
+}
+
+// CacheStore holds the entries of a cache policy. Implementations must be safe for
+// concurrent use. A store may drop entries at any time, e.g. to bound its size;
+// the policy decides whether an entry it gets back is still usable.
+type CacheStore interface {
+	Get(key string) (CacheEntry, bool)
+	Set(key string, entry CacheEntry)
+	Delete(key string)
+}
+
+// LRUStore is an in-memory CacheStore that evicts the least recently used entry
+// once it holds Capacity entries.
+type LRUStore struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry CacheEntry
+}
+
+// NewLRUStore returns an LRUStore holding up to capacity entries. A capacity below
+// 1 is treated as 1.
+func NewLRUStore(capacity int) *LRUStore {
+	return &LRUStore{
+		capacity: max(capacity, 1),
+		order:    list.New(),
+		entries:  map[string]*list.Element{},
+	}
+}
+
+func (s *LRUStore) Get(key string) (CacheEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[key]
+	if !ok {
+		return CacheEntry{}, false
+	}
+	s.order.MoveToFront(el)
+	return el.Value.(*lruItem).entry, true
+}
+
+func (s *LRUStore) Set(key string, entry CacheEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		el.Value.(*lruItem).entry = entry
+		s.order.MoveToFront(el)
+		return
+	}
+	s.entries[key] = s.order.PushFront(&lruItem{key: key, entry: entry})
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*lruItem).key)
+	}
+}
+
+func (s *LRUStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		s.order.Remove(el)
+		delete(s.entries, key)
+	}
+}
+
+// Len returns the number of entries held.
+func (s *LRUStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+type CacheOptions struct {
+	// Name identifies the policy in events. Defaults to "cache".
+	Name string
+
+	// Store holds the entries. Defaults to an LRUStore of 1024 entries.
+	Store CacheStore
+
+	// Key returns the key of a call. Calls with an empty key bypass the cache.
+	// Defaults to KeyFrom.
+	Key func(ctx context.Context) string
+
+	// TTL is how long a successful result stays fresh. Defaults to 1 minute.
+	TTL time.Duration
+
+	// StaleWhileRevalidate is how long after expiring a result is still served
+	// while it is refreshed in the background. Zero disables it.
+	StaleWhileRevalidate time.Duration
+
+	// StaleIfError is how long after expiring a result is served in place of an
+	// error from the next handler that CacheError does not cache. Zero disables it.
+	StaleIfError time.Duration
+
+	// CacheError reports whether err should be cached like a result. If nil,
+	// errors are never cached.
+	CacheError func(err error) bool
+
+	// NegativeTTL is how long a cached error stays fresh. Defaults to TTL.
+	NegativeTTL time.Duration
+
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+func DefaultCacheOptions() CacheOptions {
+	return CacheOptions{
+		Name: "cache",
+		Key:  KeyFrom,
+		TTL:  time.Minute,
+		Now:  time.Now,
+	}
+}
+
+func applyCacheDefaults(options CacheOptions) CacheOptions {
+	defaults := DefaultCacheOptions()
+
+	if options.Name == "" {
+		options.Name = defaults.Name
+	}
+	if options.Store == nil {
+		options.Store = NewLRUStore(1024)
+	}
+	if options.Key == nil {
+		options.Key = defaults.Key
+	}
+	if options.TTL <= 0 {
+		options.TTL = defaults.TTL
+	}
+	if options.NegativeTTL <= 0 {
+		options.NegativeTTL = options.TTL
+	}
+	if options.Now == nil {
+		options.Now = defaults.Now
+	}
+
+	return options
+}
+
+// CacheStats counts the lookups of a ResultCache.
+type CacheStats struct {
+	// Hits is the number of calls served a fresh entry, including cached errors.
+	Hits uint64
+
+	// Misses is the number of calls that ran the next handler.
+	Misses uint64
+
+	// Stale is the number of calls served an expired result, either while it was
+	// revalidated or in place of an error.
+	Stale uint64
+
+	// Revalidations is the number of background refreshes started.
+	Revalidations uint64
+}
+
+// Cache returns a policy that serves results from a cache and runs the next
+// handler only on a miss.
+func Cache(options CacheOptions) gosentry.Policy {
+	return NewResultCache(options).Policy()
+}
+
+// ResultCache caches the results of the next handler by key. Cached results are
+// shared between callers, so they must be safe to use concurrently. Misses for the
+// same key are not deduplicated; put a Coalesce policy inside the cache for that.
+type ResultCache struct {
+	opts CacheOptions
+
+	mu         sync.Mutex
+	refreshing map[string]bool
+	stats      CacheStats
+}
+
+// NewResultCache returns a ResultCache without validating options; see NewCache.
+func NewResultCache(options CacheOptions) *ResultCache {
+	return &ResultCache{
+		opts:       applyCacheDefaults(options),
+		refreshing: map[string]bool{},
+	}
+}
+
+func (c *ResultCache) Name() string {
+	return c.opts.Name
+}
+
+func (c *ResultCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// Invalidate removes the entry for key.
+func (c *ResultCache) Invalidate(key string) {
+	c.opts.Store.Delete(key)
+}
+
+// Policy returns a policy that caches results. Every policy returned by Policy
+// shares the same store and statistics.
+func (c *ResultCache) Policy() gosentry.Policy {
+	policy := func(next gosentry.Handler) gosentry.Handler {
+		return func(ctx context.Context) (any, error) {
+			key := c.opts.Key(ctx)
+			if key == "" {
+				return next(ctx)
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			obs := observe(ctx, gosentry.KindCache, c.opts.Name)
+			entry, ok := c.opts.Store.Get(key)
+			var expired time.Duration
+			if ok {
+				expired = c.opts.Now().Sub(entry.Expires)
+				if expired < 0 {
+					c.count(func(s *CacheStats) { s.Hits++ })
+					obs.finishWith(gosentry.Event{Reason: "hit"}, entry.Err)
+					return entry.Value, entry.Err
+				}
+				if entry.Err == nil && expired < c.opts.StaleWhileRevalidate {
+					c.count(func(s *CacheStats) { s.Stale++ })
+					c.revalidate(ctx, key, next)
+					obs.finishWith(gosentry.Event{Reason: "stale"}, nil)
+					return entry.Value, nil
+				}
+			}
+
+			c.count(func(s *CacheStats) { s.Misses++ })
+			result, err := next(ctx)
+			if c.store(key, result, err) || ctx.Err() != nil {
+				obs.finishWith(gosentry.Event{Reason: "miss"}, err)
+				return result, err
+			}
+			if ok && entry.Err == nil && expired < c.opts.StaleIfError {
+				c.count(func(s *CacheStats) { s.Stale++ })
+				obs.finishWith(gosentry.Event{Reason: "stale"}, nil)
+				return entry.Value, nil
+			}
+			obs.finishWith(gosentry.Event{Reason: "miss"}, err)
+			return result, err
+		}
+	}
+	return gosentry.WithDescriptor(policy, gosentry.Descriptor{
+		Kind: gosentry.KindCache,
+		Name: c.opts.Name,
+		Options: []gosentry.Attribute{
+			gosentry.Attr("ttl", c.opts.TTL),
+			gosentry.Attr("stale_while_revalidate", c.opts.StaleWhileRevalidate),
+			gosentry.Attr("stale_if_error", c.opts.StaleIfError),
+			gosentry.Attr("negative_caching", c.opts.CacheError != nil),
+		},
+	})
+}
+
+// store caches the outcome of a call and reports whether it did.
+func (c *ResultCache) store(key string, result any, err error) bool {
+	switch {
+	case err == nil:
+		c.opts.Store.Set(key, CacheEntry{Value: result, Expires: c.opts.Now().Add(c.opts.TTL)})
+	case c.opts.CacheError != nil && c.opts.CacheError(err):
+		c.opts.Store.Set(key, CacheEntry{Err: err, Expires: c.opts.Now().Add(c.opts.NegativeTTL)})
+	default:
+		return false
+	}
+	return true
+}
+
+// revalidate refreshes the entry for key in the background unless a refresh is
+// already running. The refresh is detached from the cancellation of ctx; a failed
+// refresh that is not cached leaves the stale entry in place.
+func (c *ResultCache) revalidate(ctx context.Context, key string, next gosentry.Handler) {
+	c.mu.Lock()
+	if c.refreshing[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing[key] = true
+	c.stats.Revalidations++
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.refreshing, key)
+			c.mu.Unlock()
+		}()
+		result, err := next(context.WithoutCancel(ctx))
+		c.store(key, result, err)
+	}()
+}
+
+func (c *ResultCache) count(update func(*CacheStats)) {
+	c.mu.Lock()
+	update(&c.stats)
+	c.mu.Unlock()
+}
@@ -0,0 +1,164 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gosentry"
+)
+
+// counting returns a handler that returns the number of calls so far, or err once
+// fail is set.
+func counting(calls *atomic.Int32, fail *atomic.Pointer[error]) gosentry.Handler {
+	return func(ctx context.Context) (any, error) {
+		n := calls.Add(1)
+		if err := fail.Load(); err != nil {
+			return nil, *err
+		}
+		return int(n), nil
+	}
+}
+
+func TestLRUStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	s := NewLRUStore(2)
+	s.Set("a", CacheEntry{Value: 1})
+	s.Set("b", CacheEntry{Value: 2})
+	s.Get("a")
+	s.Set("c", CacheEntry{Value: 3})
+
+	if _, ok := s.Get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	if e, ok := s.Get("a"); !ok || e.Value != 1 {
+		t.Fatalf("expected a to stay, got %v %v", e, ok)
+	}
+	s.Delete("a")
+	if s.Len() != 1 {
+		t.Fatalf("expected one entry, got %d", s.Len())
+	}
+}
+
+func TestCache_HitsUntilExpired(t *testing.T) {
+	clock := newFakeClock()
+	c := NewResultCache(CacheOptions{TTL: time.Minute, Now: clock.Now})
+	var calls atomic.Int32
+	var fail atomic.Pointer[error]
+	h := c.Policy()(counting(&calls, &fail))
+	ctx := WithKey(context.Background(), "k")
+
+	h(ctx)
+	if res, _ := h(ctx); res != 1 {
+		t.Fatalf("expected the cached result, got %v", res)
+	}
+	clock.Advance(time.Minute)
+	if res, _ := h(ctx); res != 2 {
+		t.Fatalf("expected a fresh result once expired, got %v", res)
+	}
+	if res, _ := h(context.Background()); res != 3 {
+		t.Fatalf("expected calls without a key to bypass the cache, got %v", res)
+	}
+	if st := c.Stats(); st.Hits != 1 || st.Misses != 2 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestCache_NegativeCaching(t *testing.T) {
+	clock := newFakeClock()
+	errNotFound := errors.New("not found")
+	errDown := errors.New("down")
+	c := NewResultCache(CacheOptions{
+		TTL:         time.Minute,
+		NegativeTTL: time.Second,
+		CacheError:  func(err error) bool { return err == errNotFound },
+		Now:         clock.Now,
+	})
+	var calls atomic.Int32
+	var fail atomic.Pointer[error]
+	h := c.Policy()(counting(&calls, &fail))
+	ctx := WithKey(context.Background(), "k")
+
+	fail.Store(&errNotFound)
+	h(ctx)
+	if _, err := h(ctx); err != errNotFound || calls.Load() != 1 {
+		t.Fatalf("expected the cached error, got %v after %d calls", err, calls.Load())
+	}
+	clock.Advance(time.Second)
+	fail.Store(&errDown)
+	h(ctx)
+	h(ctx)
+	if calls.Load() != 3 {
+		t.Fatalf("expected uncached errors to run every call, got %d calls", calls.Load())
+	}
+}
+
+func TestCache_StaleWhileRevalidate(t *testing.T) {
+	clock := newFakeClock()
+	c := NewResultCache(CacheOptions{TTL: time.Minute, StaleWhileRevalidate: time.Minute, Now: clock.Now})
+	g := newGate("fresh", nil)
+	ctx := WithKey(context.Background(), "k")
+
+	c.Policy()(func(ctx context.Context) (any, error) { return "old", nil })(ctx)
+	clock.Advance(90 * time.Second)
+
+	h := c.Policy()(g.handle)
+	for range 3 {
+		if res, err := h(ctx); res != "old" || err != nil {
+			t.Fatalf("expected the stale result, got %v, %v", res, err)
+		}
+	}
+	<-g.started
+	close(g.release)
+	waitFor(t, func() bool {
+		res, _ := h(ctx)
+		return res == "fresh"
+	})
+	if g.calls.Load() != 1 {
+		t.Fatalf("expected one background refresh, got %d", g.calls.Load())
+	}
+	if st := c.Stats(); st.Stale < 3 || st.Revalidations != 1 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestCache_StaleIfError(t *testing.T) {
+	clock := newFakeClock()
+	c := NewResultCache(CacheOptions{TTL: time.Minute, StaleIfError: time.Minute, Now: clock.Now})
+	var calls atomic.Int32
+	var fail atomic.Pointer[error]
+	h := c.Policy()(counting(&calls, &fail))
+	ctx := WithKey(context.Background(), "k")
+
+	h(ctx)
+	errDown := errors.New("down")
+	fail.Store(&errDown)
+	clock.Advance(90 * time.Second)
+	if res, err := h(ctx); res != 1 || err != nil {
+		t.Fatalf("expected the stale result in place of the error, got %v, %v", res, err)
+	}
+	clock.Advance(time.Minute)
+	if _, err := h(ctx); err != errDown {
+		t.Fatalf("expected the error once too stale, got %v", err)
+	}
+	if st := c.Stats(); st.Stale != 1 || st.Misses != 3 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestCache_EmitsLookupResult(t *testing.T) {
+	rec := &eventRecorder{}
+	ctx := gosentry.WithObserver(WithKey(context.Background(), "k"), rec)
+	h := Cache(CacheOptions{})(func(ctx context.Context) (any, error) { return "v", nil })
+
+	h(ctx)
+	h(ctx)
+	successes := rec.ofKind(gosentry.EventSuccess)
+	if len(successes) != 2 || successes[0].Reason != "miss" || successes[1].Reason != "hit" {
+		t.Fatalf("expected a miss then a hit, got %+v", successes)
+	}
+	if d, ok := gosentry.DescriptorOf(Cache(CacheOptions{})); !ok || d.Kind != gosentry.KindCache {
+		t.Fatalf("unexpected descriptor %+v", d)
+	}
+}
@@ -3,6 +3,7 @@ package policies
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
@@ -26,6 +27,9 @@ const (
 )
 
 type CircuitBreakerOptions struct {
+	// Name identifies the policy in events. Defaults to "circuit_breaker".
+	Name string
+
 	// FailureThreshold is the number of consecutive failures required to open the circuit.
 	FailureThreshold int
 
@@ -47,6 +51,7 @@ type CircuitBreakerOptions struct {
 
 func DefaultCircuitBreakerOptions() CircuitBreakerOptions {
 	return CircuitBreakerOptions{
+		Name:             "circuit_breaker",
 		FailureThreshold: 5,
 		SuccessThreshold: 1,
 		OpenTimeout:      30 * time.Second,
@@ -54,54 +59,217 @@ func DefaultCircuitBreakerOptions() CircuitBreakerOptions {
 }
 
 func CircuitBreaker(options CircuitBreakerOptions) gosentry.Policy {
-	opts := applyCircuitBreakerDefaults(options)
-	cb := newCircuitBreaker(opts)
+	return NewBreaker(options).Policy()
+}
+
+// Breaker is a circuit breaker whose state can be inspected while it guards calls.
+// Every policy returned by Policy shares the breaker's state.
+type Breaker struct {
+	opts CircuitBreakerOptions
+
+	mu sync.Mutex
 
-	return func(next gosentry.Handler) gosentry.Handler {
+	state        CircuitBreakerState
+	openedAt     time.Time
+	failures     int
+	halfSuccess  int
+	halfInFlight bool
+
+	// forced pins the breaker to CircuitOpen or CircuitClosed until Reset; empty when
+	// the breaker runs normally.
+	forced CircuitBreakerState
+}
+
+// BreakerStats is a point-in-time view of a Breaker.
+type BreakerStats struct {
+	State CircuitBreakerState
+
+	// ConsecutiveFailures counts failures since the last success while closed.
+	ConsecutiveFailures int
+
+	// OpenedAt is when the circuit last opened; zero if it never did.
+	OpenedAt time.Time
+
+	// Forced is true while the state was pinned by ForceOpen or ForceClose.
+	Forced bool
+}
+
+func NewBreaker(options CircuitBreakerOptions) *Breaker {
+	return newCircuitBreaker(applyCircuitBreakerDefaults(options))
+}
+
+// Policy returns a policy that guards calls with the breaker.
+func (c *Breaker) Policy() gosentry.Policy {
+	policy := func(next gosentry.Handler) gosentry.Handler {
 		return func(ctx context.Context) (any, error) {
 			if ctx.Err() != nil {
 				return nil, ctx.Err()
 			}
 
-			if err := cb.beforeCall(ctx); err != nil {
+			obs := observe(ctx, gosentry.KindCircuitBreaker, c.opts.Name)
+			if err := c.beforeCall(obs); err != nil {
 				return nil, err
 			}
 
 			result, err := next(ctx)
-			cb.afterCall(err)
+			c.afterCall(obs, err)
+			obs.finish(err)
 			return result, err
 		}
 	}
+	// Thresholds can be changed at runtime, so they are read when described.
+	return gosentry.WithDescriber(policy, c.descriptor)
 }
 
-type circuitBreaker struct {
-	opts CircuitBreakerOptions
+func (c *Breaker) descriptor() gosentry.Descriptor {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return gosentry.Descriptor{
+		Kind: gosentry.KindCircuitBreaker,
+		Name: c.opts.Name,
+		Options: []gosentry.Attribute{
+			gosentry.Attr("failure_threshold", c.opts.FailureThreshold),
+			gosentry.Attr("success_threshold", c.opts.SuccessThreshold),
+			gosentry.Attr("open_timeout", c.opts.OpenTimeout),
+		},
+	}
+}
 
-	mu sync.Mutex
+func (c *Breaker) Name() string {
+	return c.opts.Name
+}
 
-	state        CircuitBreakerState
-	openedAt     time.Time
-	failures     int
-	halfSuccess  int
-	halfInFlight bool
+func (c *Breaker) State() CircuitBreakerState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+func (c *Breaker) Stats() BreakerStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return BreakerStats{
+		State:               c.state,
+		ConsecutiveFailures: c.failures,
+		OpenedAt:            c.openedAt,
+		Forced:              c.forced != "",
+	}
 }
 
-func newCircuitBreaker(opts CircuitBreakerOptions) *circuitBreaker {
-	return &circuitBreaker{
+// SetThresholds changes FailureThreshold, SuccessThreshold and OpenTimeout while
+// keeping the breaker's state and counters, so an open circuit stays open. A new
+// OpenTimeout applies to the current open period too.
+func (c *Breaker) SetThresholds(failureThreshold, successThreshold int, openTimeout time.Duration) error {
+	if failureThreshold < 1 || successThreshold < 1 || openTimeout <= 0 {
+		return fmt.Errorf("circuit breaker: thresholds must be at least 1 and open timeout positive, got %d, %d and %v",
+			failureThreshold, successThreshold, openTimeout)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.opts.FailureThreshold = failureThreshold
+	c.opts.SuccessThreshold = successThreshold
+	c.opts.OpenTimeout = openTimeout
+	return nil
+}
+
+// Retune applies the thresholds and OpenTimeout of options like SetThresholds, after
+// Validate and with the defaults for zero fields. The other fields of options are
+// ignored.
+func (c *Breaker) Retune(options CircuitBreakerOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	opts := applyCircuitBreakerDefaults(options)
+	return c.SetThresholds(opts.FailureThreshold, opts.SuccessThreshold, opts.OpenTimeout)
+}
+
+// ForceOpen pins the breaker open: every call is rejected with ErrCircuitOpen until
+// Reset is called.
+func (c *Breaker) ForceOpen() {
+	c.force(func() {
+		c.forced = CircuitOpen
+		c.openLocked()
+	})
+}
+
+// ForceClose pins the breaker closed: every call is admitted and failures are not
+// counted until Reset is called.
+func (c *Breaker) ForceClose() {
+	c.force(func() {
+		c.forced = CircuitClosed
+		c.failures = 0
+		c.halfSuccess = 0
+		c.halfInFlight = false
+		c.transitionLocked(CircuitClosed)
+	})
+}
+
+// Reset releases a forced state and returns the breaker to closed with its counters cleared.
+func (c *Breaker) Reset() {
+	c.force(func() {
+		c.forced = ""
+		c.failures = 0
+		c.halfSuccess = 0
+		c.halfInFlight = false
+		c.transitionLocked(CircuitClosed)
+	})
+}
+
+// force applies an operator-initiated change and reports any transition to the
+// global observer, since there is no call context to report it on.
+func (c *Breaker) force(change func()) {
+	c.mu.Lock()
+	from := c.state
+	change()
+	to := c.state
+	c.mu.Unlock()
+
+	if from != to {
+		obs := observe(context.Background(), gosentry.KindCircuitBreaker, c.opts.Name)
+		obs.emit(gosentry.Event{Kind: gosentry.EventStateChange, From: string(from), To: string(to), Reason: "forced"})
+	}
+}
+
+func newCircuitBreaker(opts CircuitBreakerOptions) *Breaker {
+	return &Breaker{
 		opts:  opts,
 		state: CircuitClosed,
 	}
 }
 
-func (c *circuitBreaker) beforeCall(ctx context.Context) error {
-	if ctx.Err() != nil {
-		return ctx.Err()
+func (c *Breaker) beforeCall(obs observation) error {
+	if obs.ctx.Err() != nil {
+		return obs.ctx.Err()
 	}
 
-	now := c.opts.Now()
-
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	from := c.state
+	err := c.beforeCallLocked()
+	to := c.state
+	c.mu.Unlock()
+
+	if from != to {
+		obs.emit(gosentry.Event{Kind: gosentry.EventStateChange, From: string(from), To: string(to)})
+	}
+	switch err {
+	case ErrCircuitOpen:
+		obs.reject("circuit_open", err)
+	case ErrCircuitHalfOpenBusy:
+		obs.reject("half_open_busy", err)
+	}
+	return err
+}
+
+func (c *Breaker) beforeCallLocked() error {
+	switch c.forced {
+	case CircuitOpen:
+		return ErrCircuitOpen
+	case CircuitClosed:
+		return nil
+	}
+
+	now := c.opts.Now()
 
 	switch c.state {
 	case CircuitClosed:
@@ -129,9 +297,22 @@ func (c *circuitBreaker) beforeCall(ctx context.Context) error {
 	}
 }
 
-func (c *circuitBreaker) afterCall(err error) {
+func (c *Breaker) afterCall(obs observation, err error) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	from := c.state
+	c.afterCallLocked(err)
+	to := c.state
+	c.mu.Unlock()
+
+	if from != to {
+		obs.emit(gosentry.Event{Kind: gosentry.EventStateChange, From: string(from), To: string(to), Err: err})
+	}
+}
+
+func (c *Breaker) afterCallLocked(err error) {
+	if c.forced != "" {
+		return
+	}
 
 	if c.state == CircuitHalfOpen {
 		c.halfInFlight = false
@@ -174,7 +355,7 @@ func (c *circuitBreaker) afterCall(err error) {
 	}
 }
 
-func (c *circuitBreaker) openLocked() {
+func (c *Breaker) openLocked() {
 	c.failures = 0
 	c.halfSuccess = 0
 	c.halfInFlight = false
@@ -182,7 +363,7 @@ func (c *circuitBreaker) openLocked() {
 	c.transitionLocked(CircuitOpen)
 }
 
-func (c *circuitBreaker) transitionLocked(to CircuitBreakerState) {
+func (c *Breaker) transitionLocked(to CircuitBreakerState) {
 	if c.state == to {
 		return
 	}
@@ -196,6 +377,9 @@ func (c *circuitBreaker) transitionLocked(to CircuitBreakerState) {
 func applyCircuitBreakerDefaults(options CircuitBreakerOptions) CircuitBreakerOptions {
 	defaults := DefaultCircuitBreakerOptions()
 
+	if options.Name == "" {
+		options.Name = defaults.Name
+	}
 	if options.FailureThreshold == 0 {
 		options.FailureThreshold = defaults.FailureThreshold
 	}
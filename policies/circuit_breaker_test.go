@@ -6,6 +6,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"gosentry"
 )
 
 func TestCircuitBreaker_OpensAfterFailureThresholdAndRejects(t *testing.T) {
@@ -290,3 +292,118 @@ func TestCircuitBreaker_ContextErrorTakesPrecedence(t *testing.T) {
 		t.Fatalf("expected handler not called, got %d", callCount)
 	}
 }
+
+func TestBreaker_ExposesStateAndStats(t *testing.T) {
+	b := NewBreaker(CircuitBreakerOptions{Name: "payments", FailureThreshold: 2, OpenTimeout: time.Minute})
+	wrapped := b.Policy()(func(ctx context.Context) (any, error) {
+		return nil, errors.New("boom")
+	})
+
+	wrapped(context.Background())
+	if stats := b.Stats(); stats.State != CircuitClosed || stats.ConsecutiveFailures != 1 {
+		t.Fatalf("expected closed with 1 failure, got %+v", stats)
+	}
+
+	wrapped(context.Background())
+	if b.State() != CircuitOpen {
+		t.Fatalf("expected open, got %s", b.State())
+	}
+	if b.Stats().OpenedAt.IsZero() {
+		t.Fatal("expected OpenedAt to be set")
+	}
+	if b.Name() != "payments" {
+		t.Fatalf("expected name payments, got %q", b.Name())
+	}
+}
+
+func TestBreaker_ForceOpenCloseAndReset(t *testing.T) {
+	var transitions []string
+	b := NewBreaker(CircuitBreakerOptions{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Nanosecond,
+		OnStateChange: func(from, to CircuitBreakerState) {
+			transitions = append(transitions, string(from)+"->"+string(to))
+		},
+	})
+
+	fail := true
+	calls := 0
+	wrapped := b.Policy()(func(ctx context.Context) (any, error) {
+		calls++
+		if fail {
+			return nil, errors.New("boom")
+		}
+		return "ok", nil
+	})
+
+	b.ForceOpen()
+	time.Sleep(time.Millisecond)
+	if _, err := wrapped(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected forced open to reject even after OpenTimeout, got %v", err)
+	}
+	if calls != 0 || !b.Stats().Forced {
+		t.Fatalf("expected no calls while forced open, got %d", calls)
+	}
+
+	b.ForceClose()
+	wrapped(context.Background())
+	wrapped(context.Background())
+	if b.State() != CircuitClosed || calls != 2 {
+		t.Fatalf("expected forced closed to admit and ignore failures, state=%s calls=%d", b.State(), calls)
+	}
+
+	b.Reset()
+	wrapped(context.Background())
+	if b.State() != CircuitOpen || b.Stats().Forced {
+		t.Fatalf("expected normal operation after reset, got %+v", b.Stats())
+	}
+
+	want := []string{"closed->open", "open->closed", "closed->open"}
+	if len(transitions) != len(want) {
+		t.Fatalf("expected transitions %v, got %v", want, transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("expected transitions %v, got %v", want, transitions)
+		}
+	}
+}
+
+func TestBreaker_SetThresholdsKeepsState(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewBreaker(CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute, Now: func() time.Time { return now }})
+	h := b.Policy()(func(ctx context.Context) (any, error) { return nil, errors.New("boom") })
+	h(context.Background())
+
+	if err := b.SetThresholds(0, 1, time.Second); err == nil {
+		t.Fatal("expected an error for a zero failure threshold")
+	}
+	if err := b.SetThresholds(3, 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if b.State() != CircuitOpen {
+		t.Fatalf("expected the breaker to stay open, got %s", b.State())
+	}
+	now = now.Add(time.Second)
+	if _, err := h(context.Background()); errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("expected the new open timeout to admit a trial call")
+	}
+}
+
+func TestBreaker_RetuneFillsDefaults(t *testing.T) {
+	b := NewBreaker(CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute})
+	if err := b.Retune(CircuitBreakerOptions{FailureThreshold: -1}); err == nil {
+		t.Fatal("expected an error for a negative failure threshold")
+	}
+	if err := b.Retune(CircuitBreakerOptions{OpenTimeout: time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	defaults := DefaultCircuitBreakerOptions()
+	d, _ := gosentry.DescriptorOf(b.Policy())
+	if v, _ := d.Option("failure_threshold"); v != defaults.FailureThreshold {
+		t.Fatalf("expected the default failure threshold, got %v", v)
+	}
+	if v, _ := d.Option("open_timeout"); v != time.Second {
+		t.Fatalf("expected the new open timeout, got %v", v)
+	}
+}
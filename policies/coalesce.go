@@ -0,0 +1,191 @@
+package policies
+
+import (
+	"context"
+	"sync"
+
+	"gosentry"
+)
+
+type keyKey struct{}
+
+// WithKey returns a context carrying key, which the Coalesce and Cache policies use
+// by default to tell calls apart.
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyKey{}, key)
+}
+
+// KeyFrom returns the key set with WithKey, or "".
+func KeyFrom(ctx context.Context) string {
+	key, _ := ctx.Value(keyKey{}).(string)
+	return key
+}
+
+type CoalesceOptions struct {
+	// Name identifies the policy in events. Defaults to "coalesce".
+	Name string
+
+	// Key returns the key of a call; calls with the same key share one execution.
+	// Calls with an empty key run on their own. Defaults to KeyFrom.
+	Key func(ctx context.Context) string
+}
+
+func DefaultCoalesceOptions() CoalesceOptions {
+	return CoalesceOptions{
+		Name: "coalesce",
+		Key:  KeyFrom,
+	}
+}
+
+func applyCoalesceDefaults(options CoalesceOptions) CoalesceOptions {
+	defaults := DefaultCoalesceOptions()
+
+	if options.Name == "" {
+		options.Name = defaults.Name
+	}
+	if options.Key == nil {
+		options.Key = defaults.Key
+	}
+
+	return options
+}
+
+// CoalesceStats counts the calls of a Coalescer.
+type CoalesceStats struct {
+	// Executions is the number of calls that ran the next handler.
+	Executions uint64
+
+	// Deduplicated is the number of calls that shared another call's execution.
+	Deduplicated uint64
+
+	// InFlight is the number of keys being executed.
+	InFlight int
+}
+
+// Coalesce returns a policy that lets one call per key run at a time; calls with
+// the same key arriving meanwhile share its result and error.
+func Coalesce(options CoalesceOptions) gosentry.Policy {
+	return NewCoalescer(options).Policy()
+}
+
+// Coalescer deduplicates concurrent calls with the same key. The shared execution
+// is detached from the cancellation of the call that started it: it runs as long
+// as any call waits for it and is cancelled once all of them have given up. Results
+// are shared between callers, so they must be safe to use concurrently.
+type Coalescer struct {
+	opts CoalesceOptions
+
+	mu      sync.Mutex
+	flights map[string]*flight
+	stats   CoalesceStats
+}
+
+// flight is one shared execution.
+type flight struct {
+	done    chan struct{}
+	result  any
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// NewCoalescer returns a Coalescer without validating options; see NewCoalesce.
+func NewCoalescer(options CoalesceOptions) *Coalescer {
+	return &Coalescer{
+		opts:    applyCoalesceDefaults(options),
+		flights: map[string]*flight{},
+	}
+}
+
+func (c *Coalescer) Name() string {
+	return c.opts.Name
+}
+
+func (c *Coalescer) Stats() CoalesceStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.InFlight = len(c.flights)
+	return stats
+}
+
+// Policy returns a policy that coalesces calls. Every policy returned by Policy
+// shares the same executions.
+func (c *Coalescer) Policy() gosentry.Policy {
+	policy := func(next gosentry.Handler) gosentry.Handler {
+		return func(ctx context.Context) (any, error) {
+			key := c.opts.Key(ctx)
+			if key == "" {
+				return next(ctx)
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			obs := observe(ctx, gosentry.KindCoalesce, c.opts.Name)
+			f, joined := c.join(ctx, key, next)
+
+			select {
+			case <-f.done:
+				ev := gosentry.Event{}
+				if joined {
+					ev.Reason = "coalesced"
+				}
+				obs.finishWith(ev, f.err)
+				return f.result, f.err
+			case <-ctx.Done():
+				c.leave(key, f)
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return gosentry.WithDescriptor(policy, gosentry.Descriptor{
+		Kind: gosentry.KindCoalesce,
+		Name: c.opts.Name,
+	})
+}
+
+// join returns the execution for key, starting it if there is none, and reports
+// whether the call joined an existing one.
+func (c *Coalescer) join(ctx context.Context, key string, next gosentry.Handler) (*flight, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if f, ok := c.flights[key]; ok {
+		f.waiters++
+		c.stats.Deduplicated++
+		return f, true
+	}
+
+	fctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	f := &flight{done: make(chan struct{}), waiters: 1, cancel: cancel}
+	c.flights[key] = f
+	c.stats.Executions++
+
+	go func() {
+		defer cancel()
+		f.result, f.err = next(fctx)
+		c.mu.Lock()
+		if c.flights[key] == f {
+			delete(c.flights, key)
+		}
+		c.mu.Unlock()
+		close(f.done)
+	}()
+	return f, false
+}
+
+// leave removes a caller that gave up, cancelling the execution if it was the last.
+func (c *Coalescer) leave(key string, f *flight) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f.waiters--
+	if f.waiters > 0 {
+		return
+	}
+	f.cancel()
+	if c.flights[key] == f {
+		delete(c.flights, key)
+	}
+}
@@ -0,0 +1,186 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gosentry"
+)
+
+// gate is a handler that counts executions and blocks until released.
+type gate struct {
+	calls   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+	result  any
+	err     error
+}
+
+func newGate(result any, err error) *gate {
+	return &gate{started: make(chan struct{}, 16), release: make(chan struct{}), result: result, err: err}
+}
+
+func (g *gate) handle(ctx context.Context) (any, error) {
+	g.calls.Add(1)
+	g.started <- struct{}{}
+	select {
+	case <-g.release:
+		return g.result, g.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not reached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCoalesce_SharesOneExecution(t *testing.T) {
+	c := NewCoalescer(CoalesceOptions{})
+	g := newGate("value", nil)
+	h := c.Policy()(g.handle)
+	ctx := WithKey(context.Background(), "user:1")
+
+	var wg sync.WaitGroup
+	results := make([]any, 5)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = h(ctx)
+		}()
+	}
+	<-g.started
+	waitFor(t, func() bool { return c.Stats().Deduplicated == 4 })
+	close(g.release)
+	wg.Wait()
+
+	if g.calls.Load() != 1 {
+		t.Fatalf("expected one execution, got %d", g.calls.Load())
+	}
+	for _, r := range results {
+		if r != "value" {
+			t.Fatalf("expected every caller to get the result, got %v", results)
+		}
+	}
+	if st := c.Stats(); st.Executions != 1 || st.Deduplicated != 4 || st.InFlight != 0 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+}
+
+func TestCoalesce_SharesErrors(t *testing.T) {
+	errDown := errors.New("down")
+	g := newGate(nil, errDown)
+	c := NewCoalescer(CoalesceOptions{})
+	h := c.Policy()(g.handle)
+	ctx := WithKey(context.Background(), "k")
+
+	errs := make(chan error, 2)
+	go func() { _, err := h(ctx); errs <- err }()
+	<-g.started
+	go func() { _, err := h(ctx); errs <- err }()
+	waitFor(t, func() bool { return c.Stats().Deduplicated == 1 })
+	close(g.release)
+
+	for range 2 {
+		if err := <-errs; err != errDown {
+			t.Fatalf("expected the shared error, got %v", err)
+		}
+	}
+}
+
+func TestCoalesce_DistinctOrMissingKeys(t *testing.T) {
+	var calls atomic.Int32
+	h := Coalesce(CoalesceOptions{})(func(ctx context.Context) (any, error) {
+		calls.Add(1)
+		return nil, nil
+	})
+
+	h(WithKey(context.Background(), "a"))
+	h(WithKey(context.Background(), "b"))
+	h(context.Background())
+	if calls.Load() != 3 {
+		t.Fatalf("expected every call to run, got %d", calls.Load())
+	}
+}
+
+func TestCoalesce_LeaderCancellationDoesNotFailFollowers(t *testing.T) {
+	c := NewCoalescer(CoalesceOptions{})
+	g := newGate("value", nil)
+	h := c.Policy()(g.handle)
+
+	leaderCtx, cancelLeader := context.WithCancel(WithKey(context.Background(), "k"))
+	leaderErr := make(chan error, 1)
+	go func() { _, err := h(leaderCtx); leaderErr <- err }()
+	<-g.started
+
+	followerRes := make(chan any, 1)
+	go func() { res, _ := h(WithKey(context.Background(), "k")); followerRes <- res }()
+	waitFor(t, func() bool { return c.Stats().Deduplicated == 1 })
+
+	cancelLeader()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the leader to give up, got %v", err)
+	}
+	close(g.release)
+	if res := <-followerRes; res != "value" {
+		t.Fatalf("expected the follower to get the result, got %v", res)
+	}
+}
+
+func TestCoalesce_CancelledOnceEveryCallerLeaves(t *testing.T) {
+	c := NewCoalescer(CoalesceOptions{})
+	g := newGate("value", nil)
+	h := c.Policy()(g.handle)
+
+	ctx, cancel := context.WithCancel(WithKey(context.Background(), "k"))
+	done := make(chan struct{})
+	go func() { h(ctx); close(done) }()
+	<-g.started
+	cancel()
+	<-done
+
+	waitFor(t, func() bool { return c.Stats().InFlight == 0 })
+	// The next call starts a fresh execution.
+	close(g.release)
+	if res, err := h(WithKey(context.Background(), "k")); err != nil || res != "value" {
+		t.Fatalf("unexpected result %v, %v", res, err)
+	}
+	if st := c.Stats(); st.Executions != 2 {
+		t.Fatalf("expected a second execution, got %+v", st)
+	}
+}
+
+func TestCoalesce_EmitsCoalescedOutcome(t *testing.T) {
+	rec := &eventRecorder{}
+	g := newGate(nil, nil)
+	c := NewCoalescer(CoalesceOptions{})
+	h := c.Policy()(g.handle)
+	ctx := gosentry.WithObserver(WithKey(context.Background(), "k"), rec)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); h(ctx) }()
+	<-g.started
+	go func() { defer wg.Done(); h(ctx) }()
+	waitFor(t, func() bool { return c.Stats().Deduplicated == 1 })
+	close(g.release)
+	wg.Wait()
+
+	successes := rec.ofKind(gosentry.EventSuccess)
+	if len(successes) != 2 || successes[0].Reason == successes[1].Reason {
+		t.Fatalf("expected one leader and one coalesced success, got %+v", successes)
+	}
+}
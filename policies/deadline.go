@@ -0,0 +1,46 @@
+package policies
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DeadlineHeader carries the caller's remaining deadline, in whole milliseconds,
+// between services.
+const DeadlineHeader = "Gosentry-Deadline-Ms"
+
+// EncodeDeadline writes the remaining time until ctx's deadline into h.
+// It does nothing if ctx has no deadline. An already expired deadline is encoded as 0.
+func EncodeDeadline(ctx context.Context, h http.Header) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+
+	remaining := time.Until(deadline).Milliseconds()
+	if remaining < 0 {
+		remaining = 0
+	}
+	h.Set(DeadlineHeader, strconv.FormatInt(remaining, 10))
+}
+
+// DecodeDeadline derives a context from ctx whose deadline is the remaining time
+// carried in h. If h has no deadline header, ctx is returned with a no-op cancel.
+// The returned cancel function must always be called.
+func DecodeDeadline(ctx context.Context, h http.Header) (context.Context, context.CancelFunc, error) {
+	v := h.Get(DeadlineHeader)
+	if v == "" {
+		return ctx, func() {}, nil
+	}
+
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms < 0 {
+		return ctx, func() {}, fmt.Errorf("invalid %s header %q", DeadlineHeader, v)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+	return ctx, cancel, nil
+}
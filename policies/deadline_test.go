@@ -0,0 +1,67 @@
+package policies
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEncodeDeadline_WritesRemainingMilliseconds(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	h := http.Header{}
+	EncodeDeadline(ctx, h)
+
+	ms, err := strconv.Atoi(h.Get(DeadlineHeader))
+	if err != nil {
+		t.Fatalf("expected numeric header, got %q", h.Get(DeadlineHeader))
+	}
+	if ms <= 250 || ms > 300 {
+		t.Fatalf("expected remaining around 300ms, got %d", ms)
+	}
+}
+
+func TestEncodeDeadline_NoDeadline(t *testing.T) {
+	h := http.Header{}
+	EncodeDeadline(context.Background(), h)
+
+	if _, ok := h[DeadlineHeader]; ok {
+		t.Fatalf("expected no header without a deadline")
+	}
+}
+
+func TestDecodeDeadline_AppliesHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set(DeadlineHeader, "200")
+
+	ctx, cancel, err := DecodeDeadline(context.Background(), h)
+	defer cancel()
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatal("expected ctx to have a deadline")
+	}
+	if remaining := time.Until(deadline); remaining <= 150*time.Millisecond || remaining > 200*time.Millisecond {
+		t.Fatalf("expected remaining around 200ms, got %v", remaining)
+	}
+}
+
+func TestDecodeDeadline_RejectsMalformedHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set(DeadlineHeader, "soon")
+
+	ctx, cancel, err := DecodeDeadline(context.Background(), h)
+	defer cancel()
+	if err == nil {
+		t.Fatal("expected error for malformed header")
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("expected ctx without deadline")
+	}
+}
@@ -0,0 +1,320 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"gosentry"
+)
+
+type FailoverOptions struct {
+	// Name identifies the policy in events. Defaults to "failover".
+	Name string
+
+	// Endpoints are the targets in priority order. Handlers read the endpoint they
+	// are called for with Endpoint.
+	Endpoints []string
+
+	// Breaker, if set, gives every endpoint its own circuit breaker with these
+	// options; endpoints whose breaker is open are skipped whatever ShouldFailover
+	// says. Breakers are named "<Name>/<endpoint>". Cancelled calls do not count
+	// as failures.
+	Breaker *CircuitBreakerOptions
+
+	// Sticky starts each call with the endpoint that served the last successful
+	// one, instead of going back to the first endpoint as soon as it recovers.
+	Sticky bool
+
+	// ShouldFailover reports whether the next endpoint should be tried after err.
+	// If nil, every error except a PermanentError fails over.
+	ShouldFailover func(err error) bool
+}
+
+func DefaultFailoverOptions() FailoverOptions {
+	return FailoverOptions{
+		Name: "failover",
+		ShouldFailover: func(err error) bool {
+			var perm *PermanentError
+			return !errors.As(err, &perm)
+		},
+	}
+}
+
+func applyFailoverDefaults(options FailoverOptions) FailoverOptions {
+	defaults := DefaultFailoverOptions()
+
+	if options.Name == "" {
+		options.Name = defaults.Name
+	}
+	if options.ShouldFailover == nil {
+		options.ShouldFailover = defaults.ShouldFailover
+	}
+
+	return options
+}
+
+// EndpointError is the failure of one endpoint.
+type EndpointError struct {
+	Endpoint string
+	Err      error
+}
+
+// FailoverError is returned when every endpoint of a failover failed or was
+// skipped by its open circuit breaker.
+type FailoverError struct {
+	Failover string
+
+	// Attempts are in the order the endpoints were tried.
+	Attempts []EndpointError
+}
+
+func (e *FailoverError) Error() string {
+	if len(e.Attempts) == 0 {
+		return fmt.Sprintf("failover %q has no endpoints", e.Failover)
+	}
+	parts := make([]string, len(e.Attempts))
+	for i, a := range e.Attempts {
+		parts[i] = a.Endpoint + ": " + a.Err.Error()
+	}
+	return fmt.Sprintf("failover %q: all endpoints failed: %s", e.Failover, strings.Join(parts, "; "))
+}
+
+// Unwrap returns the error of every endpoint tried.
+func (e *FailoverError) Unwrap() []error {
+	errs := make([]error, len(e.Attempts))
+	for i, a := range e.Attempts {
+		errs[i] = a.Err
+	}
+	return errs
+}
+
+type endpointKey struct{}
+
+// endpointIndexKey holds the index in Endpoints of the endpoint a failover is
+// calling the handler for, so that Handlers tells duplicate endpoints apart.
+type endpointIndexKey struct{}
+
+// Endpoint returns the endpoint a failover or balancer policy is calling the
+// handler for, or "" outside of them.
+func Endpoint(ctx context.Context) string {
+	endpoint, _ := ctx.Value(endpointKey{}).(string)
+	return endpoint
+}
+
+type failoverRecordKey struct{}
+
+// FailoverRecord records which endpoints a call went to; see RecordFailover.
+type FailoverRecord struct {
+	mu       sync.Mutex
+	tried    []string
+	endpoint string
+}
+
+// RecordFailover returns a context whose failover policy reports to the returned
+// record, so the caller can tell which endpoint served the call.
+func RecordFailover(ctx context.Context) (context.Context, *FailoverRecord) {
+	rec := &FailoverRecord{}
+	return context.WithValue(ctx, failoverRecordKey{}, rec), rec
+}
+
+// Endpoint returns the endpoint that served the call, or "" if none did.
+func (r *FailoverRecord) Endpoint() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.endpoint
+}
+
+// Tried returns the endpoints called, in order, including the one that served.
+// Endpoints skipped by their open breaker are left out.
+func (r *FailoverRecord) Tried() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.tried...)
+}
+
+func (r *FailoverRecord) try(endpoint string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tried = append(r.tried, endpoint)
+}
+
+func (r *FailoverRecord) served(endpoint string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endpoint = endpoint
+}
+
+// Failover returns a policy that calls the next handler once per endpoint, in
+// priority order, until one succeeds.
+func Failover(options FailoverOptions) gosentry.Policy {
+	return NewFailoverGroup(options).Policy()
+}
+
+// FailoverGroup holds the endpoints of a failover policy. Every policy returned by
+// Policy shares the breakers and, if Sticky is set, the preferred endpoint.
+type FailoverGroup struct {
+	opts     FailoverOptions
+	breakers []*Breaker
+	guards   []gosentry.Policy
+
+	// preferred is the index of the endpoint tried first.
+	preferred atomic.Int64
+}
+
+// NewFailoverGroup returns a FailoverGroup without validating options; see
+// NewFailover.
+func NewFailoverGroup(options FailoverOptions) *FailoverGroup {
+	opts := applyFailoverDefaults(options)
+	f := &FailoverGroup{opts: opts}
+	if opts.Breaker != nil {
+		for _, endpoint := range opts.Endpoints {
+			b := NewBreaker(endpointBreakerOptions(*opts.Breaker, opts.Name+"/"+endpoint))
+			f.breakers = append(f.breakers, b)
+			f.guards = append(f.guards, b.Policy())
+		}
+	}
+	return f
+}
+
+// endpointBreakerOptions names a per-endpoint breaker and keeps cancelled calls
+// from counting against the endpoint.
+func endpointBreakerOptions(opts CircuitBreakerOptions, name string) CircuitBreakerOptions {
+	opts.Name = name
+	shouldTrip := opts.ShouldTrip
+	opts.ShouldTrip = func(err error) bool {
+		if errors.Is(err, context.Canceled) {
+			return false
+		}
+		return shouldTrip == nil || shouldTrip(err)
+	}
+	return opts
+}
+
+func (f *FailoverGroup) Name() string {
+	return f.opts.Name
+}
+
+// Breakers returns the per-endpoint breakers in the order of Endpoints, or nil if
+// Breaker is not set.
+func (f *FailoverGroup) Breakers() []*Breaker {
+	return append([]*Breaker(nil), f.breakers...)
+}
+
+// Current returns the endpoint the next call tries first.
+func (f *FailoverGroup) Current() string {
+	if len(f.opts.Endpoints) == 0 {
+		return ""
+	}
+	return f.opts.Endpoints[f.preferred.Load()]
+}
+
+// order returns the indexes of the endpoints in the order they are tried.
+func (f *FailoverGroup) order() []int {
+	first := int(f.preferred.Load())
+	order := make([]int, 0, len(f.opts.Endpoints))
+	order = append(order, first)
+	for i := range f.opts.Endpoints {
+		if i != first {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// Policy returns a policy that fails over between the endpoints.
+func (f *FailoverGroup) Policy() gosentry.Policy {
+	policy := func(next gosentry.Handler) gosentry.Handler {
+		return func(ctx context.Context) (any, error) {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			obs := observe(ctx, gosentry.KindFailover, f.opts.Name)
+			rec, _ := ctx.Value(failoverRecordKey{}).(*FailoverRecord)
+			ferr := &FailoverError{Failover: f.opts.Name}
+			if len(f.opts.Endpoints) == 0 {
+				obs.finish(ferr)
+				return nil, ferr
+			}
+
+			order := f.order()
+			for n, i := range order {
+				endpoint := f.opts.Endpoints[i]
+
+				// called tells a call rejected by the endpoint's breaker apart from
+				// one that reached the endpoint.
+				called := false
+				call := func(ctx context.Context) (any, error) {
+					called = true
+					rec.try(endpoint)
+					return next(ctx)
+				}
+				if f.guards != nil {
+					call = f.guards[i](call)
+				}
+				result, err := call(context.WithValue(context.WithValue(ctx, endpointKey{}, endpoint), endpointIndexKey{}, i))
+				ev := gosentry.Event{Attempt: n + 1, Endpoint: endpoint}
+				if err == nil {
+					if f.opts.Sticky {
+						f.preferred.Store(int64(i))
+					}
+					rec.served(endpoint)
+					obs.finishWith(ev, nil)
+					return result, nil
+				}
+
+				ferr.Attempts = append(ferr.Attempts, EndpointError{Endpoint: endpoint, Err: err})
+				if ctx.Err() != nil || (called && !f.opts.ShouldFailover(err)) {
+					obs.finishWith(ev, err)
+					return nil, err
+				}
+				if n+1 < len(order) {
+					ev.Kind, ev.Err = gosentry.EventRetry, err
+					obs.emit(ev)
+				}
+			}
+
+			obs.finishAttempt(len(order), ferr)
+			return nil, ferr
+		}
+	}
+	// The per-endpoint breakers are its children; they can be retuned at runtime.
+	return gosentry.WithDescriber(policy, func() gosentry.Descriptor {
+		d := gosentry.Descriptor{
+			Kind: gosentry.KindFailover,
+			Name: f.opts.Name,
+			Options: []gosentry.Attribute{
+				gosentry.Attr("endpoints", strings.Join(f.opts.Endpoints, ",")),
+				gosentry.Attr("breaker", f.opts.Breaker != nil),
+				gosentry.Attr("sticky", f.opts.Sticky),
+			},
+		}
+		for _, b := range f.breakers {
+			d.Children = append(d.Children, b.descriptor())
+		}
+		return d
+	})
+}
+
+// Handlers returns a handler that calls handlers[i] for Endpoints[i] through the
+// policy. It returns an error unless there is one handler per endpoint.
+func (f *FailoverGroup) Handlers(handlers ...gosentry.Handler) (gosentry.Handler, error) {
+	if len(handlers) != len(f.opts.Endpoints) {
+		return nil, fmt.Errorf("policies: failover %q has %d endpoints but %d handlers", f.opts.Name, len(f.opts.Endpoints), len(handlers))
+	}
+	handlers = slices.Clone(handlers)
+	return f.Policy()(func(ctx context.Context) (any, error) {
+		return handlers[ctx.Value(endpointIndexKey{}).(int)](ctx)
+	}), nil
+}
@@ -0,0 +1,226 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"gosentry"
+)
+
+// endpoints returns a handler that fails for the endpoints in down and records
+// every endpoint it is called for.
+func endpoints(down map[string]bool, calls *[]string) gosentry.Handler {
+	return func(ctx context.Context) (any, error) {
+		endpoint := Endpoint(ctx)
+		*calls = append(*calls, endpoint)
+		if down[endpoint] {
+			return nil, errors.New(endpoint + " down")
+		}
+		return endpoint, nil
+	}
+}
+
+func mustFailover(t *testing.T, options FailoverOptions) *FailoverGroup {
+	t.Helper()
+	f, err := NewFailover(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestFailover_TriesEndpointsInOrder(t *testing.T) {
+	var calls []string
+	f := mustFailover(t, FailoverOptions{Endpoints: []string{"primary", "secondary", "tertiary"}})
+	h := f.Policy()(endpoints(map[string]bool{"primary": true}, &calls))
+
+	ctx, rec := RecordFailover(context.Background())
+	res, err := h(ctx)
+	if err != nil || res != "secondary" {
+		t.Fatalf("expected the secondary to serve, got %v, %v", res, err)
+	}
+	if rec.Endpoint() != "secondary" || !slices.Equal(rec.Tried(), []string{"primary", "secondary"}) {
+		t.Fatalf("unexpected record %q %v", rec.Endpoint(), rec.Tried())
+	}
+
+	// Without Sticky, the next call starts at the primary again.
+	calls = nil
+	h(context.Background())
+	if calls[0] != "primary" {
+		t.Fatalf("expected the primary first, got %v", calls)
+	}
+}
+
+func TestFailover_AllEndpointsFail(t *testing.T) {
+	var calls []string
+	f := mustFailover(t, FailoverOptions{Name: "regions", Endpoints: []string{"eu", "us"}})
+	_, err := f.Policy()(endpoints(map[string]bool{"eu": true, "us": true}, &calls))(context.Background())
+
+	var ferr *FailoverError
+	if !errors.As(err, &ferr) || len(ferr.Attempts) != 2 || ferr.Attempts[1].Endpoint != "us" {
+		t.Fatalf("expected a *FailoverError listing both endpoints, got %v", err)
+	}
+}
+
+func TestFailover_PermanentErrorsDoNotFailOver(t *testing.T) {
+	errBadRequest := errors.New("bad request")
+	f := mustFailover(t, FailoverOptions{Endpoints: []string{"eu", "us"}})
+
+	calls := 0
+	_, err := f.Policy()(func(ctx context.Context) (any, error) {
+		calls++
+		return nil, Permanent(errBadRequest)
+	})(context.Background())
+	if !errors.Is(err, errBadRequest) || calls != 1 {
+		t.Fatalf("expected the permanent error from one endpoint, got %v after %d calls", err, calls)
+	}
+}
+
+func TestFailover_SkipsOpenBreakers(t *testing.T) {
+	var calls []string
+	down := map[string]bool{"eu": true}
+	f := mustFailover(t, FailoverOptions{
+		Name:      "regions",
+		Endpoints: []string{"eu", "us"},
+		Breaker:   &CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute},
+	})
+	h := f.Policy()(endpoints(down, &calls))
+
+	h(context.Background())
+	h(context.Background())
+	if !slices.Equal(calls, []string{"eu", "us", "us"}) {
+		t.Fatalf("expected the open endpoint to be skipped, got %v", calls)
+	}
+
+	breakers := f.Breakers()
+	if breakers[0].Name() != "regions/eu" || breakers[0].State() != CircuitOpen || breakers[1].State() != CircuitClosed {
+		t.Fatalf("unexpected breakers %s=%s %s=%s", breakers[0].Name(), breakers[0].State(), breakers[1].Name(), breakers[1].State())
+	}
+}
+
+func TestFailover_SkipsOpenBreakersWithCustomShouldFailover(t *testing.T) {
+	var calls []string
+	f := mustFailover(t, FailoverOptions{
+		Endpoints:      []string{"eu", "us"},
+		Breaker:        &CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute},
+		ShouldFailover: func(err error) bool { return false },
+	})
+	f.Breakers()[0].ForceOpen()
+
+	ctx, rec := RecordFailover(context.Background())
+	res, err := f.Policy()(endpoints(nil, &calls))(ctx)
+	if err != nil || res != "us" {
+		t.Fatalf("expected the open endpoint to be skipped, got %v, %v", res, err)
+	}
+	if !slices.Equal(calls, []string{"us"}) || !slices.Equal(rec.Tried(), []string{"us"}) {
+		t.Fatalf("expected only us to be called and recorded, got %v and %v", calls, rec.Tried())
+	}
+}
+
+func TestFailover_Sticky(t *testing.T) {
+	var calls []string
+	down := map[string]bool{"eu": true}
+	f := mustFailover(t, FailoverOptions{Endpoints: []string{"eu", "us"}, Sticky: true})
+	h := f.Policy()(endpoints(down, &calls))
+
+	h(context.Background())
+	delete(down, "eu")
+	h(context.Background())
+	if !slices.Equal(calls, []string{"eu", "us", "us"}) || f.Current() != "us" {
+		t.Fatalf("expected to stick to the last healthy endpoint, got %v, current %q", calls, f.Current())
+	}
+}
+
+func TestFailover_Handlers(t *testing.T) {
+	f := mustFailover(t, FailoverOptions{Endpoints: []string{"eu", "us"}})
+	h, err := f.Handlers(
+		func(context.Context) (any, error) { return nil, errors.New("eu down") },
+		func(context.Context) (any, error) { return "from us", nil },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res, err := h(context.Background()); err != nil || res != "from us" {
+		t.Fatalf("unexpected result %v, %v", res, err)
+	}
+	if _, err := f.Handlers(h); err == nil {
+		t.Fatal("expected an error for one handler and two endpoints")
+	}
+}
+
+func TestFailover_HandlersOfDuplicateEndpoints(t *testing.T) {
+	// NewFailoverGroup does not validate, so the same endpoint may appear twice, e.g.
+	// to try it again after another one; each position keeps its own handler.
+	f := NewFailoverGroup(FailoverOptions{Endpoints: []string{"eu", "us", "eu"}})
+	var calls []int
+	handler := func(i int, err error) gosentry.Handler {
+		return func(context.Context) (any, error) {
+			calls = append(calls, i)
+			return i, err
+		}
+	}
+	h, err := f.Handlers(handler(0, errors.New("down")), handler(1, errors.New("down")), handler(2, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, err := h(context.Background()); err != nil || res != 2 || !slices.Equal(calls, []int{0, 1, 2}) {
+		t.Fatalf("expected every handler once, got %v, %v after %v", res, err, calls)
+	}
+}
+
+func TestNewFailover_Validates(t *testing.T) {
+	_, err := NewFailover(FailoverOptions{Endpoints: []string{"eu", "eu"}})
+	var oerr *OptionError
+	if !errors.As(err, &oerr) || oerr.Field != "FailoverOptions.Endpoints" {
+		t.Fatalf("expected an error for the duplicate endpoint, got %v", err)
+	}
+}
+
+func TestFailover_EmitsEndpoint(t *testing.T) {
+	rec := &eventRecorder{}
+	ctx := gosentry.WithObserver(context.Background(), rec)
+	var calls []string
+	f := mustFailover(t, FailoverOptions{Endpoints: []string{"eu", "us"}})
+	f.Policy()(endpoints(map[string]bool{"eu": true}, &calls))(ctx)
+
+	retries, successes := rec.ofKind(gosentry.EventRetry), rec.ofKind(gosentry.EventSuccess)
+	if len(retries) != 1 || retries[0].Endpoint != "eu" {
+		t.Fatalf("expected a retry event for eu, got %+v", retries)
+	}
+	if len(successes) != 1 || successes[0].Endpoint != "us" || successes[0].Attempt != 2 {
+		t.Fatalf("expected a success event for us, got %+v", successes)
+	}
+}
+
+func TestFailover_Describe(t *testing.T) {
+	d, ok := gosentry.DescriptorOf(Failover(FailoverOptions{Endpoints: []string{"eu", "us"}, Sticky: true}))
+	if !ok || d.Kind != gosentry.KindFailover || d.Name != "failover" {
+		t.Fatalf("unexpected descriptor %+v", d)
+	}
+	if v, _ := d.Option("endpoints"); v != "eu,us" {
+		t.Errorf("expected the endpoints, got %v", v)
+	}
+}
+
+func TestFailover_DescribesBreakersAsChildren(t *testing.T) {
+	f := mustFailover(t, FailoverOptions{
+		Name:      "regions",
+		Endpoints: []string{"eu", "us"},
+		Breaker:   &CircuitBreakerOptions{FailureThreshold: 2},
+	})
+	if err := f.Breakers()[1].SetThresholds(4, 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	d, _ := gosentry.DescriptorOf(f.Policy())
+	if len(d.Children) != 2 || d.Children[0].Name != "regions/eu" || d.Children[1].Name != "regions/us" {
+		t.Fatalf("expected a breaker per endpoint, got %+v", d.Children)
+	}
+	if v, _ := d.Children[1].Option("failure_threshold"); v != 4 {
+		t.Fatalf("expected the retuned threshold, got %v", v)
+	}
+}
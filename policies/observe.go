@@ -0,0 +1,70 @@
+package policies
+
+import (
+	"context"
+	"time"
+
+	"gosentry"
+)
+
+// observation reports one call through a policy to the observers attached to ctx.
+// When nothing is observing, it does not read the clock and emits nothing.
+type observation struct {
+	ctx    context.Context
+	policy string
+	kind   gosentry.Kind
+	active bool
+	start  time.Time
+}
+
+func observe(ctx context.Context, kind gosentry.Kind, policy string) observation {
+	o := observation{ctx: ctx, policy: policy, kind: kind}
+	if gosentry.Observed(ctx) {
+		o.active = true
+		o.start = time.Now()
+	}
+	return o
+}
+
+func (o observation) emit(ev gosentry.Event) {
+	if !o.active {
+		return
+	}
+	ev.Policy = o.policy
+	ev.PolicyKind = string(o.kind)
+	gosentry.Emit(o.ctx, ev)
+}
+
+func (o observation) elapsed() time.Duration {
+	if !o.active {
+		return 0
+	}
+	return time.Since(o.start)
+}
+
+// finish emits EventSuccess or EventFailure for the call's outcome.
+func (o observation) finish(err error) {
+	o.finishAttempt(0, err)
+}
+
+func (o observation) finishAttempt(attempt int, err error) {
+	o.finishWith(gosentry.Event{Attempt: attempt}, err)
+}
+
+// finishWith emits the outcome event, starting from ev for policy-specific fields.
+func (o observation) finishWith(ev gosentry.Event, err error) {
+	if !o.active {
+		return
+	}
+	ev.Kind = gosentry.EventSuccess
+	if err != nil {
+		ev.Kind = gosentry.EventFailure
+	}
+	ev.Duration = o.elapsed()
+	ev.Err = err
+	o.emit(ev)
+}
+
+func (o observation) reject(reason string, err error) {
+	o.emit(gosentry.Event{Kind: gosentry.EventRejected, Reason: reason, Err: err})
+}
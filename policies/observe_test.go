@@ -0,0 +1,190 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gosentry"
+)
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []gosentry.Event
+}
+
+func (r *eventRecorder) Observe(ev gosentry.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+}
+
+func (r *eventRecorder) ofKind(kind gosentry.EventKind) []gosentry.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []gosentry.Event
+	for _, ev := range r.events {
+		if ev.Kind == kind {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+func TestObserve_RetryEmitsRetryAndOutcome(t *testing.T) {
+	rec := &eventRecorder{}
+	ctx := gosentry.WithObserver(context.Background(), rec)
+
+	attempts := 0
+	_, err := gosentry.Execute(ctx, func(ctx context.Context) (any, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("fail")
+		}
+		return "ok", nil
+	}, Retry(RetryOptions{MaxAttempts: 3, InitialDelay: time.Millisecond, Backoff: BackoffFixed}))
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+
+	retries := rec.ofKind(gosentry.EventRetry)
+	if len(retries) != 2 {
+		t.Fatalf("expected 2 retry events, got %d", len(retries))
+	}
+	if retries[0].Attempt != 1 || retries[1].Attempt != 2 || retries[0].Policy != "retry" {
+		t.Fatalf("unexpected retry events: %+v", retries)
+	}
+
+	successes := rec.ofKind(gosentry.EventSuccess)
+	if len(successes) != 1 || successes[0].Attempt != 3 {
+		t.Fatalf("expected one success on attempt 3, got %+v", successes)
+	}
+}
+
+func TestObserve_CircuitBreakerEmitsStateChangeAndRejection(t *testing.T) {
+	rec := &eventRecorder{}
+	ctx := gosentry.WithObserver(context.Background(), rec)
+
+	cb := CircuitBreaker(CircuitBreakerOptions{Name: "payments", FailureThreshold: 1, OpenTimeout: time.Minute})
+	failing := func(ctx context.Context) (any, error) { return nil, errors.New("fail") }
+
+	gosentry.Execute(ctx, failing, cb)
+	_, err := gosentry.Execute(ctx, failing, cb)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	changes := rec.ofKind(gosentry.EventStateChange)
+	if len(changes) != 1 || changes[0].From != string(CircuitClosed) || changes[0].To != string(CircuitOpen) {
+		t.Fatalf("expected closed->open transition, got %+v", changes)
+	}
+
+	rejections := rec.ofKind(gosentry.EventRejected)
+	if len(rejections) != 1 || rejections[0].Reason != "circuit_open" || rejections[0].Policy != "payments" {
+		t.Fatalf("expected one circuit_open rejection, got %+v", rejections)
+	}
+}
+
+func TestObserve_RateLimitEmitsRejection(t *testing.T) {
+	rec := &eventRecorder{}
+	ctx := gosentry.WithObserver(context.Background(), rec)
+
+	rl := RateLimit(RateLimitOptions{Rate: 1, Burst: 1})
+	ok := func(ctx context.Context) (any, error) { return "ok", nil }
+
+	gosentry.Execute(ctx, ok, rl)
+	gosentry.Execute(ctx, ok, rl)
+
+	if n := len(rec.ofKind(gosentry.EventSuccess)); n != 1 {
+		t.Fatalf("expected one success, got %d", n)
+	}
+	rejections := rec.ofKind(gosentry.EventRejected)
+	if len(rejections) != 1 || rejections[0].Reason != "rate_limited" {
+		t.Fatalf("expected one rate_limited rejection, got %+v", rejections)
+	}
+}
+
+func TestObserve_TimeoutEmitsTimeout(t *testing.T) {
+	rec := &eventRecorder{}
+	ctx := gosentry.WithObserver(context.Background(), rec)
+
+	gosentry.Execute(ctx, func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, Timeout(TimeoutOptions{Duration: 5 * time.Millisecond}))
+
+	timeouts := rec.ofKind(gosentry.EventTimeout)
+	if len(timeouts) != 1 {
+		t.Fatalf("expected one timeout event, got %d", len(timeouts))
+	}
+	var terr *TimeoutError
+	if !errors.As(timeouts[0].Err, &terr) {
+		t.Fatalf("expected timeout event to carry *TimeoutError, got %v", timeouts[0].Err)
+	}
+}
+
+func TestPolicies_CarryDescriptors(t *testing.T) {
+	limiter := NewLimiter(RateLimitOptions{Name: "rl", Rate: 1, Burst: 1})
+	breaker := NewBreaker(CircuitBreakerOptions{Name: "cb", FailureThreshold: 1})
+
+	tests := []struct {
+		policy gosentry.Policy
+		want   gosentry.Descriptor
+	}{
+		{Retry(RetryOptions{Name: "r"}), gosentry.Descriptor{Kind: gosentry.KindRetry, Name: "r"}},
+		{breaker.Policy(), gosentry.Descriptor{Kind: gosentry.KindCircuitBreaker, Name: "cb"}},
+		{Timeout(TimeoutOptions{Name: "t"}), gosentry.Descriptor{Kind: gosentry.KindTimeout, Name: "t"}},
+		{limiter.Policy(), gosentry.Descriptor{Kind: gosentry.KindRateLimit, Name: "rl"}},
+	}
+	for _, tt := range tests {
+		d, ok := gosentry.DescriptorOf(tt.policy)
+		if !ok || d.Kind != tt.want.Kind || d.Name != tt.want.Name {
+			t.Errorf("expected %+v, got %+v (%v)", tt.want, d, ok)
+		}
+	}
+
+	// Describing must not touch state.
+	if limiter.Tokens() != 1 || breaker.State() != CircuitClosed {
+		t.Fatalf("expected untouched state, got tokens=%v state=%s", limiter.Tokens(), breaker.State())
+	}
+
+	warnings := gosentry.Lint(breaker.Policy(), Retry(RetryOptions{}))
+	if len(warnings) != 1 || warnings[0].Rule != "retry-inside-circuit-breaker" {
+		t.Fatalf("expected retry-inside-circuit-breaker, got %v", warnings)
+	}
+}
+
+func TestPolicies_DescribeEffectiveOptions(t *testing.T) {
+	d, _ := gosentry.DescriptorOf(Retry(RetryOptions{MaxAttempts: 5}))
+	if v, _ := d.Option("max_attempts"); v != 5 {
+		t.Errorf("expected max_attempts=5, got %v", v)
+	}
+	if v, _ := d.Option("initial_delay"); v != DefaultRetryOptions().InitialDelay {
+		t.Errorf("expected the default initial_delay, got %v", v)
+	}
+
+	d, _ = gosentry.DescriptorOf(Timeout(TimeoutOptions{Duration: time.Second, Adaptive: &AdaptiveTimeoutOptions{}}))
+	if v, _ := d.Option("adaptive.max"); v != time.Second {
+		t.Errorf("expected adaptive.max to default to the duration, got %v", v)
+	}
+	if _, ok := d.Option("safety_margin"); ok {
+		t.Error("expected unset safety_margin to be omitted")
+	}
+
+	d, _ = gosentry.DescriptorOf(Timeout(TimeoutOptions{Duration: -1}))
+	if v, _ := d.Option("disabled"); v != true {
+		t.Errorf("expected a disabled timeout to say so, got %+v", d)
+	}
+
+	limiter := NewLimiter(RateLimitOptions{Rate: 1, Burst: 1})
+	p := limiter.Policy()
+	if err := limiter.SetRate(20); err != nil {
+		t.Fatal(err)
+	}
+	d, _ = gosentry.DescriptorOf(p)
+	if v, _ := d.Option("rate"); v != 20.0 {
+		t.Errorf("expected the live rate, got %v", v)
+	}
+}
@@ -3,6 +3,8 @@ package policies
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
 	"sync"
 	"time"
 
@@ -15,7 +17,11 @@ var (
 )
 
 type RateLimitOptions struct {
-	// Rate is the number of tokens to add per second.
+	// Name identifies the policy in events. Defaults to "rate_limit".
+	Name string
+
+	// Rate is the number of tokens to add per second. Values that are not a
+	// positive number select the default; NewRateLimit rejects them instead.
 	Rate float64
 
 	// Burst is the maximum number of tokens that can be stored in the bucket.
@@ -27,59 +33,202 @@ type RateLimitOptions struct {
 
 func DefaultRateLimitOptions() RateLimitOptions {
 	return RateLimitOptions{
+		Name:  "rate_limit",
 		Rate:  10,
 		Burst: 10,
 	}
 }
 
 func RateLimit(options RateLimitOptions) gosentry.Policy {
-	opts := applyRateLimitDefaults(options)
-
-	var mu sync.Mutex
-	tokens := float64(opts.Burst)
-	lastAt := opts.Now()
-
-	allow := func() bool {
-		mu.Lock()
-		defer mu.Unlock()
-
-		now := opts.Now()
-		elapsed := now.Sub(lastAt).Seconds()
-		tokens += elapsed * opts.Rate
-
-		if tokens > float64(opts.Burst) {
-			tokens = float64(opts.Burst)
-		}
+	return NewLimiter(options).Policy()
+}
 
-		lastAt = now
+// Limiter is a token bucket whose state can be inspected while it admits calls.
+// Every policy returned by Policy draws from the same bucket.
+type Limiter struct {
+	opts RateLimitOptions
 
-		if tokens >= 1 {
-			tokens -= 1
-			return true
-		}
+	mu     sync.Mutex
+	tokens float64
+	lastAt time.Time
+}
 
-		return false
+func NewLimiter(options RateLimitOptions) *Limiter {
+	opts := applyRateLimitDefaults(options)
+	return &Limiter{
+		opts:   opts,
+		tokens: float64(opts.Burst),
+		lastAt: opts.Now(),
 	}
+}
 
-	return func(next gosentry.Handler) gosentry.Handler {
+// Policy returns a policy that rejects calls with ErrRateLimitExceeded when the
+// bucket is empty.
+func (l *Limiter) Policy() gosentry.Policy {
+	policy := func(next gosentry.Handler) gosentry.Handler {
 		return func(ctx context.Context) (any, error) {
 			if ctx.Err() != nil {
 				return nil, ctx.Err()
 			}
 
-			if !allow() {
+			obs := observe(ctx, gosentry.KindRateLimit, l.opts.Name)
+			allowed, bucket := l.allow()
+			if !allowed {
+				bucket.Kind, bucket.Reason, bucket.Err = gosentry.EventRejected, "rate_limited", ErrRateLimitExceeded
+				obs.emit(bucket)
 				return nil, ErrRateLimitExceeded
 			}
 
-			return next(ctx)
+			result, err := next(ctx)
+			obs.finishWith(bucket, err)
+			return result, err
 		}
 	}
+	// Rate and burst can be changed at runtime, so they are read when described.
+	return gosentry.WithDescriber(policy, func() gosentry.Descriptor {
+		return gosentry.Descriptor{
+			Kind: gosentry.KindRateLimit,
+			Name: l.opts.Name,
+			Options: []gosentry.Attribute{
+				gosentry.Attr("rate", l.Rate()),
+				gosentry.Attr("burst", l.Burst()),
+			},
+		}
+	})
+}
+
+func (l *Limiter) Name() string {
+	return l.opts.Name
+}
+
+// Tokens returns the tokens currently available, including those refilled since the
+// last call.
+func (l *Limiter) Tokens() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refillLocked()
+	return l.tokens
+}
+
+func (l *Limiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.opts.Rate
+}
+
+func (l *Limiter) Burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.opts.Burst
+}
+
+// SetRate changes the refill rate, in tokens per second. Tokens accrued so far are
+// kept.
+func (l *Limiter) SetRate(rate float64) error {
+	if err := checkRate(rate); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refillLocked()
+	l.opts.Rate = rate
+	return nil
+}
+
+// SetBurst changes the bucket size. Available tokens are capped to the new size.
+func (l *Limiter) SetBurst(burst int) error {
+	if err := checkBurst(burst); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refillLocked()
+	l.setBurstLocked(burst)
+	return nil
+}
+
+// SetLimits changes the refill rate and the bucket size together. Neither is
+// changed unless both are valid.
+func (l *Limiter) SetLimits(rate float64, burst int) error {
+	if err := errors.Join(checkRate(rate), checkBurst(burst)); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refillLocked()
+	l.opts.Rate = rate
+	l.setBurstLocked(burst)
+	return nil
+}
+
+// Retune applies the Rate and Burst of options like SetLimits, after Validate and
+// with the defaults for zero fields. The other fields of options are ignored.
+func (l *Limiter) Retune(options RateLimitOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+	opts := applyRateLimitDefaults(options)
+	return l.SetLimits(opts.Rate, opts.Burst)
+}
+
+func (l *Limiter) setBurstLocked(burst int) {
+	l.opts.Burst = burst
+	if l.tokens > float64(burst) {
+		l.tokens = float64(burst)
+	}
+}
+
+func checkRate(rate float64) error {
+	if rate <= 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+		return fmt.Errorf("rate limit: rate must be a positive number, got %v", rate)
+	}
+	return nil
+}
+
+func checkBurst(burst int) error {
+	if burst <= 0 {
+		return fmt.Errorf("rate limit: burst must be positive, got %d", burst)
+	}
+	return nil
+}
+
+// allow takes a token if there is one. It also returns an event describing the
+// bucket after the decision, with Tokens, Rate and Burst set.
+func (l *Limiter) allow() (bool, gosentry.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refillLocked()
+
+	allowed := l.tokens >= 1
+	if allowed {
+		l.tokens -= 1
+	}
+	return allowed, gosentry.Event{Tokens: l.tokens, Rate: l.opts.Rate, Burst: l.opts.Burst}
+}
+
+func (l *Limiter) refillLocked() {
+	now := l.opts.Now()
+	elapsed := now.Sub(l.lastAt).Seconds()
+	l.tokens += elapsed * l.opts.Rate
+
+	if l.tokens > float64(l.opts.Burst) {
+		l.tokens = float64(l.opts.Burst)
+	}
+
+	l.lastAt = now
 }
 
 func applyRateLimitDefaults(options RateLimitOptions) RateLimitOptions {
 	defaults := DefaultRateLimitOptions()
 
-	if options.Rate <= 0 {
+	if options.Name == "" {
+		options.Name = defaults.Name
+	}
+	if checkRate(options.Rate) != nil {
 		options.Rate = defaults.Rate
 	}
 	if options.Burst <= 0 {
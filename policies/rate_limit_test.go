@@ -2,6 +2,7 @@ package policies
 
 import (
 	"context"
+	"math"
 	"testing"
 	"time"
 
@@ -148,3 +149,96 @@ func TestRateLimit_Integration(t *testing.T) {
 	}
 }
 
+func TestLimiter_TokensReflectsRefill(t *testing.T) {
+	now := time.Now()
+	l := NewLimiter(RateLimitOptions{
+		Rate:  2,
+		Burst: 4,
+		Now:   func() time.Time { return now },
+	})
+	wrapped := l.Policy()(func(ctx context.Context) (any, error) { return "ok", nil })
+
+	wrapped(context.Background())
+	wrapped(context.Background())
+	if got := l.Tokens(); got != 2 {
+		t.Fatalf("expected 2 tokens, got %v", got)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if got := l.Tokens(); got != 3 {
+		t.Fatalf("expected 3 tokens after refill, got %v", got)
+	}
+	if l.Rate() != 2 || l.Burst() != 4 {
+		t.Fatalf("unexpected rate/burst %v/%v", l.Rate(), l.Burst())
+	}
+}
+
+func TestLimiter_InvalidRateUsesDefault(t *testing.T) {
+	for _, rate := range []float64{-1, math.NaN(), math.Inf(1)} {
+		if got := NewLimiter(RateLimitOptions{Rate: rate}).Rate(); got != DefaultRateLimitOptions().Rate {
+			t.Errorf("rate %v: expected the default rate, got %v", rate, got)
+		}
+	}
+}
+
+func TestLimiter_Retune(t *testing.T) {
+	l := NewLimiter(RateLimitOptions{Rate: 1, Burst: 5})
+	if err := l.Retune(RateLimitOptions{Rate: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if l.Rate() != 3 || l.Burst() != DefaultRateLimitOptions().Burst {
+		t.Fatalf("expected rate 3 and the default burst, got %v and %d", l.Rate(), l.Burst())
+	}
+	if err := l.Retune(RateLimitOptions{Rate: 5, Burst: -1}); err == nil || l.Rate() != 3 {
+		t.Fatalf("expected an invalid burst to change nothing, got %v with rate %v", err, l.Rate())
+	}
+}
+
+func TestLimiter_SetRateAndBurst(t *testing.T) {
+	now := time.Now()
+	l := NewLimiter(RateLimitOptions{
+		Rate:  1,
+		Burst: 10,
+		Now:   func() time.Time { return now },
+	})
+
+	if err := l.SetBurst(2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := l.Tokens(); got != 2 {
+		t.Fatalf("expected tokens capped to new burst, got %v", got)
+	}
+
+	wrapped := l.Policy()(func(ctx context.Context) (any, error) { return "ok", nil })
+	wrapped(context.Background())
+	wrapped(context.Background())
+
+	if err := l.SetRate(4); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now = now.Add(250 * time.Millisecond)
+	if got := l.Tokens(); got != 1 {
+		t.Fatalf("expected refill at new rate, got %v", got)
+	}
+
+	if err := l.SetRate(-1); err == nil {
+		t.Fatal("expected error for negative rate")
+	}
+	if err := l.SetBurst(0); err == nil {
+		t.Fatal("expected error for zero burst")
+	}
+}
+
+func TestLimiter_SetLimitsIsAllOrNothing(t *testing.T) {
+	l := NewLimiter(RateLimitOptions{Rate: 1, Burst: 10})
+
+	if err := l.SetLimits(5, 0); err == nil {
+		t.Fatal("expected error for zero burst")
+	}
+	if l.Rate() != 1 || l.Burst() != 10 {
+		t.Fatalf("expected no change, got %v/%v", l.Rate(), l.Burst())
+	}
+	if err := l.SetLimits(5, 3); err != nil || l.Rate() != 5 || l.Burst() != 3 || l.Tokens() != 3 {
+		t.Fatalf("expected rate 5 burst 3, got %v/%v (%v)", l.Rate(), l.Burst(), err)
+	}
+}
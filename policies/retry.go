@@ -2,6 +2,7 @@ package policies
 
 import (
 	"context"
+	"errors"
 	"math/rand"
 	"time"
 
@@ -17,15 +18,51 @@ const (
 )
 
 type RetryOptions struct {
-	MaxAttempts  int
+	// Name identifies the policy in events. Defaults to "retry".
+	Name string
+
+	// MaxAttempts is the number of calls made at most. Zero or negative values
+	// select the default; NewRetry rejects negative ones instead.
+	MaxAttempts int
+
 	InitialDelay time.Duration
 	MaxDelay     time.Duration
 	Backoff      BackoffStrategy
 	Jitter       bool
 }
 
+// PermanentError marks an error that retrying cannot fix; see Permanent.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent wraps err so that Retry returns it at once instead of retrying. Retry
+// returns the error as it got it, mark included, so that outer retries stop too;
+// errors.Is and errors.As see through the mark. Permanent(nil) is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// retryAfterer is implemented by errors that ask for a minimum delay before the
+// next attempt, such as an HTTP response with a Retry-After header.
+type retryAfterer interface {
+	RetryAfter() time.Duration
+}
+
 func DefaultRetryOptions() RetryOptions {
 	return RetryOptions{
+		Name:         "retry",
 		MaxAttempts:  3,
 		InitialDelay: 100 * time.Millisecond,
 		MaxDelay:     5 * time.Second,
@@ -34,41 +71,87 @@ func DefaultRetryOptions() RetryOptions {
 	}
 }
 
+// Retry calls the next handler up to MaxAttempts times until it succeeds. Errors
+// wrapped with Permanent are returned at once. If an error has a
+// RetryAfter() time.Duration method, the next attempt waits at least that long;
+// when that is longer than MaxDelay, Retry gives up and returns the error.
 func Retry(options RetryOptions) gosentry.Policy {
 	opts := applyDefaults(options)
-	return func(next gosentry.Handler) gosentry.Handler {
+	policy := func(next gosentry.Handler) gosentry.Handler {
 		return func(ctx context.Context) (any, error) {
+			obs := observe(ctx, gosentry.KindRetry, opts.Name)
 			var lastErr error
 
 			for attempt := 0; attempt < opts.MaxAttempts; attempt++ {
 				if ctx.Err() != nil {
+					obs.finishAttempt(attempt, ctx.Err())
 					return nil, ctx.Err()
 				}
 
-				result, err := next(ctx)
+				attemptCtx, span := gosentry.StartSpan(ctx, "gosentry.retry.attempt",
+					gosentry.Attr("gosentry.policy", opts.Name),
+					gosentry.Attr("gosentry.attempt", attempt+1),
+				)
+				result, err := next(attemptCtx)
+				if err != nil {
+					span.RecordError(err)
+				}
+				span.End()
+
 				if err == nil {
+					obs.finishAttempt(attempt+1, nil)
 					return result, nil
 				}
 
 				lastErr = err
+				var permanent *PermanentError
+				if errors.As(err, &permanent) {
+					obs.finishAttempt(attempt+1, err)
+					return nil, err
+				}
 				if attempt == opts.MaxAttempts-1 {
 					break
 				}
 
 				delay := computeDelay(attempt, opts)
+				var ra retryAfterer
+				if errors.As(err, &ra) {
+					if wait := ra.RetryAfter(); wait > opts.MaxDelay {
+						// Waiting that long would exceed what the caller allowed.
+						obs.finishAttempt(attempt+1, err)
+						return nil, err
+					} else if wait > delay {
+						delay = wait
+					}
+				}
+				obs.emit(gosentry.Event{Kind: gosentry.EventRetry, Attempt: attempt + 1, Duration: delay, Err: err})
+
 				timer := time.NewTimer(delay)
 				select {
 				case <-timer.C:
 					timer.Stop()
 				case <-ctx.Done():
 					timer.Stop()
+					obs.finishAttempt(attempt+1, ctx.Err())
 					return nil, ctx.Err()
 				}
 			}
 
+			obs.finishAttempt(opts.MaxAttempts, lastErr)
 			return nil, lastErr
 		}
 	}
+	return gosentry.WithDescriptor(policy, gosentry.Descriptor{
+		Kind: gosentry.KindRetry,
+		Name: opts.Name,
+		Options: []gosentry.Attribute{
+			gosentry.Attr("max_attempts", opts.MaxAttempts),
+			gosentry.Attr("initial_delay", opts.InitialDelay),
+			gosentry.Attr("max_delay", opts.MaxDelay),
+			gosentry.Attr("backoff", string(opts.Backoff)),
+			gosentry.Attr("jitter", opts.Jitter),
+		},
+	})
 }
 
 func computeDelay(attempt int, opts RetryOptions) time.Duration {
@@ -85,7 +168,7 @@ func computeDelay(attempt int, opts RetryOptions) time.Duration {
 		delay = opts.InitialDelay
 	}
 
-	if opts.Jitter {
+	if opts.Jitter && delay/2 > 0 {
 		jitter := time.Duration(rand.Int63n(int64(delay / 2)))
 		delay += jitter
 	}
@@ -100,7 +183,10 @@ func computeDelay(attempt int, opts RetryOptions) time.Duration {
 func applyDefaults(options RetryOptions) RetryOptions {
 	defaults := DefaultRetryOptions()
 
-	if options.MaxAttempts == 0 {
+	if options.Name == "" {
+		options.Name = defaults.Name
+	}
+	if options.MaxAttempts <= 0 {
 		options.MaxAttempts = defaults.MaxAttempts
 	}
 	if options.InitialDelay == 0 {
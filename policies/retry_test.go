@@ -343,3 +343,110 @@ func TestRetry_WithResilienceExecute(t *testing.T) {
 		t.Fatalf("expected 2 attempts, got %d", attempts)
 	}
 }
+
+func TestRetry_NegativeMaxAttemptsUsesDefault(t *testing.T) {
+	attempts := 0
+	handler := func(ctx context.Context) (any, error) {
+		attempts++
+		return nil, errors.New("failed")
+	}
+
+	policy := Retry(RetryOptions{
+		MaxAttempts:  -1,
+		InitialDelay: time.Millisecond,
+		Backoff:      BackoffFixed,
+	})
+
+	_, err := policy(handler)(context.Background())
+	if err == nil {
+		t.Fatal("expected the handler's error, got nil")
+	}
+	if attempts != DefaultRetryOptions().MaxAttempts {
+		t.Fatalf("expected %d attempts, got %d", DefaultRetryOptions().MaxAttempts, attempts)
+	}
+}
+
+func TestRetry_JitterWithTinyDelay(t *testing.T) {
+	policy := Retry(RetryOptions{
+		MaxAttempts:  2,
+		InitialDelay: time.Nanosecond,
+		Backoff:      BackoffFixed,
+		Jitter:       true,
+	})
+
+	attempts := 0
+	policy(func(ctx context.Context) (any, error) {
+		attempts++
+		return nil, errors.New("failed")
+	})(context.Background())
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestRetry_PermanentErrorStopsRetries(t *testing.T) {
+	policy := Retry(RetryOptions{MaxAttempts: 5, InitialDelay: time.Millisecond})
+	errBad := errors.New("bad request")
+
+	attempts := 0
+	_, err := policy(func(ctx context.Context) (any, error) {
+		attempts++
+		return nil, Permanent(errBad)
+	})(context.Background())
+	var perm *PermanentError
+	if !errors.Is(err, errBad) || !errors.As(err, &perm) {
+		t.Fatalf("expected the error with its permanent mark, got %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestRetry_PermanentErrorStopsOuterRetries(t *testing.T) {
+	inner := Retry(RetryOptions{MaxAttempts: 3, InitialDelay: time.Millisecond})
+	outer := Retry(RetryOptions{MaxAttempts: 3, InitialDelay: time.Millisecond})
+
+	attempts := 0
+	outer(inner(func(ctx context.Context) (any, error) {
+		attempts++
+		return nil, Permanent(errors.New("bad request"))
+	}))(context.Background())
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt across both retries, got %d", attempts)
+	}
+}
+
+type retryAfterError time.Duration
+
+func (e retryAfterError) Error() string             { return "busy" }
+func (e retryAfterError) RetryAfter() time.Duration { return time.Duration(e) }
+
+func TestRetry_HonoursRetryAfter(t *testing.T) {
+	policy := Retry(RetryOptions{MaxAttempts: 2, InitialDelay: time.Millisecond, MaxDelay: time.Second, Backoff: BackoffFixed})
+
+	start := time.Now()
+	attempts := 0
+	policy(func(ctx context.Context) (any, error) {
+		attempts++
+		return nil, retryAfterError(30 * time.Millisecond)
+	})(context.Background())
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("expected to wait for Retry-After, returned after %s", elapsed)
+	}
+}
+
+func TestRetry_RetryAfterBeyondMaxDelayGivesUp(t *testing.T) {
+	policy := Retry(RetryOptions{MaxAttempts: 3, InitialDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+
+	attempts := 0
+	_, err := policy(func(ctx context.Context) (any, error) {
+		attempts++
+		return nil, retryAfterError(time.Minute)
+	})(context.Background())
+	if attempts != 1 || err == nil {
+		t.Fatalf("expected to give up after 1 attempt, got %d attempts, err=%v", attempts, err)
+	}
+}
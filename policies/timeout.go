@@ -2,17 +2,80 @@ package policies
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
 	"gosentry"
 )
 
+// TimeoutLayer identifies which deadline fired when a TimeoutError is returned.
+type TimeoutLayer string
+
+const (
+	// TimeoutLayerPolicy means the deadline configured on the timeout policy fired.
+	TimeoutLayerPolicy TimeoutLayer = "policy"
+
+	// TimeoutLayerParent means the caller's context deadline fired before the policy's own.
+	TimeoutLayerParent TimeoutLayer = "parent"
+
+	// TimeoutLayerBudget means the policy shortened its deadline to fit the caller's
+	// remaining budget (minus SafetyMargin) and that shortened deadline fired.
+	TimeoutLayerBudget TimeoutLayer = "budget"
+)
+
+var (
+	// ErrDeadlineBudgetExhausted is returned without calling the handler when the caller's
+	// remaining deadline, minus SafetyMargin, is below MinRemaining.
+	ErrDeadlineBudgetExhausted = errors.New("remaining deadline budget exhausted")
+)
+
+// TimeoutError is returned when a call is abandoned because a deadline was reached.
+// It wraps context.DeadlineExceeded, so errors.Is(err, context.DeadlineExceeded) holds.
+type TimeoutError struct {
+	// Duration is the timeout that applied to the call.
+	Duration time.Duration
+
+	// Elapsed is how long the call ran before it was abandoned.
+	Elapsed time.Duration
+
+	// Layer reports whether the policy's deadline or the caller's deadline fired.
+	Layer TimeoutLayer
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timeout (%s) after %s: configured %s", e.Layer, e.Elapsed, e.Duration)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
 type TimeoutOptions struct {
+	// Name identifies the policy in events. Defaults to "timeout".
+	Name string
+
 	Duration time.Duration
+
+	// Adaptive, if set, derives the timeout from the latency of successful calls.
+	// Duration is used during warm-up and as the default upper bound.
+	Adaptive *AdaptiveTimeoutOptions
+
+	// SafetyMargin is reserved from the caller's remaining deadline: the policy uses
+	// min(Duration, remaining-SafetyMargin) so the caller has time left to react.
+	SafetyMargin time.Duration
+
+	// MinRemaining makes the policy fail fast with ErrDeadlineBudgetExhausted when the
+	// caller's remaining deadline, minus SafetyMargin, is below it.
+	MinRemaining time.Duration
+
+	// OnTimeout is called whenever the policy returns a TimeoutError.
+	OnTimeout func(err *TimeoutError)
 }
 
 func DefaultTimeoutOptions() TimeoutOptions {
 	return TimeoutOptions{
+		Name:     "timeout",
 		Duration: 5 * time.Second,
 	}
 }
@@ -22,16 +85,48 @@ func Timeout(options TimeoutOptions) gosentry.Policy {
 
 	// If disabled, return a no-op policy.
 	if opts.Duration < 0 {
-		return func(next gosentry.Handler) gosentry.Handler { return next }
+		return gosentry.WithDescriptor(func(next gosentry.Handler) gosentry.Handler { return next }, gosentry.Descriptor{
+			Kind:    gosentry.KindTimeout,
+			Name:    opts.Name,
+			Options: []gosentry.Attribute{gosentry.Attr("disabled", true)},
+		})
 	}
 
-	return func(next gosentry.Handler) gosentry.Handler {
+	var adaptive *adaptiveTimeout
+	if opts.Adaptive != nil {
+		adaptive = newAdaptiveTimeout(applyAdaptiveTimeoutDefaults(*opts.Adaptive, opts.Duration), opts.Duration)
+	}
+	budgeted := opts.SafetyMargin > 0 || opts.MinRemaining > 0
+
+	policy := func(next gosentry.Handler) gosentry.Handler {
 		return func(ctx context.Context) (any, error) {
 			if ctx.Err() != nil {
 				return nil, ctx.Err()
 			}
 
-			timeoutCtx, cancel := context.WithTimeout(ctx, opts.Duration)
+			duration := opts.Duration
+			if adaptive != nil {
+				duration = adaptive.timeout()
+			}
+
+			obs := observe(ctx, gosentry.KindTimeout, opts.Name)
+			layer := TimeoutLayerPolicy
+			if budgeted {
+				if deadline, ok := ctx.Deadline(); ok {
+					remaining := time.Until(deadline) - opts.SafetyMargin
+					if remaining <= 0 || remaining < opts.MinRemaining {
+						obs.reject("deadline_budget", ErrDeadlineBudgetExhausted)
+						return nil, ErrDeadlineBudgetExhausted
+					}
+					if remaining < duration {
+						duration = remaining
+						layer = TimeoutLayerBudget
+					}
+				}
+			}
+
+			start := time.Now()
+			timeoutCtx, cancel := context.WithTimeout(ctx, duration)
 			defer cancel()
 
 			type outcome struct {
@@ -39,6 +134,17 @@ func Timeout(options TimeoutOptions) gosentry.Policy {
 				err    error
 			}
 
+			timedOut := func() error {
+				err := timeoutError(obs, opts, duration, layer, start)
+				var terr *TimeoutError
+				if adaptive != nil && errors.As(err, &terr) && terr.Layer == TimeoutLayerPolicy {
+					// The call took at least as long as the timeout. Recording it lets
+					// a timeout that shrank too far grow back once calls slow down.
+					adaptive.observe(max(terr.Elapsed, duration))
+				}
+				return err
+			}
+
 			done := make(chan outcome, 1)
 			go func() {
 				res, err := next(timeoutCtx)
@@ -47,17 +153,77 @@ func Timeout(options TimeoutOptions) gosentry.Policy {
 
 			select {
 			case out := <-done:
+				// A handler that honours its context may return the deadline error
+				// itself before we observe timeoutCtx.Done(); report it the same way.
+				if out.err != nil && errors.Is(out.err, context.DeadlineExceeded) && timeoutCtx.Err() != nil {
+					return nil, timedOut()
+				}
+				if out.err == nil && adaptive != nil {
+					adaptive.observe(time.Since(start))
+				}
+				obs.finish(out.err)
 				return out.result, out.err
 			case <-timeoutCtx.Done():
-				return nil, timeoutCtx.Err()
+				return nil, timedOut()
 			}
 		}
 	}
+	return gosentry.WithDescriptor(policy, gosentry.Descriptor{Kind: gosentry.KindTimeout, Name: opts.Name, Options: timeoutAttrs(opts)})
+}
+
+// timeoutAttrs lists the effective options of a timeout policy, leaving out the
+// optional features it does not use.
+func timeoutAttrs(opts TimeoutOptions) []gosentry.Attribute {
+	attrs := []gosentry.Attribute{gosentry.Attr("duration", opts.Duration)}
+	if opts.SafetyMargin > 0 {
+		attrs = append(attrs, gosentry.Attr("safety_margin", opts.SafetyMargin))
+	}
+	if opts.MinRemaining > 0 {
+		attrs = append(attrs, gosentry.Attr("min_remaining", opts.MinRemaining))
+	}
+	if opts.Adaptive != nil {
+		a := applyAdaptiveTimeoutDefaults(*opts.Adaptive, opts.Duration)
+		attrs = append(attrs,
+			gosentry.Attr("adaptive.percentile", a.Percentile),
+			gosentry.Attr("adaptive.multiplier", a.Multiplier),
+			gosentry.Attr("adaptive.min", a.Min),
+			gosentry.Attr("adaptive.max", a.Max),
+			gosentry.Attr("adaptive.warmup_samples", a.WarmupSamples),
+			gosentry.Attr("adaptive.window", a.Window),
+		)
+	}
+	return attrs
+}
+
+// timeoutError builds the error returned once timeoutCtx is done. Cancellation of
+// the parent context is passed through untouched; only deadlines become TimeoutErrors.
+func timeoutError(obs observation, opts TimeoutOptions, duration time.Duration, layer TimeoutLayer, start time.Time) error {
+	if err := obs.ctx.Err(); err != nil {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			obs.finish(err)
+			return err
+		}
+		layer = TimeoutLayerParent
+	}
+
+	terr := &TimeoutError{
+		Duration: duration,
+		Elapsed:  time.Since(start),
+		Layer:    layer,
+	}
+	if opts.OnTimeout != nil {
+		opts.OnTimeout(terr)
+	}
+	obs.emit(gosentry.Event{Kind: gosentry.EventTimeout, Duration: terr.Elapsed, Err: terr})
+	return terr
 }
 
 func applyTimeoutDefaults(options TimeoutOptions) TimeoutOptions {
 	defaults := DefaultTimeoutOptions()
 
+	if options.Name == "" {
+		options.Name = defaults.Name
+	}
 	if options.Duration == 0 {
 		options.Duration = defaults.Duration
 	}
@@ -90,3 +90,144 @@ func TestTimeout_DisabledWhenDurationNegative(t *testing.T) {
 		t.Fatalf("expected handler to be called")
 	}
 }
+
+func TestTimeout_ReturnsTypedErrorForPolicyDeadline(t *testing.T) {
+	var reported *TimeoutError
+	p := Timeout(TimeoutOptions{
+		Duration: 10 * time.Millisecond,
+		OnTimeout: func(err *TimeoutError) {
+			reported = err
+		},
+	})
+	h := p(func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	_, err := h(context.Background())
+
+	var terr *TimeoutError
+	if !errors.As(err, &terr) {
+		t.Fatalf("expected *TimeoutError, got %T: %v", err, err)
+	}
+	if terr.Layer != TimeoutLayerPolicy {
+		t.Fatalf("expected policy layer, got %q", terr.Layer)
+	}
+	if terr.Duration != 10*time.Millisecond {
+		t.Fatalf("expected configured duration 10ms, got %v", terr.Duration)
+	}
+	if terr.Elapsed < 10*time.Millisecond {
+		t.Fatalf("expected elapsed >= 10ms, got %v", terr.Elapsed)
+	}
+	if reported != terr {
+		t.Fatalf("expected OnTimeout to receive the returned error")
+	}
+}
+
+func TestTimeout_ReportsParentDeadline(t *testing.T) {
+	p := Timeout(TimeoutOptions{Duration: time.Second})
+	h := p(func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := h(ctx)
+
+	var terr *TimeoutError
+	if !errors.As(err, &terr) {
+		t.Fatalf("expected *TimeoutError, got %T: %v", err, err)
+	}
+	if terr.Layer != TimeoutLayerParent {
+		t.Fatalf("expected parent layer, got %q", terr.Layer)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected error to wrap DeadlineExceeded")
+	}
+}
+
+func TestTimeout_ParentCancellationIsNotATimeout(t *testing.T) {
+	called := false
+	p := Timeout(TimeoutOptions{
+		Duration:  time.Second,
+		OnTimeout: func(*TimeoutError) { called = true },
+	})
+	h := p(func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := h(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if called {
+		t.Fatalf("expected OnTimeout not to be called on cancellation")
+	}
+}
+
+func TestTimeout_BudgetShortensDurationByMargin(t *testing.T) {
+	p := Timeout(TimeoutOptions{
+		Duration:     time.Second,
+		SafetyMargin: 50 * time.Millisecond,
+	})
+
+	remainingCh := make(chan time.Duration, 1)
+	h := p(func(ctx context.Context) (any, error) {
+		deadline, _ := ctx.Deadline()
+		remainingCh <- time.Until(deadline)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := h(ctx)
+
+	var terr *TimeoutError
+	if !errors.As(err, &terr) {
+		t.Fatalf("expected *TimeoutError, got %v", err)
+	}
+	if terr.Layer != TimeoutLayerBudget {
+		t.Fatalf("expected budget layer, got %q", terr.Layer)
+	}
+	if remaining := <-remainingCh; remaining > 50*time.Millisecond {
+		t.Fatalf("expected handler deadline to leave the safety margin, got %v remaining", remaining)
+	}
+	if ctx.Err() != nil {
+		t.Fatalf("expected caller context to still be alive, got %v", ctx.Err())
+	}
+}
+
+func TestTimeout_BudgetFailsFastBelowMinRemaining(t *testing.T) {
+	called := false
+	p := Timeout(TimeoutOptions{
+		Duration:     time.Second,
+		SafetyMargin: 20 * time.Millisecond,
+		MinRemaining: 50 * time.Millisecond,
+	})
+	h := p(func(ctx context.Context) (any, error) {
+		called = true
+		return "ok", nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Millisecond)
+	defer cancel()
+
+	_, err := h(ctx)
+	if !errors.Is(err, ErrDeadlineBudgetExhausted) {
+		t.Fatalf("expected ErrDeadlineBudgetExhausted, got %v", err)
+	}
+	if called {
+		t.Fatal("expected handler not to be called")
+	}
+}
@@ -0,0 +1,299 @@
+// Package policies provides the resilience policies of gosentry.
+//
+// Every policy kind follows the same constructor convention:
+//
+//   - A function named after the kind (Retry, CircuitBreaker, Timeout, RateLimit,
+//     Bulkhead, Failover, LoadBalancer, Coalesce, Cache) returns its policy.
+//   - Kinds with live state also have a handle type (Breaker, Limiter,
+//     BulkheadPool, FailoverGroup, Balancer, Coalescer, ResultCache) built by
+//     New followed by the type name; every policy returned by its Policy method
+//     shares that state.
+//   - These constructors do not validate options; zero fields select the
+//     defaults. New followed by the kind name (NewRetry, NewCircuitBreaker, ...)
+//     is the checked form: it returns the handle, or the policy for kinds without
+//     one, or the error from the options' Validate method.
+package policies
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gosentry"
+)
+
+// OptionError reports an invalid field of an options struct.
+type OptionError struct {
+	// Field is the qualified field name, e.g. "RetryOptions.MaxAttempts".
+	Field string
+
+	Value any
+	Msg   string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("policies: %s %s, got %v", e.Field, e.Msg, e.Value)
+}
+
+// validator collects the problems found in one options struct.
+type validator struct {
+	typ  string
+	errs []error
+}
+
+func (v *validator) check(ok bool, field string, value any, msg string) {
+	if !ok {
+		v.errs = append(v.errs, &OptionError{Field: v.typ + "." + field, Value: value, Msg: msg})
+	}
+}
+
+func (v *validator) nonNegative(field string, d time.Duration) {
+	v.check(d >= 0, field, d, "must not be negative")
+}
+
+// uniqueNames checks that names is a non-empty list of non-empty, distinct names.
+func (v *validator) uniqueNames(field string, names []string) {
+	v.check(len(names) > 0, field, names, "must not be empty")
+	unique := true
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		unique = unique && name != "" && !seen[name]
+		seen[name] = true
+	}
+	v.check(unique, field, names, "must not contain empty or duplicate names")
+}
+
+func (v *validator) err() error {
+	return errors.Join(v.errs...)
+}
+
+// Validate reports every field of o that the retry policy cannot honour. Zero
+// fields are valid: they select the defaults. The returned error joins one
+// *OptionError per problem.
+func (o RetryOptions) Validate() error {
+	v := &validator{typ: "RetryOptions"}
+	v.check(o.MaxAttempts >= 0, "MaxAttempts", o.MaxAttempts, "must not be negative")
+	v.nonNegative("InitialDelay", o.InitialDelay)
+	v.nonNegative("MaxDelay", o.MaxDelay)
+	switch o.Backoff {
+	case "", BackoffFixed, BackoffLinear, BackoffExponential:
+	default:
+		v.check(false, "Backoff", o.Backoff, fmt.Sprintf("must be %q, %q or %q", BackoffFixed, BackoffLinear, BackoffExponential))
+	}
+
+	if o.InitialDelay >= 0 && o.MaxDelay >= 0 {
+		eff := applyDefaults(o)
+		v.check(eff.MaxDelay >= eff.InitialDelay, "MaxDelay", eff.MaxDelay,
+			fmt.Sprintf("must be at least InitialDelay (%s)", eff.InitialDelay))
+	}
+	return v.err()
+}
+
+// NewRetry is like Retry but returns an error instead of a policy if options fail
+// Validate.
+func NewRetry(options RetryOptions) (gosentry.Policy, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return Retry(options), nil
+}
+
+// Validate reports every field of o that the circuit breaker cannot honour. Zero
+// fields are valid: they select the defaults.
+func (o CircuitBreakerOptions) Validate() error {
+	v := &validator{typ: "CircuitBreakerOptions"}
+	o.validate(v, "")
+	return v.err()
+}
+
+func (o CircuitBreakerOptions) validate(v *validator, prefix string) {
+	v.check(o.FailureThreshold >= 0, prefix+"FailureThreshold", o.FailureThreshold, "must not be negative")
+	v.check(o.SuccessThreshold >= 0, prefix+"SuccessThreshold", o.SuccessThreshold, "must not be negative")
+	v.nonNegative(prefix+"OpenTimeout", o.OpenTimeout)
+}
+
+// NewCircuitBreaker is like NewBreaker but returns an error if options fail Validate.
+func NewCircuitBreaker(options CircuitBreakerOptions) (*Breaker, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return NewBreaker(options), nil
+}
+
+// Validate reports every field of o that the timeout policy cannot honour. Zero
+// fields are valid: they select the defaults, and a negative Duration disables the
+// policy.
+func (o TimeoutOptions) Validate() error {
+	v := &validator{typ: "TimeoutOptions"}
+	v.nonNegative("SafetyMargin", o.SafetyMargin)
+	v.nonNegative("MinRemaining", o.MinRemaining)
+	if o.Adaptive != nil {
+		o.Adaptive.validate(v, "Adaptive.")
+	}
+	return v.err()
+}
+
+// Validate reports every field of o that the adaptive timeout cannot honour. Zero
+// fields are valid: they select the defaults.
+func (o AdaptiveTimeoutOptions) Validate() error {
+	v := &validator{typ: "AdaptiveTimeoutOptions"}
+	o.validate(v, "")
+	return v.err()
+}
+
+func (o AdaptiveTimeoutOptions) validate(v *validator, prefix string) {
+	v.check(o.Percentile >= 0 && o.Percentile <= 1, prefix+"Percentile", o.Percentile, "must be in (0, 1]")
+	v.check(o.Multiplier >= 0 && !math.IsInf(o.Multiplier, 0), prefix+"Multiplier", o.Multiplier, "must be a positive number")
+	v.nonNegative(prefix+"Min", o.Min)
+	v.nonNegative(prefix+"Max", o.Max)
+	if o.Min > 0 && o.Max > 0 {
+		v.check(o.Max >= o.Min, prefix+"Max", o.Max, fmt.Sprintf("must be at least %sMin (%s)", prefix, o.Min))
+	}
+	v.check(o.WarmupSamples >= 0, prefix+"WarmupSamples", o.WarmupSamples, "must not be negative")
+	v.check(o.Window >= 0, prefix+"Window", o.Window, "must not be negative")
+}
+
+// NewTimeout is like Timeout but returns an error instead of a policy if options
+// fail Validate.
+func NewTimeout(options TimeoutOptions) (gosentry.Policy, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return Timeout(options), nil
+}
+
+// Validate reports every field of o that the rate limiter cannot honour. Zero
+// fields are valid: they select the defaults.
+func (o RateLimitOptions) Validate() error {
+	v := &validator{typ: "RateLimitOptions"}
+	v.check(o.Rate >= 0 && !math.IsInf(o.Rate, 0), "Rate", o.Rate, "must be a positive number")
+	v.check(o.Burst >= 0, "Burst", o.Burst, "must not be negative")
+	return v.err()
+}
+
+// NewRateLimit is like NewLimiter but returns an error if options fail Validate.
+func NewRateLimit(options RateLimitOptions) (*Limiter, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return NewLimiter(options), nil
+}
+
+// Validate reports every field of o that the bulkhead cannot honour. Zero fields
+// are valid: they select the defaults.
+func (o BulkheadOptions) Validate() error {
+	v := &validator{typ: "BulkheadOptions"}
+	v.check(o.MaxConcurrent >= 0, "MaxConcurrent", o.MaxConcurrent, "must not be negative")
+	v.check(o.MaxWaiting >= 0, "MaxWaiting", o.MaxWaiting, "must not be negative")
+	v.nonNegative("MaxWait", o.MaxWait)
+	return v.err()
+}
+
+// NewBulkhead is like NewBulkheadPool but returns an error if options fail
+// Validate.
+func NewBulkhead(options BulkheadOptions) (*BulkheadPool, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return NewBulkheadPool(options), nil
+}
+
+// Validate reports every field of o that the failover policy cannot honour:
+// Endpoints must be non-empty and unique, and Breaker, if set, valid.
+func (o FailoverOptions) Validate() error {
+	v := &validator{typ: "FailoverOptions"}
+	v.uniqueNames("Endpoints", o.Endpoints)
+	if o.Breaker != nil {
+		o.Breaker.validate(v, "Breaker.")
+	}
+	return v.err()
+}
+
+// NewFailover is like NewFailoverGroup but returns an error if options fail
+// Validate.
+func NewFailover(options FailoverOptions) (*FailoverGroup, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return NewFailoverGroup(options), nil
+}
+
+// Validate reports every field of o that the balancer cannot honour: Backends
+// must be non-empty and unique, and Outlier, if set, valid.
+func (o BalancerOptions) Validate() error {
+	v := &validator{typ: "BalancerOptions"}
+	v.uniqueNames("Backends", o.Backends)
+	switch o.Strategy {
+	case "", BalanceRoundRobin, BalanceRandom, BalanceLeastInFlight, BalancePowerOfTwo:
+	default:
+		v.check(false, "Strategy", o.Strategy, fmt.Sprintf("must be %q, %q, %q or %q",
+			BalanceRoundRobin, BalanceRandom, BalanceLeastInFlight, BalancePowerOfTwo))
+	}
+	v.nonNegative("LatencyDecay", o.LatencyDecay)
+	v.nonNegative("FailurePenalty", o.FailurePenalty)
+	if o.Outlier != nil {
+		o.Outlier.validate(v, "Outlier.")
+	}
+	return v.err()
+}
+
+// NewLoadBalancer is like NewBalancer but returns an error if options fail
+// Validate.
+func NewLoadBalancer(options BalancerOptions) (*Balancer, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return NewBalancer(options), nil
+}
+
+// Validate reports every field of o that outlier detection cannot honour. Zero
+// fields are valid: they select the defaults.
+func (o OutlierOptions) Validate() error {
+	v := &validator{typ: "OutlierOptions"}
+	o.validate(v, "")
+	return v.err()
+}
+
+func (o OutlierOptions) validate(v *validator, prefix string) {
+	v.check(o.ErrorRate >= 0 && o.ErrorRate <= 1, prefix+"ErrorRate", o.ErrorRate, "must be in [0, 1]")
+	v.check(o.MinRequests >= 0, prefix+"MinRequests", o.MinRequests, "must not be negative")
+	v.nonNegative(prefix+"Interval", o.Interval)
+	v.nonNegative(prefix+"BaseEjectionTime", o.BaseEjectionTime)
+	v.nonNegative(prefix+"MaxEjectionTime", o.MaxEjectionTime)
+	v.check(o.MaxEjectionPercent >= 0 && o.MaxEjectionPercent <= 100, prefix+"MaxEjectionPercent", o.MaxEjectionPercent, "must be in [0, 100]")
+}
+
+// Validate reports every field of o that the cache policy cannot honour. Zero
+// fields are valid: they select the defaults.
+func (o CacheOptions) Validate() error {
+	v := &validator{typ: "CacheOptions"}
+	v.nonNegative("TTL", o.TTL)
+	v.nonNegative("StaleWhileRevalidate", o.StaleWhileRevalidate)
+	v.nonNegative("StaleIfError", o.StaleIfError)
+	v.nonNegative("NegativeTTL", o.NegativeTTL)
+	return v.err()
+}
+
+// NewCache is like NewResultCache but returns an error if options fail Validate.
+func NewCache(options CacheOptions) (*ResultCache, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return NewResultCache(options), nil
+}
+
+// Validate reports every field of o that the coalesce policy cannot honour. Every
+// CoalesceOptions is currently valid: zero fields select the defaults.
+func (o CoalesceOptions) Validate() error {
+	v := &validator{typ: "CoalesceOptions"}
+	return v.err()
+}
+
+// NewCoalesce is like NewCoalescer but returns an error if options fail Validate.
+func NewCoalesce(options CoalesceOptions) (*Coalescer, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return NewCoalescer(options), nil
+}
@@ -0,0 +1,165 @@
+package policies
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func optionErrors(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("expected joined errors, got %T", err)
+	}
+	var fields []string
+	for _, e := range joined.Unwrap() {
+		var oerr *OptionError
+		if !errors.As(e, &oerr) {
+			t.Fatalf("expected *OptionError, got %T", e)
+		}
+		fields = append(fields, oerr.Field)
+	}
+	return fields
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want []string
+	}{
+		{"retry defaults", RetryOptions{}.Validate(), nil},
+		{"retry default options", DefaultRetryOptions().Validate(), nil},
+		{
+			"retry invalid",
+			RetryOptions{MaxAttempts: -1, InitialDelay: -time.Second, Backoff: "random"}.Validate(),
+			[]string{"RetryOptions.MaxAttempts", "RetryOptions.InitialDelay", "RetryOptions.Backoff"},
+		},
+		{
+			"retry max below initial",
+			RetryOptions{InitialDelay: time.Second, MaxDelay: time.Millisecond}.Validate(),
+			[]string{"RetryOptions.MaxDelay"},
+		},
+		{
+			"retry initial above default max",
+			RetryOptions{InitialDelay: time.Minute}.Validate(),
+			[]string{"RetryOptions.MaxDelay"},
+		},
+		{"circuit breaker defaults", CircuitBreakerOptions{}.Validate(), nil},
+		{
+			"circuit breaker invalid",
+			CircuitBreakerOptions{FailureThreshold: -1, SuccessThreshold: -2, OpenTimeout: -time.Second}.Validate(),
+			[]string{"CircuitBreakerOptions.FailureThreshold", "CircuitBreakerOptions.SuccessThreshold", "CircuitBreakerOptions.OpenTimeout"},
+		},
+		{"timeout disabled", TimeoutOptions{Duration: -1}.Validate(), nil},
+		{
+			"timeout invalid",
+			TimeoutOptions{SafetyMargin: -1, Adaptive: &AdaptiveTimeoutOptions{Percentile: 2, Min: time.Second, Max: time.Millisecond}}.Validate(),
+			[]string{"TimeoutOptions.SafetyMargin", "TimeoutOptions.Adaptive.Percentile", "TimeoutOptions.Adaptive.Max"},
+		},
+		{
+			"adaptive invalid",
+			AdaptiveTimeoutOptions{Multiplier: math.Inf(1), Window: -1}.Validate(),
+			[]string{"AdaptiveTimeoutOptions.Multiplier", "AdaptiveTimeoutOptions.Window"},
+		},
+		{"rate limit defaults", RateLimitOptions{}.Validate(), nil},
+		{
+			"rate limit invalid",
+			RateLimitOptions{Rate: math.NaN(), Burst: -1}.Validate(),
+			[]string{"RateLimitOptions.Rate", "RateLimitOptions.Burst"},
+		},
+		{"bulkhead defaults", BulkheadOptions{}.Validate(), nil},
+		{
+			"bulkhead invalid",
+			BulkheadOptions{MaxConcurrent: -1, MaxWaiting: -1, MaxWait: -time.Second}.Validate(),
+			[]string{"BulkheadOptions.MaxConcurrent", "BulkheadOptions.MaxWaiting", "BulkheadOptions.MaxWait"},
+		},
+		{"failover valid", FailoverOptions{Endpoints: []string{"eu", "us"}}.Validate(), nil},
+		{
+			"failover invalid",
+			FailoverOptions{Endpoints: []string{"eu", "eu"}, Breaker: &CircuitBreakerOptions{FailureThreshold: -1}}.Validate(),
+			[]string{"FailoverOptions.Endpoints", "FailoverOptions.Breaker.FailureThreshold"},
+		},
+		{"failover no endpoints", FailoverOptions{}.Validate(), []string{"FailoverOptions.Endpoints"}},
+		{"cache defaults", CacheOptions{}.Validate(), nil},
+		{
+			"cache invalid",
+			CacheOptions{TTL: -time.Second, StaleIfError: -time.Second}.Validate(),
+			[]string{"CacheOptions.TTL", "CacheOptions.StaleIfError"},
+		},
+		{"balancer valid", BalancerOptions{Backends: []string{"a", "b"}, Outlier: &OutlierOptions{}}.Validate(), nil},
+		{
+			"balancer invalid",
+			BalancerOptions{Backends: []string{"a", ""}, Strategy: "fastest", Outlier: &OutlierOptions{ErrorRate: 2, MaxEjectionPercent: 101}}.Validate(),
+			[]string{"BalancerOptions.Backends", "BalancerOptions.Strategy", "BalancerOptions.Outlier.ErrorRate", "BalancerOptions.Outlier.MaxEjectionPercent"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := optionErrors(t, tt.err)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("expected %v, got %v (%v)", tt.want, got, tt.err)
+			}
+		})
+	}
+}
+
+func TestOptionError_Message(t *testing.T) {
+	err := RateLimitOptions{Rate: -1}.Validate()
+	want := "policies: RateLimitOptions.Rate must be a positive number, got -1"
+	if err == nil || err.Error() != want {
+		t.Fatalf("expected %q, got %v", want, err)
+	}
+
+	err = RetryOptions{InitialDelay: time.Second, MaxDelay: time.Millisecond}.Validate()
+	want = "policies: RetryOptions.MaxDelay must be at least InitialDelay (1s), got 1ms"
+	if err == nil || err.Error() != want {
+		t.Fatalf("expected %q, got %v", want, err)
+	}
+}
+
+func TestCheckedConstructors(t *testing.T) {
+	if _, err := NewRetry(RetryOptions{MaxAttempts: -1}); err == nil {
+		t.Error("expected NewRetry to reject negative MaxAttempts")
+	}
+	if p, err := NewRetry(RetryOptions{MaxAttempts: 2}); err != nil || p == nil {
+		t.Errorf("expected a policy, got %v", err)
+	}
+	if _, err := NewCircuitBreaker(CircuitBreakerOptions{OpenTimeout: -1}); err == nil {
+		t.Error("expected NewCircuitBreaker to reject negative OpenTimeout")
+	}
+	if _, err := NewTimeout(TimeoutOptions{MinRemaining: -1}); err == nil {
+		t.Error("expected NewTimeout to reject negative MinRemaining")
+	}
+	if _, err := NewRateLimit(RateLimitOptions{Rate: -1}); err == nil {
+		t.Error("expected NewRateLimit to reject negative Rate")
+	}
+	if l, err := NewRateLimit(RateLimitOptions{Rate: 5, Burst: 2}); err != nil || l.Burst() != 2 {
+		t.Errorf("expected a limiter, got %v", err)
+	}
+	if _, err := NewBulkhead(BulkheadOptions{MaxWaiting: -1}); err == nil {
+		t.Error("expected NewBulkhead to reject negative MaxWaiting")
+	}
+	if b, err := NewBulkhead(BulkheadOptions{MaxConcurrent: 3}); err != nil || b.Capacity() != 3 {
+		t.Errorf("expected a bulkhead pool, got %v", err)
+	}
+	if _, err := NewFailover(FailoverOptions{}); err == nil {
+		t.Error("expected NewFailover to reject empty Endpoints")
+	}
+	if _, err := NewLoadBalancer(BalancerOptions{Backends: []string{"a"}, Strategy: "fastest"}); err == nil {
+		t.Error("expected NewLoadBalancer to reject an unknown Strategy")
+	}
+	if _, err := NewCache(CacheOptions{TTL: -1}); err == nil {
+		t.Error("expected NewCache to reject negative TTL")
+	}
+	if c, err := NewCoalesce(CoalesceOptions{Name: "profiles"}); err != nil || c.Name() != "profiles" {
+		t.Errorf("expected a coalescer, got %v", err)
+	}
+}
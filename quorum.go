@@ -0,0 +1,133 @@
+package gosentry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrQuorumDecided is the error of replicas still running when a quorum was
+// reached or became impossible; they are cancelled.
+var ErrQuorumDecided = errors.New("gosentry: replica cancelled after the quorum was decided")
+
+// Replica is one target of ExecuteQuorum.
+type Replica struct {
+	// Name identifies the replica in errors.
+	Name string
+
+	// Pipeline protects calls to this replica. Nil applies no policies.
+	Pipeline *Pipeline
+
+	Handler Handler
+}
+
+func (r Replica) execute(ctx context.Context) (any, error) {
+	if r.Pipeline == nil {
+		return Execute(ctx, r.Handler)
+	}
+	return r.Pipeline.Execute(ctx, r.Handler)
+}
+
+type QuorumOptions struct {
+	// Required is the number of successes needed. Defaults to a majority of the
+	// replicas; 1 returns at the first success.
+	Required int
+}
+
+func DefaultQuorumOptions() QuorumOptions {
+	return QuorumOptions{}
+}
+
+func applyQuorumDefaults(options QuorumOptions, replicas int) QuorumOptions {
+	if options.Required <= 0 {
+		options.Required = replicas/2 + 1
+	}
+	return options
+}
+
+// ReplicaError is the failure of one replica.
+type ReplicaError struct {
+	Replica string
+	Err     error
+}
+
+// QuorumError is returned when too many replicas failed for the quorum to be
+// reached.
+type QuorumError struct {
+	Required  int
+	Successes int
+	Replicas  int
+
+	// Failures are in the order the replicas failed.
+	Failures []ReplicaError
+}
+
+func (e *QuorumError) Error() string {
+	parts := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		parts[i] = f.Replica + ": " + f.Err.Error()
+	}
+	msg := fmt.Sprintf("gosentry: quorum of %d not reached, %d of %d replicas succeeded", e.Required, e.Successes, e.Replicas)
+	if len(parts) > 0 {
+		msg += ": " + strings.Join(parts, "; ")
+	}
+	return msg
+}
+
+// Unwrap returns the error of every failed replica.
+func (e *QuorumError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// ExecuteQuorum runs every replica in parallel, each through its own pipeline, and
+// returns as soon as Required of them have succeeded, or with a *QuorumError as soon
+// as that is no longer possible. Replicas still running are then cancelled and their
+// results report ErrQuorumDecided. Results are in the order of replicas.
+func ExecuteQuorum(ctx context.Context, replicas []Replica, options QuorumOptions) ([]Result, error) {
+	opts := applyQuorumDefaults(options, len(replicas))
+	results := make([]Result, len(replicas))
+	for i := range results {
+		results[i].Err = ErrQuorumDecided
+	}
+	qerr := &QuorumError{Required: opts.Required, Replicas: len(replicas)}
+	if opts.Required > len(replicas) {
+		return results, qerr
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		i int
+		Result
+	}
+	outcomes := make(chan outcome, len(replicas))
+	for i, r := range replicas {
+		go func() {
+			value, err := r.execute(ctx)
+			outcomes <- outcome{i: i, Result: Result{Value: value, Err: err}}
+		}()
+	}
+
+	for range replicas {
+		o := <-outcomes
+		results[o.i] = o.Result
+		if o.Err == nil {
+			qerr.Successes++
+			if qerr.Successes == opts.Required {
+				return results, nil
+			}
+			continue
+		}
+		qerr.Failures = append(qerr.Failures, ReplicaError{Replica: replicas[o.i].Name, Err: o.Err})
+		if len(replicas)-len(qerr.Failures) < opts.Required {
+			return results, qerr
+		}
+	}
+	return results, qerr
+}
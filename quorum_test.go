@@ -0,0 +1,92 @@
+package gosentry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// hanging blocks until cancelled.
+func hanging(ctx context.Context) (any, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestExecuteQuorum_MajorityCancelsRest(t *testing.T) {
+	cancelled := make(chan struct{})
+	replicas := []Replica{
+		{Name: "a", Handler: value("a")},
+		{Name: "b", Handler: func(ctx context.Context) (any, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return nil, ctx.Err()
+		}},
+		{Name: "c", Handler: value("c")},
+	}
+
+	results, err := ExecuteQuorum(context.Background(), replicas, DefaultQuorumOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Value != "a" || results[2].Value != "c" || results[1].Err != ErrQuorumDecided {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("expected the outstanding replica to be cancelled")
+	}
+}
+
+func TestExecuteQuorum_FirstSuccess(t *testing.T) {
+	replicas := []Replica{
+		{Name: "slow", Handler: hanging},
+		{Name: "fast", Handler: value(1)},
+	}
+
+	results, err := ExecuteQuorum(context.Background(), replicas, QuorumOptions{Required: 1})
+	if err != nil || results[1].Value != 1 {
+		t.Fatalf("unexpected results %+v, %v", results, err)
+	}
+}
+
+func TestExecuteQuorum_FailsOnceImpossible(t *testing.T) {
+	errDown := errors.New("down")
+	replicas := []Replica{
+		{Name: "a", Handler: failing(errDown)},
+		{Name: "b", Handler: failing(errDown)},
+		{Name: "c", Handler: hanging},
+	}
+
+	_, err := ExecuteQuorum(context.Background(), replicas, QuorumOptions{Required: 2})
+	var qerr *QuorumError
+	if !errors.As(err, &qerr) {
+		t.Fatalf("expected a *QuorumError, got %v", err)
+	}
+	if qerr.Successes != 0 || len(qerr.Failures) != 2 || !errors.Is(err, errDown) {
+		t.Fatalf("unexpected error %+v", qerr)
+	}
+}
+
+func TestExecuteQuorum_RequiredAboveReplicas(t *testing.T) {
+	_, err := ExecuteQuorum(context.Background(), []Replica{{Name: "a", Handler: value(1)}}, QuorumOptions{Required: 2})
+	var qerr *QuorumError
+	if !errors.As(err, &qerr) {
+		t.Fatalf("expected a *QuorumError, got %v", err)
+	}
+}
+
+func TestExecuteQuorum_UsesReplicaPipelines(t *testing.T) {
+	rec := &recorder{}
+	replicas := []Replica{
+		{Name: "a", Pipeline: NewPipeline("replica-a").WithObserver(rec), Handler: value(1)},
+	}
+
+	if _, err := ExecuteQuorum(context.Background(), replicas, DefaultQuorumOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.events) != 1 || rec.events[0].Pipeline != "replica-a" {
+		t.Fatalf("expected the replica's pipeline to run, got %+v", rec.events)
+	}
+}
@@ -0,0 +1,135 @@
+package gosentry
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Attribute is a key/value pair attached to spans and span events.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+func Attr(key string, value any) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Tracer starts spans. Its shape mirrors OpenTelemetry's trace.Tracer so an adapter
+// is a thin wrapper.
+type Tracer interface {
+	// Start creates a span that is a child of the span in ctx, if any, and returns a
+	// context carrying the new span.
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is a single traced operation.
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	AddEvent(name string, attrs ...Attribute)
+
+	// RecordError records err on the span and marks it as failed.
+	RecordError(err error)
+
+	End()
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Attribute)    {}
+func (nopSpan) AddEvent(string, ...Attribute) {}
+func (nopSpan) RecordError(error)             {}
+func (nopSpan) End()                          {}
+
+type tracerHolder struct {
+	tracer Tracer
+}
+
+var globalTracer atomic.Pointer[tracerHolder]
+
+// SetTracer installs a tracer used by every execution in the process that has no
+// tracer attached to its context or pipeline. Passing nil removes it.
+func SetTracer(t Tracer) {
+	if t == nil {
+		globalTracer.Store(nil)
+		return
+	}
+	globalTracer.Store(&tracerHolder{tracer: t})
+}
+
+// WithTracer returns a context whose executions are traced with t.
+func WithTracer(ctx context.Context, t Tracer) context.Context {
+	var info execInfo
+	if parent := execInfoFrom(ctx); parent != nil {
+		info = *parent
+	}
+	info.tracer = t
+	return context.WithValue(ctx, execInfoKey{}, &info)
+}
+
+func tracerFrom(ctx context.Context) Tracer {
+	if info := execInfoFrom(ctx); info != nil && info.tracer != nil {
+		return info.tracer
+	}
+	if h := globalTracer.Load(); h != nil {
+		return h.tracer
+	}
+	return nil
+}
+
+type spanKey struct{}
+
+func spanFrom(ctx context.Context) Span {
+	span, _ := ctx.Value(spanKey{}).(Span)
+	return span
+}
+
+// SpanFromContext returns the gosentry span carried by ctx, or a no-op span.
+func SpanFromContext(ctx context.Context) Span {
+	if span := spanFrom(ctx); span != nil {
+		return span
+	}
+	return nopSpan{}
+}
+
+// StartSpan starts a child span using the tracer attached to ctx (or the global one).
+// Without a tracer it returns ctx unchanged and a no-op span, so policies can call it
+// unconditionally.
+func StartSpan(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	tracer := tracerFrom(ctx)
+	if tracer == nil {
+		return ctx, nopSpan{}
+	}
+
+	ctx, span := tracer.Start(ctx, name, attrs...)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func eventAttributes(ev Event) []Attribute {
+	attrs := make([]Attribute, 0, 6)
+	if ev.Policy != "" {
+		attrs = append(attrs, Attr("gosentry.policy", ev.Policy))
+	}
+	if ev.PolicyKind != "" {
+		attrs = append(attrs, Attr("gosentry.policy_kind", ev.PolicyKind))
+	}
+	if ev.Attempt > 0 {
+		attrs = append(attrs, Attr("gosentry.attempt", ev.Attempt))
+	}
+	if ev.Duration > 0 {
+		attrs = append(attrs, Attr("gosentry.duration", ev.Duration))
+	}
+	if ev.Reason != "" {
+		attrs = append(attrs, Attr("gosentry.reason", ev.Reason))
+	}
+	if ev.Endpoint != "" {
+		attrs = append(attrs, Attr("gosentry.endpoint", ev.Endpoint))
+	}
+	if ev.Kind == EventStateChange {
+		attrs = append(attrs, Attr("gosentry.from", ev.From), Attr("gosentry.to", ev.To))
+	}
+	if ev.Err != nil {
+		attrs = append(attrs, Attr("error", ev.Err.Error()))
+	}
+	return attrs
+}
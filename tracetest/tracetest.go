@@ -0,0 +1,118 @@
+// Package tracetest provides an in-memory gosentry.Tracer for tests.
+package tracetest
+
+import (
+	"context"
+	"sync"
+
+	"gosentry"
+)
+
+// Recorder is a gosentry.Tracer that keeps every span it starts in memory.
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+type spanKey struct{}
+
+// Start implements gosentry.Tracer.
+func (r *Recorder) Start(ctx context.Context, name string, attrs ...gosentry.Attribute) (context.Context, gosentry.Span) {
+	parent, _ := ctx.Value(spanKey{}).(*Span)
+	span := &Span{
+		Name:       name,
+		Parent:     parent,
+		Attributes: attributeMap(attrs),
+	}
+
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Spans returns all spans started so far, in start order.
+func (r *Recorder) Spans() []*Span {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Span(nil), r.spans...)
+}
+
+// Named returns the spans with the given name, in start order.
+func (r *Recorder) Named(name string) []*Span {
+	var out []*Span
+	for _, s := range r.Spans() {
+		if s.Name == name {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Span is a recorded span. Read its fields only after End has been called.
+type Span struct {
+	Name       string
+	Parent     *Span
+	Attributes map[string]any
+	Events     []Event
+	Errors     []error
+	Ended      bool
+
+	mu sync.Mutex
+}
+
+// Event is an event recorded on a span.
+type Event struct {
+	Name       string
+	Attributes map[string]any
+}
+
+func (s *Span) SetAttributes(attrs ...gosentry.Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range attrs {
+		s.Attributes[a.Key] = a.Value
+	}
+}
+
+func (s *Span) AddEvent(name string, attrs ...gosentry.Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Events = append(s.Events, Event{Name: name, Attributes: attributeMap(attrs)})
+}
+
+func (s *Span) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Errors = append(s.Errors, err)
+}
+
+func (s *Span) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Ended = true
+}
+
+// EventNames returns the names of the events recorded on s, in order.
+func (s *Span) EventNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, len(s.Events))
+	for i, ev := range s.Events {
+		names[i] = ev.Name
+	}
+	return names
+}
+
+func attributeMap(attrs []gosentry.Attribute) map[string]any {
+	m := make(map[string]any, len(attrs))
+	for _, a := range attrs {
+		m[a.Key] = a.Value
+	}
+	return m
+}
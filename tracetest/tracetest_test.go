@@ -0,0 +1,107 @@
+package tracetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gosentry"
+	"gosentry/policies"
+)
+
+func TestRecorder_RetryAttemptsAreChildSpans(t *testing.T) {
+	rec := NewRecorder()
+	attempts := 0
+	p := gosentry.NewPipeline("payments",
+		policies.Retry(policies.RetryOptions{MaxAttempts: 3, InitialDelay: time.Millisecond, Backoff: policies.BackoffFixed}),
+	).WithTracer(rec)
+
+	_, err := p.Execute(context.Background(), func(ctx context.Context) (any, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("flaky")
+		}
+		return "ok", nil
+	})
+	if err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+
+	exec := rec.Named("gosentry.execute")
+	if len(exec) != 1 || !exec[0].Ended {
+		t.Fatalf("expected one ended execution span, got %d", len(exec))
+	}
+	if exec[0].Attributes["gosentry.pipeline"] != "payments" {
+		t.Fatalf("expected pipeline attribute, got %v", exec[0].Attributes)
+	}
+
+	children := rec.Named("gosentry.retry.attempt")
+	if len(children) != 3 {
+		t.Fatalf("expected 3 attempt spans, got %d", len(children))
+	}
+	for i, c := range children {
+		if c.Parent != exec[0] {
+			t.Fatalf("expected attempt %d to be a child of the execution span", i+1)
+		}
+		if c.Attributes["gosentry.attempt"] != i+1 {
+			t.Fatalf("expected attempt attribute %d, got %v", i+1, c.Attributes["gosentry.attempt"])
+		}
+	}
+	if len(children[0].Errors) != 1 || len(children[2].Errors) != 0 {
+		t.Fatalf("expected only failed attempts to record errors")
+	}
+
+	names := exec[0].EventNames()
+	retries := 0
+	for _, n := range names {
+		if n == "gosentry.retry" {
+			retries++
+		}
+	}
+	if retries != 2 {
+		t.Fatalf("expected 2 retry events on the execution span, got %v", names)
+	}
+}
+
+func TestRecorder_PolicyRejectionsAnnotateSpan(t *testing.T) {
+	rec := NewRecorder()
+	ctx := gosentry.WithTracer(context.Background(), rec)
+	cb := policies.CircuitBreaker(policies.CircuitBreakerOptions{FailureThreshold: 1, OpenTimeout: time.Minute})
+	failing := func(ctx context.Context) (any, error) { return nil, errors.New("down") }
+
+	gosentry.Execute(ctx, failing, cb)
+	_, err := gosentry.Execute(ctx, failing, cb)
+	if !errors.Is(err, policies.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	spans := rec.Named("gosentry.execute")
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 execution spans, got %d", len(spans))
+	}
+
+	rejected := spans[1]
+	found := false
+	for _, ev := range rejected.Events {
+		if ev.Name == "gosentry.rejected" && ev.Attributes["gosentry.reason"] == "circuit_open" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected a rejection event, got %v", rejected.EventNames())
+	}
+	if len(rejected.Errors) != 1 {
+		t.Fatalf("expected execution span to record the error")
+	}
+}
+
+func TestStartSpan_NoTracerIsNoop(t *testing.T) {
+	ctx := context.Background()
+	got, span := gosentry.StartSpan(ctx, "noop")
+	if got != ctx {
+		t.Fatal("expected context to be returned unchanged")
+	}
+	span.AddEvent("ignored")
+	span.End()
+}